@@ -13,8 +13,8 @@ const ProjectDetail = ({ project, onClose, onEdit, onDelete }) => {
 
     const fetchProjectTasks = async () => {
         try {
-            const response = await api.get(`/api/tasks?project_id=${project.id}`);
-            setTasks(response.data);
+            const response = await api.get(`/api/tasks?project_id=${project.id}&limit=100`);
+            setTasks(response.data.items);
         } catch (error) {
             console.error('Error fetching project tasks:', error);
         } finally {
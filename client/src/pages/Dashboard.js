@@ -21,37 +21,22 @@ const Dashboard = () => {
 
     const fetchDashboardData = async () => {
         try {
-            // Получаем задачи с защитой от null
-            const tasksResponse = await api.get('/api/tasks?limit=5');
-            const allTasksResponse = await api.get('/api/tasks?limit=1000');
+            const tz = Intl.DateTimeFormat().resolvedOptions().timeZone || 'UTC';
+            const [tasksResponse, statsResponse] = await Promise.all([
+                api.get('/api/tasks?limit=5'),
+                api.get(`/api/stats/overview?tz=${encodeURIComponent(tz)}`),
+            ]);
 
-            // Получаем проекты с защитой от null
-            const projectsResponse = await api.get('/api/projects?limit=5');
-
-            // Безопасное извлечение данных
-            const recentTasksData = Array.isArray(tasksResponse?.data) ? tasksResponse.data : [];
-            const allTasksData = Array.isArray(allTasksResponse?.data) ? allTasksResponse.data : [];
-            const projectsData = Array.isArray(projectsResponse?.data) ? projectsResponse.data : [];
-
-            // Фильтруем только валидные задачи
-            const validAllTasks = allTasksData.filter(task => task && typeof task === 'object');
+            const recentTasksData = Array.isArray(tasksResponse?.data?.items) ? tasksResponse.data.items : [];
             const validRecentTasks = recentTasksData.filter(task => task && typeof task === 'object');
 
-            // Рассчитываем статистику
-            const totalTasks = validAllTasks.length;
-            const completedTasks = validAllTasks.filter(task => task.status === 'done').length;
-            const activeProjects = projectsData.length;
-            const overdueTasks = validAllTasks.filter(task =>
-                task.status !== 'done' &&
-                task.due_date &&
-                new Date(task.due_date) < new Date()
-            ).length;
-
+            // Счётчики считает сервер
+            const overview = statsResponse?.data || {};
             setStats({
-                totalTasks,
-                completedTasks,
-                activeProjects,
-                overdueTasks,
+                totalTasks: overview.total ?? 0,
+                completedTasks: overview.done ?? 0,
+                activeProjects: overview.active_projects ?? 0,
+                overdueTasks: overview.overdue ?? 0,
             });
 
             setRecentTasks(validRecentTasks);
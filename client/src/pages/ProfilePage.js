@@ -48,21 +48,16 @@ const ProfilePage = () => {
 
     const fetchUserStats = async () => {
         try {
-            const [tasksResponse, projectsResponse] = await Promise.all([
-                api.get('/api/tasks?limit=1000'),
-                api.get('/api/projects?limit=1000'),
+            // Нужны только счётчики из конверта списка
+            const [tasksResponse, doneResponse, projectsResponse] = await Promise.all([
+                api.get('/api/tasks?limit=1'),
+                api.get('/api/tasks?limit=1&status=done'),
+                api.get('/api/projects?limit=1'),
             ]);
 
-            // Двойная проверка: response.data может быть null или не массивом
-            const tasks = Array.isArray(tasksResponse?.data) ? tasksResponse.data : [];
-            const projects = Array.isArray(projectsResponse?.data) ? projectsResponse.data : [];
-
-            // Фильтруем только валидные задачи (не null/undefined)
-            const validTasks = tasks.filter(task => task && typeof task === 'object');
-
-            const totalTasks = validTasks.length;
-            const completedTasks = validTasks.filter(task => task.status === 'done').length;
-            const totalProjects = projects.length;
+            const totalTasks = tasksResponse?.data?.total ?? 0;
+            const completedTasks = doneResponse?.data?.total ?? 0;
+            const totalProjects = projectsResponse?.data?.total ?? 0;
 
             setStats({
                 totalTasks,
@@ -25,10 +25,10 @@ const ProjectsPage = () => {
     const fetchProjects = async () => {
         try {
             setLoading(true);
-            const response = await api.get('/api/projects');
+            const response = await api.get('/api/projects?limit=100');
 
             // ЗАЩИТА ОТ NULL - ДОБАВЬТЕ ЭТУ СТРОЧКУ
-            const projectsData = response?.data ? response.data : [];
+            const projectsData = response?.data?.items ? response.data.items : [];
 
             setProjects(projectsData);
             setError('');
@@ -42,9 +42,8 @@ const TasksPage = () => {
             };
 
             const response = await api.get('/api/tasks', { params });
-            setTasks(response.data);
-            // В реальном приложении сервер должен возвращать информацию о пагинации
-            setPagination(prev => ({ ...prev, total: response.data.length }));
+            setTasks(response.data.items);
+            setPagination(prev => ({ ...prev, total: response.data.total }));
             setError('');
         } catch (error) {
             setError('Failed to fetch tasks');
@@ -57,7 +56,7 @@ const TasksPage = () => {
     const fetchProjects = async () => {
         try {
             const response = await api.get('/api/projects?limit=100');
-            setProjects(response.data);
+            setProjects(response.data.items);
         } catch (error) {
             console.error('Error fetching projects:', error);
         }
@@ -231,7 +230,7 @@ const TasksPage = () => {
                 {/* Tasks List */}
                 <div className="tasks-list-container">
                     <div className="tasks-header">
-                        <h3>Your Tasks ({tasks.length})</h3>
+                        <h3>Your Tasks ({pagination.total})</h3>
                         <div className="tasks-actions">
                             <button
                                 onClick={() => setShowForm(true)}
@@ -279,12 +278,12 @@ const TasksPage = () => {
                                     </button>
 
                                     <span className="page-info">
-                    Page {pagination.page}
+                    Page {pagination.page} of {Math.ceil(pagination.total / pagination.limit)}
                   </span>
 
                                     <button
                                         onClick={() => handlePageChange(pagination.page + 1)}
-                                        disabled={tasks.length < pagination.limit}
+                                        disabled={pagination.page * pagination.limit >= pagination.total}
                                         className="page-btn"
                                     >
                                         Next
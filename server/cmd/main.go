@@ -1,15 +1,30 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"github.com/gin-contrib/cors"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
 	"log"
+	"net/http"
 	"os"
+	"os/signal"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
 	"task-tracker/internal/handlers"
 	"task-tracker/internal/middleware"
 	"task-tracker/internal/models"
 	"task-tracker/internal/repository"
 	"task-tracker/internal/service"
 	"task-tracker/pkg/database"
+	"task-tracker/pkg/events"
+	"task-tracker/pkg/idempotency"
+	"task-tracker/pkg/jobs"
+	"task-tracker/pkg/mail"
+	"task-tracker/pkg/storage"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -51,33 +66,221 @@ func main() {
 		&models.User{},
 		&models.Project{},
 		&models.Task{},
+		&models.ChecklistItem{},
+		&models.Blob{},
+		&models.Attachment{},
+		&models.TimeEntry{},
+		&models.SavedView{},
+		&models.SavedViewPin{},
+		&models.TaskKeyAlias{},
+		&models.Webhook{},
+		&models.WebhookDelivery{},
+		&models.NotificationPreferences{},
+		&models.SentNotification{},
 	)
 	if err != nil {
 		log.Fatal("Failed to migrate models:", err)
 	}
+	if err := repository.MigrateSearch(db); err != nil {
+		log.Fatal("Failed to create search indexes:", err)
+	}
+	if err := repository.MigrateSavedViewPins(db); err != nil {
+		log.Fatal("Failed to migrate pinned views:", err)
+	}
+	if err := repository.MigrateTaskKeys(db); err != nil {
+		log.Fatal("Failed to assign task keys:", err)
+	}
+	if err := repository.MigrateTaskRanks(db); err != nil {
+		log.Fatal("Failed to assign task ranks:", err)
+	}
+	if err := repository.MigrateSync(db); err != nil {
+		log.Fatal("Failed to prepare sync journal:", err)
+	}
+	if err := repository.MigrateOutbox(db); err != nil {
+		log.Fatal("Failed to create outbox:", err)
+	}
+
+	// Хранилище вложений
+	blobStore, err := storage.New(storage.Config{
+		Driver:    os.Getenv("STORAGE_DRIVER"),
+		Dir:       os.Getenv("STORAGE_DIR"),
+		Endpoint:  os.Getenv("S3_ENDPOINT"),
+		Bucket:    os.Getenv("S3_BUCKET"),
+		Region:    os.Getenv("S3_REGION"),
+		AccessKey: os.Getenv("S3_ACCESS_KEY"),
+		SecretKey: os.Getenv("S3_SECRET_KEY"),
+	})
+	if err != nil {
+		log.Fatal("Failed to initialize blob storage:", err)
+	}
+
+	attachmentConfig := service.AttachmentConfig{}
+	if v := os.Getenv("ATTACHMENT_MAX_SIZE"); v != "" {
+		attachmentConfig.MaxSize, err = strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			log.Fatal("Invalid ATTACHMENT_MAX_SIZE:", err)
+		}
+	}
+	if v := os.Getenv("ATTACHMENT_ALLOWED_TYPES"); v != "" {
+		for _, t := range strings.Split(v, ",") {
+			attachmentConfig.AllowedTypes = append(attachmentConfig.AllowedTypes, strings.TrimSpace(t))
+		}
+	}
+
+	webhookConfig := service.WebhookConfig{}
+	if v := os.Getenv("WEBHOOK_ALLOW_PRIVATE_NETWORKS"); v != "" {
+		if webhookConfig.AllowPrivateNetworks, err = strconv.ParseBool(v); err != nil {
+			log.Fatal("Invalid WEBHOOK_ALLOW_PRIVATE_NETWORKS:", err)
+		}
+	}
+
+	// Ключи идемпотентности POST-запросов
+	idempotencyStore, err := idempotency.New(idempotency.Config{
+		Driver: os.Getenv("IDEMPOTENCY_STORE"),
+		DB:     db,
+	})
+	if err != nil {
+		log.Fatal("Failed to init idempotency store:", err)
+	}
+	idempotencyTTL := 24 * time.Hour
+	if v := os.Getenv("IDEMPOTENCY_TTL"); v != "" {
+		if idempotencyTTL, err = time.ParseDuration(v); err != nil {
+			log.Fatal("Invalid IDEMPOTENCY_TTL:", err)
+		}
+	}
+	// Сколько ключ остаётся занятым выполняющимся запросом; должно быть дольше самого долгого запроса
+	idempotencyLock := 5 * time.Minute
+	if v := os.Getenv("IDEMPOTENCY_LOCK_TIMEOUT"); v != "" {
+		if idempotencyLock, err = time.ParseDuration(v); err != nil {
+			log.Fatal("Invalid IDEMPOTENCY_LOCK_TIMEOUT:", err)
+		}
+	}
 
 	// Инициализация репозиториев
 	userRepo := repository.NewUserRepository(db)
 	taskRepo := repository.NewTaskRepository(db)
 	projectRepo := repository.NewProjectRepository(db)
+	checklistRepo := repository.NewChecklistRepository(db)
+	attachmentRepo := repository.NewAttachmentRepository(db)
+	timeEntryRepo := repository.NewTimeEntryRepository(db)
+	statsRepo := repository.NewStatsRepository(db)
+	searchRepo := repository.NewSearchRepository(db)
+	savedViewRepo := repository.NewSavedViewRepository(db)
+	transactor := repository.NewTransactor(db)
+	syncRepo := repository.NewSyncRepository(db)
+	webhookRepo := repository.NewWebhookRepository(db)
+	outboxRepo := repository.NewOutboxRepository(db)
+	notificationRepo := repository.NewNotificationRepository(db)
+
+	// Очередь фоновых заданий: служебные задания идут по расписанию, по одному на все реплики
+	jobConfig := jobs.Config{DB: db}
+	if v := os.Getenv("JOB_CONCURRENCY"); v != "" {
+		if jobConfig.Concurrency, err = strconv.Atoi(v); err != nil {
+			log.Fatal("Invalid JOB_CONCURRENCY:", err)
+		}
+	}
+	jobQueue, err := jobs.New(jobConfig)
+	if err != nil {
+		log.Fatal("Failed to init job queue:", err)
+	}
+
+	// Почта уходит через очередь заданий, чтобы сбои SMTP повторялись
+	mailer, err := mail.New(mail.Config{
+		Driver:   os.Getenv("MAIL_DRIVER"),
+		From:     os.Getenv("MAIL_FROM"),
+		Host:     os.Getenv("SMTP_HOST"),
+		Port:     os.Getenv("SMTP_PORT"),
+		Username: os.Getenv("SMTP_USERNAME"),
+		Password: os.Getenv("SMTP_PASSWORD"),
+		Dir:      os.Getenv("MAIL_DIR"),
+	})
+	if err != nil {
+		log.Fatal("Failed to init mailer:", err)
+	}
+	if driver := os.Getenv("MAIL_DRIVER"); driver == "" || driver == "file" {
+		log.Println("Mail driver is file: messages are saved to MAIL_DIR instead of being sent; set MAIL_DRIVER=smtp in production")
+	}
+	sendMail := jobs.Register(jobQueue, "mail.send", func(ctx context.Context, msg mail.Message) error {
+		return mailer.Send(ctx, msg)
+	}, jobs.KindOptions{MaxAttempts: 8, Timeout: time.Minute})
+
+	// Шина событий для realtime-клиентов; история позволяет догнать пропущенное после переподключения
+	eventBus := events.NewBus(1000)
 
 	// Инициализация сервисов
+	webhookService := service.NewWebhookService(webhookRepo, projectRepo, webhookConfig)
 	userService := service.NewUserService(userRepo) // было: authService
-	taskService := service.NewTaskService(taskRepo)
-	projectService := service.NewProjectService(projectRepo, userRepo)
+	attachmentService := service.NewAttachmentService(attachmentRepo, taskRepo, blobStore, attachmentConfig)
+	taskService := service.NewTaskService(taskRepo, projectRepo, attachmentService)
+	projectService := service.NewProjectService(projectRepo, userRepo, taskRepo, savedViewRepo, attachmentService)
+	checklistService := service.NewChecklistService(checklistRepo, taskRepo)
+	timeTrackingService := service.NewTimeTrackingService(timeEntryRepo, taskRepo)
+	statsService := service.NewStatsService(statsRepo)
+	searchService := service.NewSearchService(searchRepo)
+	savedViewService := service.NewSavedViewService(savedViewRepo, projectRepo, taskService)
+	batchService := service.NewBatchService(transactor, userRepo, savedViewRepo, attachmentService)
+	syncService := service.NewSyncService(syncRepo, transactor, taskRepo, projectRepo, userRepo, savedViewRepo, attachmentService)
+	reminderService := service.NewReminderService(notificationRepo, taskRepo, queuedMailer{send: sendMail})
+
+	// События изменений попадают в outbox вместе с самими изменениями. Вебхуки получают
+	// каждое событие один раз на все реплики, а поток событий — на каждой реплике, ведь
+	// клиенты подключены к разным
+	outboxDispatcher := service.NewOutboxDispatcher(outboxRepo, webhookService.Enqueue)
+	outboxFollower := service.NewOutboxFollower(outboxRepo, eventBus)
+
+	if err := scheduleMaintenance(jobQueue, taskRepo, idempotencyStore, syncService, webhookService, outboxDispatcher); err != nil {
+		log.Fatal("Failed to schedule maintenance jobs:", err)
+	}
+	if err := scheduleReminders(jobQueue, reminderService); err != nil {
+		log.Fatal("Failed to schedule reminders:", err)
+	}
+
+	// Администраторы — пользователи с email из ADMIN_EMAILS (через запятую)
+	var adminEmails []string
+	for _, email := range strings.Split(os.Getenv("ADMIN_EMAILS"), ",") {
+		if email = strings.ToLower(strings.TrimSpace(email)); email != "" {
+			adminEmails = append(adminEmails, email)
+		}
+	}
+	isAdmin := func(userID string) (bool, error) {
+		id, err := uuid.Parse(userID)
+		if err != nil {
+			return false, nil
+		}
+		user, err := userRepo.FindByID(id)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		return slices.Contains(adminEmails, strings.ToLower(user.Email)), nil
+	}
 
 	// Инициализация хэндлеров
 	userHandler := handlers.NewUserHandler(userService, os.Getenv("JWT_SECRET")) // было: authHandler
 	taskHandler := handlers.NewTaskHandler(taskService)
 	projectHandler := handlers.NewProjectHandler(projectService)
+	checklistHandler := handlers.NewChecklistHandler(checklistService)
+	attachmentHandler := handlers.NewAttachmentHandler(attachmentService)
+	timeTrackingHandler := handlers.NewTimeTrackingHandler(timeTrackingService)
+	statsHandler := handlers.NewStatsHandler(statsService)
+	searchHandler := handlers.NewSearchHandler(searchService)
+	savedViewHandler := handlers.NewSavedViewHandler(savedViewService)
+	batchHandler := handlers.NewBatchHandler(batchService)
+	syncHandler := handlers.NewSyncHandler(syncService)
+	eventHandler := handlers.NewEventHandler(eventBus)
+	webhookHandler := handlers.NewWebhookHandler(webhookService)
+	jobHandler := handlers.NewJobHandler(jobQueue)
+	reminderHandler := handlers.NewReminderHandler(reminderService)
 
 	// Настройка роутера
 	r := gin.Default()
 	config := cors.Config{
 		AllowOrigins:     []string{"http://localhost:3000", "http://localhost", "http://127.0.0.1", "http://localhost:80"},
 		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Authorization", "X-Refresh-Token", "Content-Type"},
-		ExposeHeaders:    []string{"Authorization", "X-Refresh-Token"},
+		AllowHeaders:     []string{"Origin", "Authorization", "X-Refresh-Token", "Content-Type", "If-Match", "If-None-Match", "Idempotency-Key", "Last-Event-ID"},
+		ExposeHeaders:    []string{"Authorization", "X-Refresh-Token", "Content-Disposition", "Link", "Warning", "ETag", "Idempotent-Replayed"},
 		AllowCredentials: true,
 		MaxAge:           12 * time.Hour,
 	}
@@ -97,34 +300,231 @@ func main() {
 	// Защищенные роуты
 	api := r.Group("/api")
 	api.Use(middleware.AuthMiddleware(os.Getenv("JWT_SECRET")))
+	api.Use(taskHandler.ResolveTaskKey())
+	api.Use(middleware.Idempotency(idempotencyStore, idempotencyTTL, idempotencyLock))
 
 	// Пользователь
 	api.GET("/profile", userHandler.GetProfile)
 	api.PUT("/profile", userHandler.UpdateProfile) // Добавили обновление профиля
+	api.GET("/profile/reminders", reminderHandler.GetPreferences)
+	api.PUT("/profile/reminders", reminderHandler.UpdatePreferences)
 
 	// Задачи
 	api.GET("/tasks", taskHandler.ListTasks)
 	api.POST("/tasks", taskHandler.CreateTask)
+	api.POST("/tasks/bulk", taskHandler.BulkTasks)
 	api.GET("/tasks/:id", taskHandler.GetTask)
 	api.PUT("/tasks/:id", taskHandler.UpdateTask)
+	api.PATCH("/tasks/:id", taskHandler.PatchTask)
 	api.DELETE("/tasks/:id", taskHandler.DeleteTask)
 	api.PUT("/tasks/:id/status", taskHandler.UpdateTaskStatus)
+	api.POST("/tasks/:id/recurrence/skip", taskHandler.SkipOccurrence)
+	api.POST("/tasks/:id/recurrence/stop", taskHandler.StopRecurrence)
+	api.POST("/tasks/:id/move", taskHandler.MoveTask)
+
+	// Чек-листы задач
+	api.GET("/tasks/:id/checklist", checklistHandler.ListItems)
+	api.POST("/tasks/:id/checklist", checklistHandler.CreateItem)
+	api.PUT("/tasks/:id/checklist/order", checklistHandler.ReorderItems)
+	api.PUT("/tasks/:id/checklist/:item_id", checklistHandler.UpdateItem)
+	api.DELETE("/tasks/:id/checklist/:item_id", checklistHandler.DeleteItem)
+	api.POST("/tasks/:id/checklist/:item_id/toggle", checklistHandler.ToggleItem)
+	api.POST("/tasks/:id/checklist/:item_id/promote", checklistHandler.PromoteItem)
+
+	// Вложения
+	api.GET("/tasks/:id/attachments", attachmentHandler.ListAttachments)
+	api.POST("/tasks/:id/attachments", attachmentHandler.UploadAttachments)
+	api.GET("/tasks/:id/attachments/:attachment_id", attachmentHandler.DownloadAttachment)
+	api.DELETE("/tasks/:id/attachments/:attachment_id", attachmentHandler.DeleteAttachment)
+
+	// Учёт времени
+	api.GET("/timer", timeTrackingHandler.CurrentTimer)
+	api.POST("/timer/stop", timeTrackingHandler.StopTimer)
+	api.POST("/tasks/:id/timer/start", timeTrackingHandler.StartTimer)
+	api.GET("/tasks/:id/time-entries", timeTrackingHandler.ListEntries)
+	api.POST("/tasks/:id/time-entries", timeTrackingHandler.CreateEntry)
+	api.GET("/time-entries/report", timeTrackingHandler.Report)
+	api.PUT("/time-entries/:id", timeTrackingHandler.UpdateEntry)
+	api.DELETE("/time-entries/:id", timeTrackingHandler.DeleteEntry)
+
+	// Статистика
+	api.GET("/stats/overview", statsHandler.Overview)
+
+	// Поиск
+	api.GET("/search", searchHandler.Search)
+
+	// Сохранённые представления
+	api.GET("/views", savedViewHandler.ListViews)
+	api.POST("/views", savedViewHandler.CreateView)
+	api.GET("/views/:id", savedViewHandler.GetView)
+	api.PUT("/views/:id", savedViewHandler.UpdateView)
+	api.DELETE("/views/:id", savedViewHandler.DeleteView)
+	api.GET("/views/:id/tasks", savedViewHandler.ExecuteView)
+	api.PUT("/views/:id/pin", savedViewHandler.PinView)
+	api.DELETE("/views/:id/pin", savedViewHandler.UnpinView)
 
 	// Проекты
 	api.GET("/projects", projectHandler.ListProjects)
 	api.POST("/projects", projectHandler.CreateProject)
 	api.GET("/projects/:id", projectHandler.GetProject)
 	api.PUT("/projects/:id", projectHandler.UpdateProject)
+	api.PATCH("/projects/:id", projectHandler.PatchProject)
 	api.DELETE("/projects/:id", projectHandler.DeleteProject)
+	api.GET("/projects/:id/board", projectHandler.GetBoard)
+
+	// Вебхуки проекта
+	api.GET("/projects/:id/webhooks", webhookHandler.ListWebhooks)
+	api.POST("/projects/:id/webhooks", webhookHandler.CreateWebhook)
+	api.GET("/projects/:id/webhooks/:webhook_id", webhookHandler.GetWebhook)
+	api.PUT("/projects/:id/webhooks/:webhook_id", webhookHandler.UpdateWebhook)
+	api.DELETE("/projects/:id/webhooks/:webhook_id", webhookHandler.DeleteWebhook)
+	api.GET("/projects/:id/webhooks/:webhook_id/deliveries", webhookHandler.ListDeliveries)
+	api.POST("/projects/:id/webhooks/:webhook_id/deliveries/:delivery_id/redeliver", webhookHandler.Redeliver)
+
+	// Пакет операций в одной транзакции
+	api.POST("/batch", batchHandler.Batch)
+
+	// Дельта-синхронизация офлайн-клиентов
+	api.GET("/sync", syncHandler.Pull)
+	api.POST("/sync", syncHandler.Push)
+
+	// Поток изменений для открытых клиентов
+	api.GET("/events", eventHandler.Stream)
+
+	// Администрирование
+	admin := api.Group("/admin")
+	admin.Use(middleware.RequireAdmin(isAdmin))
+	admin.GET("/jobs", jobHandler.ListJobs)
+	admin.GET("/jobs/:id", jobHandler.GetJob)
+	admin.POST("/jobs/:id/retry", jobHandler.RetryJob)
+	admin.DELETE("/jobs/:id", jobHandler.DeleteJob)
+
+	// Частая фоновая работа идёт вместе с очередью, чтобы остановка дождалась и её
+	jobQueue.Every("outbox.dispatch", 500*time.Millisecond, func(context.Context) (int, error) {
+		return outboxDispatcher.Dispatch()
+	})
+	jobQueue.Every("outbox.follow", 250*time.Millisecond, func(context.Context) (int, error) {
+		return outboxFollower.Follow()
+	})
+	jobQueue.Every("webhooks.deliver", 5*time.Second, webhookService.DeliverDue)
+	jobQueue.Start()
 
 	// Запуск сервера
 	port := os.Getenv("PORT")
 	if port == "" {
 		port = "8080"
 	}
+	srv := &http.Server{Addr: ":" + port, Handler: r}
+	// Открытые потоки событий иначе не дали бы серверу остановиться
+	srv.RegisterOnShutdown(eventBus.Close)
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		log.Printf("Server starting on port %s", port)
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal(err)
+		}
+	}()
+	<-ctx.Done()
 
-	log.Printf("Server starting on port %s", port)
-	if err := r.Run(":" + port); err != nil {
-		log.Fatal(err)
+	// Плавная остановка: дожидаемся начатых запросов и заданий, новых не берём
+	log.Println("Shutting down...")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Printf("server shutdown: %v", err)
 	}
+	if err := jobQueue.Shutdown(shutdownCtx); err != nil {
+		log.Printf("job queue shutdown: %v", err)
+	}
+}
+
+// Сколько ждать начатые запросы и задания при остановке
+const shutdownTimeout = 30 * time.Second
+
+// scheduleMaintenance регистрирует служебные задания и их расписания
+func scheduleMaintenance(q *jobs.Queue, tasks repository.TaskRepository, idem idempotency.Store, sync service.SyncService, webhooks service.WebhookService, outbox service.OutboxDispatcher) error {
+	type none struct{}
+
+	// Раздвигает ранги в колонках, где после частых перестановок соседние задачи
+	// сблизились, — чтобы перебалансировка не выпадала на запрос пользователя
+	rebalance := jobs.Register(q, "tasks.rebalance_ranks", func(ctx context.Context, _ none) error {
+		n, err := tasks.RebalanceRanks(repository.RankRebalanceGap)
+		if n > 0 {
+			log.Printf("rebalanced ranks in %d columns", n)
+		}
+		return err
+	}, jobs.KindOptions{})
+
+	idempotencyPurge := jobs.Register(q, "idempotency.purge", func(ctx context.Context, _ none) error {
+		_, err := idem.DeleteExpired(ctx)
+		return err
+	}, jobs.KindOptions{})
+
+	tombstonesPurge := jobs.Register(q, "sync.purge_tombstones", func(ctx context.Context, _ none) error {
+		_, err := sync.PurgeTombstones()
+		return err
+	}, jobs.KindOptions{})
+
+	deliveriesPurge := jobs.Register(q, "webhooks.purge_deliveries", func(ctx context.Context, _ none) error {
+		_, err := webhooks.PurgeDeliveries()
+		return err
+	}, jobs.KindOptions{})
+
+	outboxPurge := jobs.Register(q, "outbox.purge", func(ctx context.Context, _ none) error {
+		_, err := outbox.Purge()
+		return err
+	}, jobs.KindOptions{})
+
+	jobsPurge := jobs.Register(q, "jobs.purge", func(ctx context.Context, _ none) error {
+		_, err := q.PurgeFinished(ctx, time.Now().Add(-jobRetention))
+		return err
+	}, jobs.KindOptions{})
+
+	return errors.Join(
+		rebalance.Schedule("@hourly", none{}),
+		idempotencyPurge.Schedule("5 * * * *", none{}),
+		tombstonesPurge.Schedule("10 * * * *", none{}),
+		deliveriesPurge.Schedule("15 * * * *", none{}),
+		outboxPurge.Schedule("20 * * * *", none{}),
+		jobsPurge.Schedule("30 3 * * *", none{}),
+	)
+}
+
+// Сколько хранить выполненные задания
+const jobRetention = 7 * 24 * time.Hour
+
+// scheduleReminders раз в пять минут рассылает напоминания о сроках тем, у кого наступило
+// выбранное время; отметки об отправке не дают повторить письмо
+func scheduleReminders(q *jobs.Queue, reminders service.ReminderService) error {
+	type none struct{}
+
+	send := jobs.Register(q, "reminders.send", func(ctx context.Context, _ none) error {
+		n, err := reminders.SendDue(ctx, time.Now())
+		if n > 0 {
+			log.Printf("sent %d reminders", n)
+		}
+		return err
+	}, jobs.KindOptions{MaxAttempts: 3})
+
+	purge := jobs.Register(q, "reminders.purge_sent", func(ctx context.Context, _ none) error {
+		_, err := reminders.PurgeSent()
+		return err
+	}, jobs.KindOptions{})
+
+	return errors.Join(
+		send.Schedule("*/5 * * * *", none{}),
+		purge.Schedule("45 3 * * *", none{}),
+	)
+}
+
+// queuedMailer ставит письма в очередь заданий вместо отправки на месте
+type queuedMailer struct {
+	send jobs.Kind[mail.Message]
+}
+
+func (m queuedMailer) Send(ctx context.Context, msg mail.Message) error {
+	_, err := m.send.Enqueue(ctx, msg, jobs.EnqueueOptions{})
+	return err
 }
@@ -0,0 +1,82 @@
+package dto
+
+import (
+	"encoding/json"
+	"github.com/google/uuid"
+	"task-tracker/internal/models"
+)
+
+type BatchMethod string
+
+const (
+	BatchCreate BatchMethod = "create"
+	BatchUpdate BatchMethod = "update"
+	BatchDelete BatchMethod = "delete"
+)
+
+type BatchResource string
+
+const (
+	BatchTask    BatchResource = "task"
+	BatchProject BatchResource = "project"
+)
+
+// BatchOperation — одна операция пакета. Созданный объект получает имя Ref, и следующие
+// операции могут ссылаться на него строкой "$имя" в id и в project_id тела.
+// В create id необязателен — это UUID, выбранный клиентом для нового объекта.
+// Body для create — поля как у POST /tasks или /projects, для update — JSON Merge Patch
+type BatchOperation struct {
+	Method   BatchMethod     `json:"method"`
+	Resource BatchResource   `json:"resource"`
+	Ref      string          `json:"ref,omitempty"`
+	ID       string          `json:"id,omitempty"`
+	IfMatch  *int            `json:"if_match,omitempty"`
+	Body     json.RawMessage `json:"body,omitempty"`
+}
+
+type TaskCreate struct {
+	Title              string              `json:"title"`
+	Description        string              `json:"description"`
+	Priority           models.TaskPriority `json:"priority"`
+	DueDate            *string             `json:"due_date"`
+	ProjectID          *uuid.UUID          `json:"project_id"`
+	Recurrence         string              `json:"recurrence"`
+	RecurrenceTimezone string              `json:"recurrence_timezone"`
+	Estimate           *float64            `json:"estimate"`
+	RemainingEstimate  *float64            `json:"remaining_estimate"`
+}
+
+type ProjectCreate struct {
+	Name         string                    `json:"name"`
+	Key          string                    `json:"key"`
+	Description  string                    `json:"description"`
+	Color        string                    `json:"color"`
+	EstimateUnit models.EstimateUnit       `json:"estimate_unit"`
+	WIPLimits    map[models.TaskStatus]int `json:"wip_limits"`
+	WIPStrict    bool                      `json:"wip_strict"`
+}
+
+type BatchOpStatus string
+
+const (
+	BatchOK         BatchOpStatus = "ok"
+	BatchFailed     BatchOpStatus = "failed"
+	BatchRolledBack BatchOpStatus = "rolled_back" // выполнена, но отменена из-за ошибки в другой операции
+	BatchSkipped    BatchOpStatus = "skipped"     // не выполнялась
+)
+
+type BatchOpResult struct {
+	Index  int           `json:"index"`
+	Ref    string        `json:"ref,omitempty"`
+	Result BatchOpStatus `json:"result"`
+	Status int           `json:"status,omitempty"` // HTTP-статус, который вернул бы одиночный запрос
+	ID     *uuid.UUID    `json:"id,omitempty"`
+	Data   any           `json:"data,omitempty"`
+	Error  string        `json:"error,omitempty"`
+}
+
+// BatchResult — итог пакета. Пакет применяется целиком или не применяется вовсе
+type BatchResult struct {
+	Committed bool            `json:"committed"`
+	Results   []BatchOpResult `json:"results"`
+}
@@ -2,7 +2,9 @@ package dto
 
 import (
 	"github.com/google/uuid"
+	"strings"
 	"task-tracker/internal/models"
+	"time"
 )
 
 type RegisterRequest struct {
@@ -23,15 +25,202 @@ type UpdateProfileRequest struct {
 }
 
 type ListProjectsResponse struct {
-	*models.Project     // Встраиваем всю структуру Project
-	TotalTasks      int `json:"total_tasks"`
-	CompletedTasks  int `json:"completed_tasks"`
+	*models.Project           // Встраиваем всю структуру Project
+	TotalTasks        int     `json:"total_tasks"`
+	CompletedTasks    int     `json:"completed_tasks"`
+	TotalEstimate     float64 `json:"total_estimate"`
+	RemainingEstimate float64 `json:"remaining_estimate"`
 }
 
 type TaskFilter struct {
-	UserID    *uuid.UUID
-	ProjectID *uuid.UUID
-	Status    models.TaskStatus
-	Priority  models.TaskPriority
-	Search    string
+	UserID     *uuid.UUID
+	ViewerID   *uuid.UUID // только задачи, которые видит пользователь (свои и из его проектов)
+	ProjectID  *uuid.UUID
+	ProjectIDs []uuid.UUID
+	NoProject  bool // только задачи без проекта («входящие»)
+	ParentID   *uuid.UUID
+	Statuses   []models.TaskStatus
+	Priorities []models.TaskPriority
+	Search     string
+
+	EstimateMin *float64
+	EstimateMax *float64
+	HasEstimate *bool
+
+	// Диапазоны: нижняя граница включается, верхняя — нет
+	DueFrom     *time.Time
+	DueTo       *time.Time
+	CreatedFrom *time.Time
+	CreatedTo   *time.Time
+	UpdatedFrom *time.Time
+	UpdatedTo   *time.Time
+	HasDueDate  *bool
+
+	// Overdue отбирает незавершённые задачи со сроком раньше Today
+	// (календарная дата пользователя в полночь UTC, как хранятся сроки)
+	Overdue *bool
+	Today   time.Time
+
+	// Query — запрос на языке pkg/taskql; сервис разбирает его в поля выше.
+	// Даты создания и изменения в нём отсчитываются в поясе Location
+	Query    string
+	Location *time.Location
+
+	Sort []SortField
+}
+
+// SortField — ключ сортировки; в запросе записывается как "field" или "-field" (по убыванию)
+type SortField struct {
+	Field string
+	Desc  bool
+}
+
+// ParseSort разбирает список вида "estimate,-created_at"
+func ParseSort(value string) []SortField {
+	var fields []SortField
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		field := SortField{Field: part}
+		if strings.HasPrefix(part, "-") {
+			field = SortField{Field: part[1:], Desc: true}
+		}
+		fields = append(fields, field)
+	}
+	return fields
+}
+
+type TimeReportFilter struct {
+	ViewerID       uuid.UUID
+	From           time.Time
+	To             time.Time
+	Timezone       string
+	ProjectID      *uuid.UUID
+	UserID         *uuid.UUID
+	GroupByProject bool
+	GroupByUser    bool
+	GroupByDay     bool
+}
+
+type TimeReportRow struct {
+	ProjectID   *uuid.UUID `json:"project_id,omitempty"`
+	ProjectName *string    `json:"project_name,omitempty"`
+	UserID      *uuid.UUID `json:"user_id,omitempty"`
+	UserName    *string    `json:"user_name,omitempty"`
+	Day         *string    `json:"day,omitempty"`
+	Seconds     int64      `json:"seconds"`
+	Entries     int64      `json:"entries"`
+}
+
+// StatsBounds — границы периодов для сводной статистики.
+// Today/Tomorrow/WeekEnd сравниваются со сроками задач, CompletedSince* — с моментом выполнения
+type StatsBounds struct {
+	Today            time.Time
+	Tomorrow         time.Time
+	WeekEnd          time.Time
+	CompletedSince7  time.Time
+	CompletedSince30 time.Time
+}
+
+type TaskCounts struct {
+	Total               int64 `json:"total"`
+	Todo                int64 `json:"todo"`
+	InProgress          int64 `json:"in_progress"`
+	Done                int64 `json:"done"`
+	LowPriority         int64 `json:"low_priority"`
+	MediumPriority      int64 `json:"medium_priority"`
+	HighPriority        int64 `json:"high_priority"`
+	Overdue             int64 `json:"overdue"`
+	DueToday            int64 `json:"due_today"`
+	DueThisWeek         int64 `json:"due_this_week"`
+	CompletedLast7Days  int64 `json:"completed_last_7_days"`
+	CompletedLast30Days int64 `json:"completed_last_30_days"`
+}
+
+// ProjectStats — счётчики по одному проекту; задачи без проекта идут строкой с пустым ProjectID
+type ProjectStats struct {
+	ProjectID   *uuid.UUID `json:"project_id"`
+	ProjectName *string    `json:"project_name"`
+	Color       *string    `json:"color"`
+	TaskCounts
+}
+
+type StatsOverview struct {
+	TaskCounts
+	ActiveProjects int64          `json:"active_projects"`
+	TotalProjects  int64          `json:"total_projects"`
+	Projects       []ProjectStats `json:"projects"`
+}
+
+// SearchHit — найденная задача или проект. Highlight и Snippet содержат экранированный
+// текст с совпадениями в <mark>
+type SearchHit struct {
+	Type      string     `json:"type"`
+	ID        uuid.UUID  `json:"id"`
+	ProjectID *uuid.UUID `json:"project_id,omitempty"`
+	Title     string     `json:"title"`
+	Status    *string    `json:"status,omitempty"`
+	Highlight string     `json:"highlight"`
+	Snippet   string     `json:"snippet"`
+	Rank      float64    `json:"rank"`
+}
+
+// ProjectListResponse — страница проектов и закреплённые представления для боковой панели
+type ProjectListResponse struct {
+	*ListResponse[ListProjectsResponse]
+	PinnedViews []models.SavedView `json:"pinned_views"`
+}
+
+// SavedViewResult — представление и найденные по нему задачи
+type SavedViewResult struct {
+	View *models.SavedView `json:"view"`
+	*ListResponse[models.Task]
+}
+
+// BoardColumn — колонка доски проекта: число видимых задач и первые задачи по рангу
+type BoardColumn struct {
+	Status    models.TaskStatus `json:"status"`
+	Count     int64             `json:"count"`
+	WIPLimit  *int              `json:"wip_limit"`
+	OverLimit bool              `json:"over_limit"`
+	Tasks     []models.Task     `json:"tasks"`
+}
+
+type Board struct {
+	Project *models.Project `json:"project"`
+	Columns []BoardColumn   `json:"columns"`
+}
+
+// WIPWarning сообщает, что задача переведена в колонку сверх её лимита
+type WIPWarning struct {
+	Status models.TaskStatus `json:"status"`
+	Limit  int               `json:"limit"`
+	Count  int64             `json:"count"`
+}
+
+type BulkItemStatus string
+
+const (
+	BulkOK       BulkItemStatus = "ok"
+	BulkNotFound BulkItemStatus = "not_found"
+	BulkFailed   BulkItemStatus = "failed"
+)
+
+type BulkItemResult struct {
+	ID      uuid.UUID      `json:"id"`
+	Result  BulkItemStatus `json:"result"`
+	Error   string         `json:"error,omitempty"`
+	Warning *WIPWarning    `json:"warning,omitempty"`
+}
+
+// BulkResult — отчёт о массовой операции по каждой задаче. RolledBack означает, что
+// из-за ошибок в режиме all_or_nothing не применено ни одно изменение
+type BulkResult struct {
+	Matched    int              `json:"matched"`
+	Succeeded  int              `json:"succeeded"`
+	Failed     int              `json:"failed"`
+	RolledBack bool             `json:"rolled_back"`
+	Items      []BulkItemResult `json:"items"`
 }
@@ -0,0 +1,28 @@
+package dto
+
+const (
+	DefaultPageLimit = 10
+	MaxPageLimit     = 100
+)
+
+// PageRequest задаёт страницу списка: либо номером (Page), либо курсором,
+// полученным из предыдущего ответа. Курсор устойчив к вставкам между запросами.
+type PageRequest struct {
+	Page   int
+	Limit  int
+	Cursor string
+}
+
+type PageInfo struct {
+	Page       int    `json:"page,omitempty"`
+	Limit      int    `json:"limit"`
+	HasMore    bool   `json:"has_more"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// ListResponse — общий конверт для списков
+type ListResponse[T any] struct {
+	Items    []T      `json:"items"`
+	Total    int64    `json:"total"`
+	PageInfo PageInfo `json:"page_info"`
+}
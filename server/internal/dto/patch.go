@@ -0,0 +1,67 @@
+package dto
+
+import (
+	"encoding/json"
+	"github.com/google/uuid"
+	"task-tracker/internal/models"
+	"time"
+)
+
+// Field — поле документа JSON Merge Patch (RFC 7396). Set=false — поля нет в запросе
+// (не менять), Set=true и Value=nil — явный null (очистить)
+type Field[T any] struct {
+	Set   bool
+	Value *T
+}
+
+func (f *Field[T]) UnmarshalJSON(data []byte) error {
+	f.Set = true
+	if string(data) == "null" {
+		f.Value = nil
+		return nil
+	}
+	var v T
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	f.Value = &v
+	return nil
+}
+
+// Null — поле передано со значением null
+func (f Field[T]) Null() bool {
+	return f.Set && f.Value == nil
+}
+
+type TaskPatch struct {
+	Title              Field[string]              `json:"title"`
+	Description        Field[string]              `json:"description"`
+	Status             Field[models.TaskStatus]   `json:"status"`
+	Priority           Field[models.TaskPriority] `json:"priority"`
+	DueDate            Field[string]              `json:"due_date"`
+	ProjectID          Field[uuid.UUID]           `json:"project_id"`
+	Estimate           Field[float64]             `json:"estimate"`
+	RemainingEstimate  Field[float64]             `json:"remaining_estimate"`
+	Recurrence         Field[string]              `json:"recurrence"`
+	RecurrenceTimezone Field[string]              `json:"recurrence_timezone"`
+}
+
+// ProjectPatch.WIPLimits сливается с текущими лимитами по правилам RFC 7396:
+// null у колонки снимает её лимит, null у всего поля снимает все лимиты
+type ProjectPatch struct {
+	Name         Field[string]                     `json:"name"`
+	Description  Field[string]                     `json:"description"`
+	Color        Field[string]                     `json:"color"`
+	EstimateUnit Field[models.EstimateUnit]        `json:"estimate_unit"`
+	WIPLimits    Field[map[models.TaskStatus]*int] `json:"wip_limits"`
+	WIPStrict    Field[bool]                       `json:"wip_strict"`
+}
+
+// ParseDueDate принимает дату (YYYY-MM-DD) или момент времени в RFC 3339
+func ParseDueDate(value string) (time.Time, error) {
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		t, err = time.Parse(time.RFC3339, value)
+	}
+	return t, err
+}
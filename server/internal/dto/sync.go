@@ -0,0 +1,57 @@
+package dto
+
+import (
+	"encoding/json"
+	"github.com/google/uuid"
+	"task-tracker/internal/models"
+)
+
+// SyncChanges — изменения после курсора. Клиент сначала применяет Deleted, затем сохраняет
+// Tasks и Projects поверх своих копий; при HasMore сразу запрашивает следующую страницу
+type SyncChanges struct {
+	Tasks    []models.Task    `json:"tasks"`
+	Projects []models.Project `json:"projects"`
+	Deleted  []SyncDeletion   `json:"deleted"`
+	Cursor   string           `json:"cursor"`
+	HasMore  bool             `json:"has_more"`
+}
+
+// SyncDeletion — объект удалён или перестал быть виден пользователю (задачу переназначили,
+// перенесли в чужой проект); клиент в обоих случаях убирает свою копию
+type SyncDeletion struct {
+	Resource BatchResource `json:"resource"`
+	ID       uuid.UUID     `json:"id"`
+}
+
+// SyncMutation — изменение, сделанное клиентом офлайн. Новые объекты клиент создаёт со своими
+// UUID; для update и delete BaseVersion — версия, от которой он отталкивался
+type SyncMutation struct {
+	Method      BatchMethod     `json:"method"`
+	Resource    BatchResource   `json:"resource"`
+	ID          uuid.UUID       `json:"id"`
+	BaseVersion *int            `json:"base_version"`
+	Data        json.RawMessage `json:"data,omitempty"`
+}
+
+type SyncMutationStatus string
+
+const (
+	SyncApplied  SyncMutationStatus = "applied"
+	SyncConflict SyncMutationStatus = "conflict" // объект изменился на сервере; Data — его текущее состояние
+	SyncFailed   SyncMutationStatus = "failed"
+)
+
+type SyncMutationResult struct {
+	Index  int                `json:"index"`
+	ID     uuid.UUID          `json:"id"`
+	Result SyncMutationStatus `json:"result"`
+	Status int                `json:"status,omitempty"`
+	Data   any                `json:"data,omitempty"`
+	Error  string             `json:"error,omitempty"`
+	Err    error              `json:"-"`
+}
+
+// SyncPushResult — итог по каждой мутации: они применяются независимо друг от друга
+type SyncPushResult struct {
+	Results []SyncMutationResult `json:"results"`
+}
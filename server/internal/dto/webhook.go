@@ -0,0 +1,23 @@
+package dto
+
+import (
+	"encoding/json"
+	"github.com/google/uuid"
+	"task-tracker/internal/models"
+	"time"
+)
+
+// WebhookPayload — тело запроса, которое получает вебхук
+type WebhookPayload struct {
+	ID        string          `json:"id"`
+	Type      string          `json:"type"`
+	ProjectID uuid.UUID       `json:"project_id"`
+	CreatedAt time.Time       `json:"created_at"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// CreatedWebhook — ответ на создание: единственный раз, когда виден ключ подписи
+type CreatedWebhook struct {
+	models.Webhook
+	Secret string `json:"secret"`
+}
@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"net/http"
+	"task-tracker/internal/dto"
+	"task-tracker/internal/service"
+)
+
+type BatchHandler struct {
+	service service.BatchService
+}
+
+func NewBatchHandler(service service.BatchService) *BatchHandler {
+	return &BatchHandler{service: service}
+}
+
+// Batch выполняет пакет операций над задачами и проектами атомарно. Если операция
+// не удалась, ответ получает её статус, а в results видно, какая операция и почему
+func (h *BatchHandler) Batch(c *gin.Context) {
+	userID, err := uuid.Parse(c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user id"})
+		return
+	}
+
+	var req struct {
+		Operations []dto.BatchOperation `json:"operations" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := h.service.Execute(userID, req.Operations)
+	var opErr *service.BatchOpError
+	if errors.As(err, &opErr) {
+		status := statusFor(opErr.Err)
+		result.Results[opErr.Index].Status = status
+		c.JSON(status, result)
+		return
+	}
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	for i, op := range req.Operations {
+		result.Results[i].Status = http.StatusOK
+		if op.Method == dto.BatchCreate {
+			result.Results[i].Status = http.StatusCreated
+		}
+	}
+	c.JSON(http.StatusOK, result)
+}
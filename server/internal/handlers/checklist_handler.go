@@ -0,0 +1,174 @@
+package handlers
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"net/http"
+	"task-tracker/internal/service"
+)
+
+type ChecklistHandler struct {
+	service service.ChecklistService
+}
+
+func NewChecklistHandler(service service.ChecklistService) *ChecklistHandler {
+	return &ChecklistHandler{service: service}
+}
+
+func (h *ChecklistHandler) ListItems(c *gin.Context) {
+	taskID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid task id"})
+		return
+	}
+
+	items, err := h.service.List(taskID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, items)
+}
+
+func (h *ChecklistHandler) CreateItem(c *gin.Context) {
+	taskID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid task id"})
+		return
+	}
+
+	var req struct {
+		Title string `json:"title" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	item, err := h.service.Create(taskID, req.Title)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, item)
+}
+
+func (h *ChecklistHandler) UpdateItem(c *gin.Context) {
+	taskID, itemID, ok := parseChecklistParams(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Title *string `json:"title"`
+		Done  *bool   `json:"done"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	item, err := h.service.Update(taskID, itemID, service.UpdateChecklistItemRequest{
+		Title: req.Title,
+		Done:  req.Done,
+	})
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, item)
+}
+
+func (h *ChecklistHandler) ToggleItem(c *gin.Context) {
+	taskID, itemID, ok := parseChecklistParams(c)
+	if !ok {
+		return
+	}
+
+	item, err := h.service.Toggle(taskID, itemID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, item)
+}
+
+func (h *ChecklistHandler) DeleteItem(c *gin.Context) {
+	taskID, itemID, ok := parseChecklistParams(c)
+	if !ok {
+		return
+	}
+
+	if err := h.service.Delete(taskID, itemID); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Checklist item deleted successfully"})
+}
+
+func (h *ChecklistHandler) ReorderItems(c *gin.Context) {
+	taskID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid task id"})
+		return
+	}
+
+	var req struct {
+		ItemIDs []uuid.UUID `json:"item_ids" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	items, err := h.service.Reorder(taskID, req.ItemIDs)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, items)
+}
+
+func (h *ChecklistHandler) PromoteItem(c *gin.Context) {
+	userID, err := uuid.Parse(c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user id"})
+		return
+	}
+
+	taskID, itemID, ok := parseChecklistParams(c)
+	if !ok {
+		return
+	}
+
+	subtask, err := h.service.Promote(taskID, itemID, userID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, subtask)
+}
+
+func parseChecklistParams(c *gin.Context) (uuid.UUID, uuid.UUID, bool) {
+	taskID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid task id"})
+		return uuid.Nil, uuid.Nil, false
+	}
+	itemID, err := uuid.Parse(c.Param("item_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid checklist item id"})
+		return uuid.Nil, uuid.Nil, false
+	}
+	return taskID, itemID, true
+}
@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"net/http"
+	"task-tracker/internal/repository"
+	"task-tracker/internal/service"
+	"task-tracker/pkg/jobs"
+	"task-tracker/pkg/storage"
+	"task-tracker/pkg/taskql"
+)
+
+// statusFor сопоставляет доменные ошибки с HTTP-статусами
+func statusFor(err error) int {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound),
+		errors.Is(err, service.ErrChecklistItemNotFound),
+		errors.Is(err, service.ErrAttachmentNotFound),
+		errors.Is(err, service.ErrTimeEntryNotFound),
+		errors.Is(err, service.ErrNoRunningTimer),
+		errors.Is(err, service.ErrSavedViewNotFound),
+		errors.Is(err, service.ErrWebhookNotFound),
+		errors.Is(err, service.ErrDeliveryNotFound),
+		errors.Is(err, jobs.ErrNotFound),
+		errors.Is(err, storage.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, repository.ErrVersionConflict):
+		return http.StatusPreconditionFailed
+	case errors.Is(err, service.ErrSyncCursorExpired):
+		return http.StatusGone
+	case errors.Is(err, service.ErrAttachmentTooLarge):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, service.ErrUnsupportedMediaType):
+		return http.StatusUnsupportedMediaType
+	case errors.Is(err, service.ErrForbidden):
+		return http.StatusForbidden
+	case errors.Is(err, service.ErrNoNextOccurrence),
+		errors.Is(err, repository.ErrTimerAlreadyRunning),
+		errors.Is(err, repository.ErrProjectKeyTaken),
+		errors.Is(err, service.ErrWIPLimitReached),
+		errors.Is(err, service.ErrIDTaken),
+		errors.Is(err, jobs.ErrNotRetryable),
+		errors.Is(err, jobs.ErrJobRunning),
+		errors.Is(err, jobs.ErrDuplicateJob):
+		return http.StatusConflict
+	case errors.Is(err, service.ErrEmptyTitle),
+		errors.Is(err, service.ErrInvalidRecurrence),
+		errors.Is(err, service.ErrRecurrenceNeedsDueDate),
+		errors.Is(err, service.ErrNotRecurring),
+		errors.Is(err, service.ErrInvalidTimeRange),
+		errors.Is(err, service.ErrInvalidEstimate),
+		errors.Is(err, service.ErrInvalidEstimateUnit),
+		errors.Is(err, service.ErrInvalidProjectKey),
+		errors.Is(err, service.ErrInvalidTaskKey),
+		errors.Is(err, service.ErrInvalidMove),
+		errors.Is(err, service.ErrInvalidWIPLimit),
+		errors.Is(err, service.ErrInvalidBulk),
+		errors.Is(err, service.ErrInvalidPatch),
+		errors.Is(err, service.ErrProjectNotFound),
+		errors.Is(err, service.ErrInvalidBatch),
+		errors.Is(err, service.ErrInvalidSync),
+		errors.Is(err, service.ErrInvalidSyncCursor),
+		errors.Is(err, repository.ErrNeighborNotInColumn),
+		errors.Is(err, service.ErrInvalidSearch),
+		errors.Is(err, service.ErrInvalidSavedView),
+		errors.Is(err, service.ErrInvalidWebhook),
+		errors.Is(err, service.ErrInvalidReminderPreferences),
+		errors.Is(err, taskql.ErrSyntax),
+		errors.Is(err, repository.ErrUnknownSortField),
+		errors.Is(err, repository.ErrInvalidCursor),
+		errors.Is(err, repository.ErrChecklistMismatch):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func respondError(c *gin.Context, err error) {
+	c.JSON(statusFor(err), gin.H{"error": err.Error()})
+}
@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// ETag ресурса — его версия. Тег сильный: им можно пользоваться в If-Match
+func etag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+func setETag(c *gin.Context, version int) {
+	c.Header("ETag", etag(version))
+}
+
+// ifMatch читает версию из If-Match. nil — заголовка нет или он равен "*"; слабый или
+// чужой тег даёт версию 0, которая не совпадает ни с одной
+func ifMatch(c *gin.Context) *int {
+	value := strings.TrimSpace(c.GetHeader("If-Match"))
+	if value == "" || value == "*" {
+		return nil
+	}
+	version, err := strconv.Atoi(strings.Trim(value, `"`))
+	if err != nil || !strings.HasPrefix(value, `"`) {
+		version = 0
+	}
+	return &version
+}
+
+// notModified отвечает 304, если у клиента уже есть эта версия (If-None-Match)
+func notModified(c *gin.Context, version int) bool {
+	for _, tag := range strings.Split(c.GetHeader("If-None-Match"), ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == etag(version) || tag == "*" {
+			setETag(c, version)
+			c.Status(http.StatusNotModified)
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"io"
+	"net/http"
+	"task-tracker/pkg/events"
+	"time"
+)
+
+// Комментарий-пинг не даёт прокси закрыть молчащее соединение
+const eventsHeartbeat = 25 * time.Second
+
+type EventHandler struct {
+	bus *events.Bus
+}
+
+func NewEventHandler(bus *events.Bus) *EventHandler {
+	return &EventHandler{bus: bus}
+}
+
+// Stream — поток Server-Sent Events об изменениях задач и проектов, видимых пользователю.
+// Переподключившийся клиент передаёт Last-Event-ID (EventSource делает это сам) и получает
+// пропущенное; если пропущенное уже не восстановить, первым приходит событие reset —
+// клиенту нужно перечитать данные
+func (h *EventHandler) Stream(c *gin.Context) {
+	userID, err := uuid.Parse(c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user id"})
+		return
+	}
+	lastID := c.GetHeader("Last-Event-ID")
+	if lastID == "" {
+		lastID = c.Query("last_event_id")
+	}
+
+	sub, missed, resumed := h.bus.Subscribe(func(e events.Event) bool {
+		return e.VisibleTo(userID)
+	}, lastID)
+	defer h.bus.Unsubscribe(sub)
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	w := c.Writer
+	io.WriteString(w, "retry: 3000\n\n")
+	if !resumed {
+		fmt.Fprintf(w, "id: %s\nevent: reset\ndata: {}\n\n", h.bus.LastID())
+	}
+	for _, e := range missed {
+		writeEvent(w, e)
+	}
+	w.Flush()
+
+	heartbeat := time.NewTicker(eventsHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case e, ok := <-sub.C():
+			if !ok {
+				// Клиент не успевал читать и отключён шиной; переподключится с Last-Event-ID
+				return
+			}
+			writeEvent(w, e)
+		case <-heartbeat.C:
+			io.WriteString(w, ": ping\n\n")
+		}
+		w.Flush()
+	}
+}
+
+func writeEvent(w io.Writer, e events.Event) {
+	fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", e.ID, e.Type, e.Data)
+}
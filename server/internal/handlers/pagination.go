@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"net/url"
+	"strconv"
+	"strings"
+	"task-tracker/internal/dto"
+)
+
+// parsePageRequest читает page, limit и cursor; limit ограничивается сверху
+func parsePageRequest(c *gin.Context) (dto.PageRequest, error) {
+	req := dto.PageRequest{
+		Page:   1,
+		Limit:  dto.DefaultPageLimit,
+		Cursor: c.Query("cursor"),
+	}
+
+	if v := c.Query("page"); v != "" {
+		page, err := strconv.Atoi(v)
+		if err != nil || page < 1 {
+			return req, errors.New("page must be a positive integer")
+		}
+		req.Page = page
+	}
+	if v := c.Query("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 {
+			return req, errors.New("limit must be a positive integer")
+		}
+		req.Limit = min(limit, dto.MaxPageLimit)
+	}
+	if req.Cursor != "" && c.Query("page") != "" {
+		return req, errors.New("page and cursor cannot be combined")
+	}
+
+	return req, nil
+}
+
+// setLinkHeader выставляет заголовок Link (RFC 8288). Ссылка next всегда
+// курсорная, first и prev есть только при постраничной навигации по номеру.
+func setLinkHeader(c *gin.Context, info dto.PageInfo) {
+	var links []string
+
+	if info.NextCursor != "" {
+		links = append(links, pageLink(c, map[string]string{
+			"cursor": info.NextCursor,
+			"limit":  strconv.Itoa(info.Limit),
+		}, "next"))
+	}
+	if info.Page > 0 {
+		links = append(links, pageLink(c, map[string]string{
+			"page":  "1",
+			"limit": strconv.Itoa(info.Limit),
+		}, "first"))
+		if info.Page > 1 {
+			links = append(links, pageLink(c, map[string]string{
+				"page":  strconv.Itoa(info.Page - 1),
+				"limit": strconv.Itoa(info.Limit),
+			}, "prev"))
+		}
+	}
+
+	if len(links) > 0 {
+		c.Header("Link", strings.Join(links, ", "))
+	}
+}
+
+func pageLink(c *gin.Context, params map[string]string, rel string) string {
+	u := url.URL{Path: c.Request.URL.Path}
+	query := c.Request.URL.Query()
+	query.Del("page")
+	query.Del("cursor")
+	for k, v := range params {
+		query.Set(k, v)
+	}
+	u.RawQuery = query.Encode()
+	return "<" + u.String() + `>; rel="` + rel + `"`
+}
@@ -0,0 +1,28 @@
+package handlers
+
+import (
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+const mergePatchContentType = "application/merge-patch+json"
+
+// bindMergePatch читает документ JSON Merge Patch (RFC 7396). Принимается и обычный
+// application/json; неизвестные поля отклоняются, чтобы опечатка не превращалась в «не менять»
+func bindMergePatch(c *gin.Context, patch any) bool {
+	switch c.ContentType() {
+	case mergePatchContentType, "application/json":
+	default:
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "Content-Type must be " + mergePatchContentType})
+		return false
+	}
+
+	decoder := json.NewDecoder(c.Request.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(patch); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+	return true
+}
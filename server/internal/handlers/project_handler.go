@@ -5,6 +5,8 @@ import (
 	"github.com/google/uuid"
 	"net/http"
 	"strconv"
+	"task-tracker/internal/dto"
+	"task-tracker/internal/models"
 	"task-tracker/internal/service"
 )
 
@@ -25,9 +27,13 @@ func (h *ProjectHandler) CreateProject(c *gin.Context) {
 	}
 
 	var req struct {
-		Name        string `json:"name" binding:"required"`
-		Description string `json:"description"`
-		Color       string `json:"color"`
+		Name         string                    `json:"name" binding:"required"`
+		Key          string                    `json:"key"`
+		Description  string                    `json:"description"`
+		Color        string                    `json:"color"`
+		EstimateUnit models.EstimateUnit       `json:"estimate_unit"`
+		WIPLimits    map[models.TaskStatus]int `json:"wip_limits"`
+		WIPStrict    bool                      `json:"wip_strict"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -36,14 +42,18 @@ func (h *ProjectHandler) CreateProject(c *gin.Context) {
 	}
 
 	projectReq := service.CreateProjectRequest{
-		Name:        req.Name,
-		Description: req.Description,
-		Color:       req.Color,
+		Name:         req.Name,
+		Key:          req.Key,
+		Description:  req.Description,
+		Color:        req.Color,
+		EstimateUnit: req.EstimateUnit,
+		WIPLimits:    req.WIPLimits,
+		WIPStrict:    req.WIPStrict,
 	}
 
 	project, err := h.service.Create(projectReq, reporterID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
@@ -62,7 +72,11 @@ func (h *ProjectHandler) GetProject(c *gin.Context) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
 		return
 	}
+	if notModified(c, project.Version) {
+		return
+	}
 
+	setETag(c, project.Version)
 	c.JSON(http.StatusOK, project)
 }
 
@@ -70,15 +84,19 @@ func (h *ProjectHandler) ListProjects(c *gin.Context) {
 	userID := c.GetString("user_id")
 	reporterID, _ := uuid.Parse(userID)
 
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	page, err := parsePageRequest(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
-	projects, err := h.service.List(reporterID, page, limit)
+	projects, err := h.service.List(reporterID, page)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
+	setLinkHeader(c, projects.PageInfo)
 	c.JSON(http.StatusOK, projects)
 }
 
@@ -90,9 +108,12 @@ func (h *ProjectHandler) UpdateProject(c *gin.Context) {
 	}
 
 	var req struct {
-		Name        string `json:"name"`
-		Description string `json:"description"`
-		Color       string `json:"color"`
+		Name         string                    `json:"name"`
+		Description  string                    `json:"description"`
+		Color        string                    `json:"color"`
+		EstimateUnit models.EstimateUnit       `json:"estimate_unit"`
+		WIPLimits    map[models.TaskStatus]int `json:"wip_limits"`
+		WIPStrict    *bool                     `json:"wip_strict"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -101,17 +122,49 @@ func (h *ProjectHandler) UpdateProject(c *gin.Context) {
 	}
 
 	updateReq := service.UpdateProjectRequest{
-		Name:        req.Name,
-		Description: req.Description,
-		Color:       req.Color,
+		Name:         req.Name,
+		Description:  req.Description,
+		Color:        req.Color,
+		EstimateUnit: req.EstimateUnit,
+		WIPLimits:    req.WIPLimits,
+		WIPStrict:    req.WIPStrict,
+		IfMatch:      ifMatch(c),
 	}
 
 	if err := h.service.Update(id, updateReq); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
-	project, _ := h.service.GetByID(id)
+	project, err := h.service.GetByID(id)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	setETag(c, project.Version)
+	c.JSON(http.StatusOK, project)
+}
+
+// PatchProject частично обновляет проект по правилам JSON Merge Patch
+func (h *ProjectHandler) PatchProject(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid project ID"})
+		return
+	}
+
+	var patch dto.ProjectPatch
+	if !bindMergePatch(c, &patch) {
+		return
+	}
+
+	project, err := h.service.Patch(id, patch, ifMatch(c))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	setETag(c, project.Version)
 	c.JSON(http.StatusOK, project)
 }
 
@@ -122,10 +175,37 @@ func (h *ProjectHandler) DeleteProject(c *gin.Context) {
 		return
 	}
 
-	if err := h.service.Delete(id); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := h.service.Delete(id, ifMatch(c)); err != nil {
+		respondError(c, err)
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Project deleted successfully"})
 }
+
+// GetBoard возвращает доску проекта; ?limit= ограничивает число задач в каждой колонке
+func (h *ProjectHandler) GetBoard(c *gin.Context) {
+	userID, err := uuid.Parse(c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid project ID"})
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "0"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit"})
+		return
+	}
+
+	board, err := h.service.Board(id, userID, limit)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, board)
+}
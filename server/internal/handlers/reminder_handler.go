@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"net/http"
+	"task-tracker/internal/models"
+	"task-tracker/internal/service"
+)
+
+type ReminderHandler struct {
+	service service.ReminderService
+}
+
+func NewReminderHandler(service service.ReminderService) *ReminderHandler {
+	return &ReminderHandler{service: service}
+}
+
+func (h *ReminderHandler) GetPreferences(c *gin.Context) {
+	userID, err := uuid.Parse(c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	prefs, err := h.service.Preferences(userID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, prefs)
+}
+
+// UpdatePreferences заменяет настройки напоминаний целиком
+func (h *ReminderHandler) UpdatePreferences(c *gin.Context) {
+	userID, err := uuid.Parse(c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var req struct {
+		Mode       models.ReminderMode `json:"mode" binding:"required"`
+		DaysBefore int                 `json:"days_before"`
+		RemindAt   string              `json:"remind_at" binding:"required"`
+		Timezone   string              `json:"timezone"`
+		Overdue    bool                `json:"overdue"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	prefs, err := h.service.UpdatePreferences(userID, service.UpdateReminderPreferencesRequest{
+		Mode:       req.Mode,
+		DaysBefore: req.DaysBefore,
+		RemindAt:   req.RemindAt,
+		Timezone:   req.Timezone,
+		Overdue:    req.Overdue,
+	})
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, prefs)
+}
@@ -0,0 +1,211 @@
+package handlers
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"net/http"
+	"task-tracker/internal/models"
+	"task-tracker/internal/service"
+	"time"
+)
+
+type SavedViewHandler struct {
+	service service.SavedViewService
+}
+
+func NewSavedViewHandler(service service.SavedViewService) *SavedViewHandler {
+	return &SavedViewHandler{service: service}
+}
+
+func (h *SavedViewHandler) CreateView(c *gin.Context) {
+	userID, err := uuid.Parse(c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user id"})
+		return
+	}
+
+	var req struct {
+		Name      string              `json:"name" binding:"required"`
+		ProjectID *uuid.UUID          `json:"project_id"`
+		Filters   models.ViewFilters  `json:"filters"`
+		Sort      string              `json:"sort"`
+		GroupBy   models.ViewGrouping `json:"group_by"`
+		Pinned    bool                `json:"pinned"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	view, err := h.service.Create(userID, service.CreateSavedViewRequest{
+		Name:      req.Name,
+		ProjectID: req.ProjectID,
+		Filters:   req.Filters,
+		Sort:      req.Sort,
+		GroupBy:   req.GroupBy,
+		Pinned:    req.Pinned,
+	})
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, view)
+}
+
+// ListViews возвращает личные представления и общие представления видимых проектов; ?project_id= сужает до одного проекта
+func (h *SavedViewHandler) ListViews(c *gin.Context) {
+	userID, err := uuid.Parse(c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user id"})
+		return
+	}
+
+	var projectID *uuid.UUID
+	if pid := c.Query("project_id"); pid != "" {
+		parsed, err := uuid.Parse(pid)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid project id"})
+			return
+		}
+		projectID = &parsed
+	}
+
+	views, err := h.service.List(userID, projectID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, views)
+}
+
+func (h *SavedViewHandler) GetView(c *gin.Context) {
+	userID, id, ok := parseViewParams(c)
+	if !ok {
+		return
+	}
+
+	view, err := h.service.Get(id, userID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, view)
+}
+
+func (h *SavedViewHandler) UpdateView(c *gin.Context) {
+	userID, id, ok := parseViewParams(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Name    string               `json:"name"`
+		Filters *models.ViewFilters  `json:"filters"`
+		Sort    *string              `json:"sort"`
+		GroupBy *models.ViewGrouping `json:"group_by"`
+		Pinned  *bool                `json:"pinned"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	view, err := h.service.Update(id, userID, service.UpdateSavedViewRequest{
+		Name:    req.Name,
+		Filters: req.Filters,
+		Sort:    req.Sort,
+		GroupBy: req.GroupBy,
+		Pinned:  req.Pinned,
+	})
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, view)
+}
+
+func (h *SavedViewHandler) DeleteView(c *gin.Context) {
+	userID, id, ok := parseViewParams(c)
+	if !ok {
+		return
+	}
+
+	if err := h.service.Delete(id, userID); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "View deleted successfully"})
+}
+
+// PinView закрепляет представление в боковой панели текущего пользователя
+func (h *SavedViewHandler) PinView(c *gin.Context) {
+	h.setPinned(c, true)
+}
+
+// UnpinView снимает закрепление только у текущего пользователя
+func (h *SavedViewHandler) UnpinView(c *gin.Context) {
+	h.setPinned(c, false)
+}
+
+func (h *SavedViewHandler) setPinned(c *gin.Context, pinned bool) {
+	userID, id, ok := parseViewParams(c)
+	if !ok {
+		return
+	}
+
+	view, err := h.service.SetPinned(id, userID, pinned)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, view)
+}
+
+// ExecuteView возвращает задачи представления с обычной пагинацией; ?tz= задаёт «сегодня» для сроков
+func (h *SavedViewHandler) ExecuteView(c *gin.Context) {
+	userID, id, ok := parseViewParams(c)
+	if !ok {
+		return
+	}
+
+	loc, err := time.LoadLocation(c.DefaultQuery("tz", "UTC"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid timezone"})
+		return
+	}
+
+	page, err := parsePageRequest(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := h.service.Execute(id, userID, loc, page)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	setLinkHeader(c, result.PageInfo)
+	c.JSON(http.StatusOK, result)
+}
+
+func parseViewParams(c *gin.Context) (uuid.UUID, uuid.UUID, bool) {
+	userID, err := uuid.Parse(c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user id"})
+		return uuid.Nil, uuid.Nil, false
+	}
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid view id"})
+		return uuid.Nil, uuid.Nil, false
+	}
+	return userID, id, true
+}
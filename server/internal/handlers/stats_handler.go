@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"net/http"
+	"task-tracker/internal/service"
+	"time"
+)
+
+type StatsHandler struct {
+	service service.StatsService
+}
+
+func NewStatsHandler(service service.StatsService) *StatsHandler {
+	return &StatsHandler{service: service}
+}
+
+// Overview возвращает сводку для дашборда; ?tz= (IANA, по умолчанию UTC)
+// определяет, какой день считать сегодняшним
+func (h *StatsHandler) Overview(c *gin.Context) {
+	userID, err := uuid.Parse(c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user id"})
+		return
+	}
+
+	loc, err := time.LoadLocation(c.DefaultQuery("tz", "UTC"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid timezone"})
+		return
+	}
+
+	overview, err := h.service.Overview(userID, loc)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, overview)
+}
@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"net/http"
+	"strconv"
+	"task-tracker/internal/dto"
+	"task-tracker/internal/service"
+)
+
+type SyncHandler struct {
+	service service.SyncService
+}
+
+func NewSyncHandler(service service.SyncService) *SyncHandler {
+	return &SyncHandler{service: service}
+}
+
+// Pull отдаёт изменения после курсора ?since=; без курсора — полную выгрузку постранично
+func (h *SyncHandler) Pull(c *gin.Context) {
+	userID, err := uuid.Parse(c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user id"})
+		return
+	}
+
+	limit := 0
+	if value := c.Query("limit"); value != "" {
+		if limit, err = strconv.Atoi(value); err != nil || limit < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit"})
+			return
+		}
+	}
+
+	changes, err := h.service.Changes(userID, c.Query("since"), limit)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, changes)
+}
+
+// Push применяет офлайн-изменения клиента. Ответ всегда 200: исход каждой мутации —
+// в results, конфликтующие возвращаются с текущим состоянием объекта
+func (h *SyncHandler) Push(c *gin.Context) {
+	userID, err := uuid.Parse(c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user id"})
+		return
+	}
+
+	var req struct {
+		Mutations []dto.SyncMutation `json:"mutations" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := h.service.Push(userID, req.Mutations)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	for i := range result.Results {
+		item := &result.Results[i]
+		switch {
+		case item.Err != nil:
+			item.Status = statusFor(item.Err)
+		case req.Mutations[i].Method == dto.BatchCreate:
+			item.Status = http.StatusCreated
+		default:
+			item.Status = http.StatusOK
+		}
+	}
+	c.JSON(http.StatusOK, result)
+}
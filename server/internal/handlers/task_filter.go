@@ -0,0 +1,176 @@
+package handlers
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"slices"
+	"strconv"
+	"strings"
+	"task-tracker/internal/dto"
+	"task-tracker/internal/models"
+	"task-tracker/internal/service"
+	"time"
+)
+
+// parseTaskFilter читает параметры списка задач. Списки значений передаются через
+// запятую (status=todo,in_progress), диапазоны — парами *_from/*_to: дата (YYYY-MM-DD,
+// обе границы включительно) или момент времени в RFC 3339. Даты создания и изменения
+// отсчитываются в поясе tz (IANA, по умолчанию UTC). Параметр q — запрос на языке pkg/taskql,
+// его условия складываются с остальными параметрами
+func parseTaskFilter(c *gin.Context, userID uuid.UUID) (dto.TaskFilter, error) {
+	filter := dto.TaskFilter{
+		UserID: &userID,
+		Search: c.Query("search"),
+	}
+
+	loc, err := time.LoadLocation(c.DefaultQuery("tz", "UTC"))
+	if err != nil {
+		return filter, fmt.Errorf("invalid tz %q", c.Query("tz"))
+	}
+	filter.Today = service.DueDateToday(time.Now(), loc)
+	filter.Location = loc
+	filter.Query = c.Query("q")
+
+	if pid := c.Query("project_id"); pid == "none" {
+		filter.NoProject = true
+	} else if pid != "" {
+		parsed, err := uuid.Parse(pid)
+		if err != nil {
+			return filter, fmt.Errorf("invalid project_id %q: expected UUID or \"none\"", pid)
+		}
+		filter.ProjectID = &parsed
+	}
+
+	if parent := c.Query("parent_id"); parent != "" {
+		parsed, err := uuid.Parse(parent)
+		if err != nil {
+			return filter, fmt.Errorf("invalid parent_id %q", parent)
+		}
+		filter.ParentID = &parsed
+	}
+
+	if filter.Statuses, err = parseEnumList(c, "status", models.TaskStatuses); err != nil {
+		return filter, err
+	}
+	if filter.Priorities, err = parseEnumList(c, "priority", models.TaskPriorities); err != nil {
+		return filter, err
+	}
+
+	if filter.EstimateMin, err = parseFloatQuery(c, "estimate_min"); err != nil {
+		return filter, fmt.Errorf("invalid estimate_min %q", c.Query("estimate_min"))
+	}
+	if filter.EstimateMax, err = parseFloatQuery(c, "estimate_max"); err != nil {
+		return filter, fmt.Errorf("invalid estimate_max %q", c.Query("estimate_max"))
+	}
+	if filter.HasEstimate, err = parseBoolQuery(c, "has_estimate"); err != nil {
+		return filter, err
+	}
+
+	// Сроки хранятся как полночь UTC, поэтому даты в них разбираются без пояса
+	if filter.DueFrom, filter.DueTo, err = parseRangeQuery(c, "due", time.UTC); err != nil {
+		return filter, err
+	}
+	if filter.CreatedFrom, filter.CreatedTo, err = parseRangeQuery(c, "created", loc); err != nil {
+		return filter, err
+	}
+	if filter.UpdatedFrom, filter.UpdatedTo, err = parseRangeQuery(c, "updated", loc); err != nil {
+		return filter, err
+	}
+
+	noDueDate, err := parseBoolQuery(c, "no_due_date")
+	if err != nil {
+		return filter, err
+	}
+	if noDueDate != nil {
+		hasDueDate := !*noDueDate
+		filter.HasDueDate = &hasDueDate
+	}
+	if filter.Overdue, err = parseBoolQuery(c, "overdue"); err != nil {
+		return filter, err
+	}
+
+	filter.Sort = dto.ParseSort(c.Query("sort"))
+	return filter, nil
+}
+
+// parseEnumList разбирает список через запятую и проверяет каждое значение по allowed
+func parseEnumList[T ~string](c *gin.Context, name string, allowed []T) ([]T, error) {
+	v := c.Query(name)
+	if v == "" {
+		return nil, nil
+	}
+
+	var values []T
+	for _, part := range strings.Split(v, ",") {
+		value := T(strings.TrimSpace(part))
+		if !slices.Contains(allowed, value) {
+			return nil, fmt.Errorf("invalid %s %q: expected one of %v", name, value, allowed)
+		}
+		if !slices.Contains(values, value) {
+			values = append(values, value)
+		}
+	}
+	return values, nil
+}
+
+func parseBoolQuery(c *gin.Context, name string) (*bool, error) {
+	v := c.Query(name)
+	if v == "" {
+		return nil, nil
+	}
+	parsed, err := strconv.ParseBool(v)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s %q: expected true or false", name, v)
+	}
+	return &parsed, nil
+}
+
+func parseFloatQuery(c *gin.Context, name string) (*float64, error) {
+	v := c.Query(name)
+	if v == "" {
+		return nil, nil
+	}
+	parsed, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return nil, err
+	}
+	return &parsed, nil
+}
+
+// parseRangeQuery читает <prefix>_from и <prefix>_to и возвращает полуинтервал [from, to):
+// верхняя граница-дата сдвигается на следующий день, чтобы сама дата вошла в диапазон
+func parseRangeQuery(c *gin.Context, prefix string, loc *time.Location) (*time.Time, *time.Time, error) {
+	fromName, toName := prefix+"_from", prefix+"_to"
+
+	from, _, err := parseTimeQuery(c.Query(fromName), loc)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid %s %q: expected YYYY-MM-DD or RFC 3339", fromName, c.Query(fromName))
+	}
+	to, dateOnly, err := parseTimeQuery(c.Query(toName), loc)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid %s %q: expected YYYY-MM-DD or RFC 3339", toName, c.Query(toName))
+	}
+	if to != nil && dateOnly {
+		next := to.AddDate(0, 0, 1)
+		to = &next
+	}
+	if from != nil && to != nil && !to.After(*from) {
+		return nil, nil, fmt.Errorf("%s must not be after %s", fromName, toName)
+	}
+	return from, to, nil
+}
+
+func parseTimeQuery(v string, loc *time.Location) (*time.Time, bool, error) {
+	if v == "" {
+		return nil, false, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", v, loc); err == nil {
+		return &t, true, nil
+	}
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		return nil, false, err
+	}
+	return &t, false, nil
+}
@@ -1,13 +1,17 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 	"net/http"
-	"strconv"
+	"strings"
 	"task-tracker/internal/dto"
 	"task-tracker/internal/models"
 	"task-tracker/internal/service"
+	"task-tracker/pkg/taskql"
 	"time"
 )
 
@@ -32,6 +36,10 @@ func (h *TaskHandler) CreateTask(c *gin.Context) {
 		Priority    models.TaskPriority `json:"priority" binding:"required,oneof=low medium high"`
 		DueDate     *string             `json:"due_date"`
 		ProjectID   *uuid.UUID          `json:"project_id"`
+		Recurrence  string              `json:"recurrence"`
+		Timezone    string              `json:"recurrence_timezone"`
+		Estimate    *float64            `json:"estimate"`
+		Remaining   *float64            `json:"remaining_estimate"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -39,26 +47,26 @@ func (h *TaskHandler) CreateTask(c *gin.Context) {
 		return
 	}
 
-	var dueDate *time.Time
-	if req.DueDate != nil {
-		t, err := time.Parse("2006-01-02", *req.DueDate)
-		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date format (YYYY-MM-DD)"})
-			return
-		}
-		dueDate = &t
+	dueDate, err := parseDueDate(req.DueDate)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date format (YYYY-MM-DD)"})
+		return
 	}
 
 	task, err := h.service.Create(service.CreateTaskRequest{
-		Title:       req.Title,
-		Description: req.Description,
-		Priority:    req.Priority,
-		DueDate:     dueDate,
-		ProjectID:   req.ProjectID, // nil → NULL
+		Title:              req.Title,
+		Description:        req.Description,
+		Priority:           req.Priority,
+		DueDate:            dueDate,
+		ProjectID:          req.ProjectID, // nil → NULL
+		Recurrence:         req.Recurrence,
+		RecurrenceTimezone: req.Timezone,
+		Estimate:           req.Estimate,
+		RemainingEstimate:  req.Remaining,
 	}, userID)
 
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
@@ -77,7 +85,11 @@ func (h *TaskHandler) GetTask(c *gin.Context) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
 		return
 	}
+	if notModified(c, task.Version) {
+		return
+	}
 
+	setETag(c, task.Version)
 	c.JSON(http.StatusOK, task)
 }
 
@@ -88,35 +100,30 @@ func (h *TaskHandler) ListTasks(c *gin.Context) {
 		return
 	}
 
-	filter := dto.TaskFilter{
-		UserID: &userID,
-	}
-
-	if pid := c.Query("project_id"); pid != "" {
-		if parsed, err := uuid.Parse(pid); err == nil {
-			filter.ProjectID = &parsed
-		}
+	filter, err := parseTaskFilter(c, userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
 
-	if status := c.Query("status"); status != "" {
-		filter.Status = models.TaskStatus(status)
+	page, err := parsePageRequest(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
 
-	if priority := c.Query("priority"); priority != "" {
-		filter.Priority = models.TaskPriority(priority)
+	tasks, err := h.service.List(filter, page)
+	var queryErr *taskql.Error
+	if errors.As(err, &queryErr) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": queryErr.Error(), "position": queryErr.Pos})
+		return
 	}
-
-	filter.Search = c.Query("search")
-
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
-
-	tasks, err := h.service.List(filter, page, limit)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
+	setLinkHeader(c, tasks.PageInfo)
 	c.JSON(http.StatusOK, tasks)
 }
 
@@ -126,6 +133,11 @@ func (h *TaskHandler) UpdateTask(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid task id"})
 		return
 	}
+	userID, err := uuid.Parse(c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user id"})
+		return
+	}
 
 	var req struct {
 		Title       string              `json:"title"`
@@ -134,6 +146,10 @@ func (h *TaskHandler) UpdateTask(c *gin.Context) {
 		Status      models.TaskStatus   `json:"status"`
 		DueDate     *string             `json:"due_date"`
 		ProjectID   *uuid.UUID          `json:"project_id"`
+		Recurrence  string              `json:"recurrence"`
+		Timezone    string              `json:"recurrence_timezone"`
+		Estimate    *float64            `json:"estimate"`
+		Remaining   *float64            `json:"remaining_estimate"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -141,31 +157,38 @@ func (h *TaskHandler) UpdateTask(c *gin.Context) {
 		return
 	}
 
-	var dueDate *time.Time
-	if req.DueDate != nil {
-		t, err := time.Parse("2006-01-02", *req.DueDate)
-		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date format"})
-			return
-		}
-		dueDate = &t
+	dueDate, err := parseDueDate(req.DueDate)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date format"})
+		return
 	}
 
-	err = h.service.Update(id, service.UpdateTaskRequest{
-		Title:       req.Title,
-		Description: req.Description,
-		Priority:    req.Priority,
-		Status:      req.Status,
-		DueDate:     dueDate,
-		ProjectID:   req.ProjectID, // nil → отвязать
+	err = h.service.Update(id, userID, service.UpdateTaskRequest{
+		Title:              req.Title,
+		Description:        req.Description,
+		Priority:           req.Priority,
+		Status:             req.Status,
+		DueDate:            dueDate,
+		ProjectID:          req.ProjectID, // nil — не менять; отвязать можно через PATCH
+		Recurrence:         req.Recurrence,
+		RecurrenceTimezone: req.Timezone,
+		Estimate:           req.Estimate,
+		RemainingEstimate:  req.Remaining,
+		IfMatch:            ifMatch(c),
 	})
 
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
-	task, _ := h.service.GetByID(id)
+	// Между записью и чтением задачу могли удалить: отвечаем 404, а не null без ETag
+	task, err := h.service.GetByID(id)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	setETag(c, task.Version)
 	c.JSON(http.StatusOK, task)
 }
 
@@ -185,15 +208,204 @@ func (h *TaskHandler) UpdateTaskStatus(c *gin.Context) {
 		return
 	}
 
-	if err := h.service.UpdateStatus(id, req.Status); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	warning, err := h.service.UpdateStatus(id, req.Status, ifMatch(c))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	setWIPWarning(c, warning)
+
+	task, err := h.service.GetByID(id)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	setETag(c, task.Version)
+	c.JSON(http.StatusOK, task)
+}
+
+func (h *TaskHandler) SkipOccurrence(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid task id"})
+		return
+	}
+
+	task, err := h.service.SkipOccurrence(id)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, task)
+}
+
+func (h *TaskHandler) StopRecurrence(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid task id"})
+		return
+	}
+
+	task, err := h.service.StopRecurrence(id)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, task)
+}
+
+// MoveTask переставляет задачу на доске: {"status": "in_progress", "after_id": "..."}
+// или "before_id"; без соседа задача уходит в конец колонки
+func (h *TaskHandler) MoveTask(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid task id"})
+		return
+	}
+
+	var req struct {
+		Status   models.TaskStatus `json:"status" binding:"omitempty,oneof=todo in_progress done"`
+		AfterID  *uuid.UUID        `json:"after_id"`
+		BeforeID *uuid.UUID        `json:"before_id"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	task, warning, err := h.service.Move(id, service.MoveTaskRequest{
+		Status:   req.Status,
+		AfterID:  req.AfterID,
+		BeforeID: req.BeforeID,
+	})
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	setWIPWarning(c, warning)
+	c.JSON(http.StatusOK, task)
+}
+
+// BulkTasks применяет одно изменение к списку задач (ids) или к задачам по фильтру (filter,
+// как у сохранённых представлений) в одной транзакции и возвращает результат по каждой задаче
+func (h *TaskHandler) BulkTasks(c *gin.Context) {
+	userID, err := uuid.Parse(c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user id"})
+		return
+	}
+
+	var req struct {
+		IDs          []uuid.UUID         `json:"ids"`
+		Filter       *models.ViewFilters `json:"filter"`
+		Delete       bool                `json:"delete"`
+		Status       models.TaskStatus   `json:"status"`
+		Priority     models.TaskPriority `json:"priority"`
+		ProjectID    *uuid.UUID          `json:"project_id"`
+		NoProject    bool                `json:"no_project"`
+		AssigneeID   *uuid.UUID          `json:"assignee_id"`
+		DueDate      *string             `json:"due_date"`
+		ClearDueDate bool                `json:"clear_due_date"`
+		Labels       []string            `json:"labels"`
+		AllOrNothing bool                `json:"all_or_nothing"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	// Меток и исполнителей у задач пока нет: лучше отказать, чем молча проигнорировать.
+	// Владельца задачи (user_id) массовой операцией не меняем
+	if req.Labels != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Labels are not supported"})
+		return
+	}
+	if req.AssigneeID != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Assignees are not supported"})
+		return
+	}
+
+	dueDate, err := parseDueDate(req.DueDate)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid due date"})
+		return
+	}
+	loc, err := time.LoadLocation(c.DefaultQuery("tz", "UTC"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid timezone"})
+		return
+	}
+
+	result, err := h.service.Bulk(userID, service.BulkTaskRequest{
+		IDs:          req.IDs,
+		Filter:       req.Filter,
+		Location:     loc,
+		Delete:       req.Delete,
+		Status:       req.Status,
+		Priority:     req.Priority,
+		ProjectID:    req.ProjectID,
+		NoProject:    req.NoProject,
+		DueDate:      dueDate,
+		ClearDueDate: req.ClearDueDate,
+		AllOrNothing: req.AllOrNothing,
+	})
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// setWIPWarning сообщает о превышении нестрогого WIP-лимита заголовком Warning
+func setWIPWarning(c *gin.Context, w *dto.WIPWarning) {
+	if w == nil {
+		return
+	}
+	c.Header("Warning", fmt.Sprintf(`299 - "WIP limit of %s exceeded: %d of %d"`, w.Status, w.Count, w.Limit))
+}
+
+// PatchTask частично обновляет задачу: null очищает поле, отсутствующие поля не меняются
+func (h *TaskHandler) PatchTask(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid task id"})
+		return
+	}
+	userID, err := uuid.Parse(c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user id"})
+		return
+	}
+
+	var patch dto.TaskPatch
+	if !bindMergePatch(c, &patch) {
+		return
+	}
+
+	task, err := h.service.Patch(id, userID, patch, ifMatch(c))
+	if err != nil {
+		respondError(c, err)
 		return
 	}
 
-	task, _ := h.service.GetByID(id)
+	setETag(c, task.Version)
 	c.JSON(http.StatusOK, task)
 }
 
+func parseDueDate(value *string) (*time.Time, error) {
+	if value == nil {
+		return nil, nil
+	}
+	t, err := dto.ParseDueDate(*value)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
 func (h *TaskHandler) DeleteTask(c *gin.Context) {
 	id, err := uuid.Parse(c.Param("id"))
 	if err != nil {
@@ -201,10 +413,43 @@ func (h *TaskHandler) DeleteTask(c *gin.Context) {
 		return
 	}
 
-	if err := h.service.Delete(id); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := h.service.Delete(id, ifMatch(c)); err != nil {
+		respondError(c, err)
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Task deleted successfully"})
 }
+
+// ResolveTaskKey позволяет обращаться к задаче по ключу (/api/tasks/API-42/...) вместо UUID:
+// подменяет параметр :id найденным идентификатором до вызова обработчика
+func (h *TaskHandler) ResolveTaskKey() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !strings.HasPrefix(c.FullPath(), "/api/tasks/:id") {
+			c.Next()
+			return
+		}
+		ref := c.Param("id")
+		if _, err := uuid.Parse(ref); err == nil {
+			c.Next()
+			return
+		}
+
+		id, err := h.service.ResolveKey(ref)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Task not found"})
+				return
+			}
+			c.AbortWithStatusJSON(statusFor(err), gin.H{"error": err.Error()})
+			return
+		}
+
+		for i := range c.Params {
+			if c.Params[i].Key == "id" {
+				c.Params[i].Value = id.String()
+			}
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,294 @@
+package handlers
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"net/http"
+	"strings"
+	"task-tracker/internal/dto"
+	"task-tracker/internal/service"
+	"time"
+)
+
+type TimeTrackingHandler struct {
+	service service.TimeTrackingService
+}
+
+func NewTimeTrackingHandler(service service.TimeTrackingService) *TimeTrackingHandler {
+	return &TimeTrackingHandler{service: service}
+}
+
+func (h *TimeTrackingHandler) StartTimer(c *gin.Context) {
+	userID, err := uuid.Parse(c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user id"})
+		return
+	}
+	taskID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid task id"})
+		return
+	}
+
+	var req struct {
+		Note string `json:"note"`
+	}
+	// Тело необязательно
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	entry, err := h.service.StartTimer(taskID, userID, req.Note)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, entry)
+}
+
+func (h *TimeTrackingHandler) StopTimer(c *gin.Context) {
+	userID, err := uuid.Parse(c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user id"})
+		return
+	}
+
+	entry, err := h.service.StopTimer(userID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, entry)
+}
+
+func (h *TimeTrackingHandler) CurrentTimer(c *gin.Context) {
+	userID, err := uuid.Parse(c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user id"})
+		return
+	}
+
+	entry, err := h.service.CurrentTimer(userID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"timer": entry})
+}
+
+func (h *TimeTrackingHandler) ListEntries(c *gin.Context) {
+	taskID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid task id"})
+		return
+	}
+
+	entries, err := h.service.ListEntries(taskID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, entries)
+}
+
+func (h *TimeTrackingHandler) CreateEntry(c *gin.Context) {
+	userID, err := uuid.Parse(c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user id"})
+		return
+	}
+	taskID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid task id"})
+		return
+	}
+
+	var req struct {
+		StartedAt       time.Time  `json:"started_at" binding:"required"`
+		EndedAt         *time.Time `json:"ended_at"`
+		DurationMinutes int        `json:"duration_minutes"`
+		Note            string     `json:"note"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Запись задаётся либо концом интервала, либо длительностью
+	var endedAt time.Time
+	switch {
+	case req.EndedAt != nil && req.DurationMinutes == 0:
+		endedAt = *req.EndedAt
+	case req.EndedAt == nil && req.DurationMinutes > 0:
+		endedAt = req.StartedAt.Add(time.Duration(req.DurationMinutes) * time.Minute)
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Provide either ended_at or a positive duration_minutes"})
+		return
+	}
+
+	entry, err := h.service.AddEntry(taskID, userID, service.ManualTimeEntryRequest{
+		StartedAt: req.StartedAt,
+		EndedAt:   endedAt,
+		Note:      req.Note,
+	})
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, entry)
+}
+
+func (h *TimeTrackingHandler) UpdateEntry(c *gin.Context) {
+	userID, err := uuid.Parse(c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user id"})
+		return
+	}
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid time entry id"})
+		return
+	}
+
+	var req struct {
+		StartedAt *time.Time `json:"started_at"`
+		EndedAt   *time.Time `json:"ended_at"`
+		Note      *string    `json:"note"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	entry, err := h.service.UpdateEntry(id, userID, service.UpdateTimeEntryRequest{
+		StartedAt: req.StartedAt,
+		EndedAt:   req.EndedAt,
+		Note:      req.Note,
+	})
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, entry)
+}
+
+func (h *TimeTrackingHandler) DeleteEntry(c *gin.Context) {
+	userID, err := uuid.Parse(c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user id"})
+		return
+	}
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid time entry id"})
+		return
+	}
+
+	if err := h.service.DeleteEntry(id, userID); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Time entry deleted successfully"})
+}
+
+// Report агрегирует учтённое время: ?from=YYYY-MM-DD&to=YYYY-MM-DD (включительно),
+// project_id, user_id, group_by=project,user,day и tz (IANA, по умолчанию UTC)
+func (h *TimeTrackingHandler) Report(c *gin.Context) {
+	viewerID, err := uuid.Parse(c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user id"})
+		return
+	}
+
+	tz := c.DefaultQuery("tz", "UTC")
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid timezone"})
+		return
+	}
+
+	now := time.Now().In(loc)
+	from := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, loc)
+	to := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+	if v := c.Query("from"); v != "" {
+		if from, err = time.ParseInLocation("2006-01-02", v, loc); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid from date (YYYY-MM-DD)"})
+			return
+		}
+	}
+	if v := c.Query("to"); v != "" {
+		if to, err = time.ParseInLocation("2006-01-02", v, loc); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid to date (YYYY-MM-DD)"})
+			return
+		}
+	}
+
+	filter := dto.TimeReportFilter{
+		ViewerID: viewerID,
+		From:     from,
+		To:       to.AddDate(0, 0, 1),
+		Timezone: tz,
+	}
+
+	if pid := c.Query("project_id"); pid != "" {
+		parsed, err := uuid.Parse(pid)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid project id"})
+			return
+		}
+		filter.ProjectID = &parsed
+	}
+	if uid := c.Query("user_id"); uid != "" {
+		parsed, err := uuid.Parse(uid)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user id"})
+			return
+		}
+		filter.UserID = &parsed
+	}
+	if groupBy := c.Query("group_by"); groupBy != "" {
+		for _, g := range strings.Split(groupBy, ",") {
+			switch strings.TrimSpace(g) {
+			case "project":
+				filter.GroupByProject = true
+			case "user":
+				filter.GroupByUser = true
+			case "day":
+				filter.GroupByDay = true
+			default:
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown group_by value: " + g})
+				return
+			}
+		}
+	}
+
+	rows, err := h.service.Report(filter)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	var total int64
+	for _, row := range rows {
+		total += row.Seconds
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"from":          from.Format("2006-01-02"),
+		"to":            to.Format("2006-01-02"),
+		"rows":          rows,
+		"total_seconds": total,
+	})
+}
@@ -0,0 +1,191 @@
+package handlers
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"net/http"
+	"strconv"
+	"task-tracker/internal/models"
+	"task-tracker/internal/service"
+)
+
+type WebhookHandler struct {
+	service service.WebhookService
+}
+
+func NewWebhookHandler(service service.WebhookService) *WebhookHandler {
+	return &WebhookHandler{service: service}
+}
+
+// CreateWebhook регистрирует вебхук проекта; ключ подписи возвращается только в этом ответе
+func (h *WebhookHandler) CreateWebhook(c *gin.Context) {
+	userID, projectID, ok := parseProjectParams(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		URL    string   `json:"url" binding:"required"`
+		Events []string `json:"events" binding:"required"`
+		Active *bool    `json:"active"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	webhook, err := h.service.Create(projectID, userID, service.CreateWebhookRequest{
+		URL:    req.URL,
+		Events: req.Events,
+		Active: req.Active,
+	})
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, webhook)
+}
+
+func (h *WebhookHandler) ListWebhooks(c *gin.Context) {
+	userID, projectID, ok := parseProjectParams(c)
+	if !ok {
+		return
+	}
+
+	webhooks, err := h.service.List(projectID, userID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, webhooks)
+}
+
+func (h *WebhookHandler) GetWebhook(c *gin.Context) {
+	userID, projectID, id, ok := parseWebhookParams(c)
+	if !ok {
+		return
+	}
+
+	webhook, err := h.service.Get(projectID, id, userID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, webhook)
+}
+
+// UpdateWebhook меняет адрес, подписки или активность; "active": true включает отключённый вебхук
+func (h *WebhookHandler) UpdateWebhook(c *gin.Context) {
+	userID, projectID, id, ok := parseWebhookParams(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		URL    string   `json:"url"`
+		Events []string `json:"events"`
+		Active *bool    `json:"active"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	webhook, err := h.service.Update(projectID, id, userID, service.UpdateWebhookRequest{
+		URL:    req.URL,
+		Events: req.Events,
+		Active: req.Active,
+	})
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, webhook)
+}
+
+func (h *WebhookHandler) DeleteWebhook(c *gin.Context) {
+	userID, projectID, id, ok := parseWebhookParams(c)
+	if !ok {
+		return
+	}
+
+	if err := h.service.Delete(projectID, id, userID); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Webhook deleted successfully"})
+}
+
+// ListDeliveries возвращает журнал доставок, новые первыми; ?status= и ?limit= сужают выборку
+func (h *WebhookHandler) ListDeliveries(c *gin.Context) {
+	userID, projectID, id, ok := parseWebhookParams(c)
+	if !ok {
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "0"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit"})
+		return
+	}
+
+	deliveries, err := h.service.Deliveries(projectID, id, userID, models.DeliveryStatus(c.Query("status")), limit)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, deliveries)
+}
+
+// Redeliver ставит в очередь повторную отправку события из журнала
+func (h *WebhookHandler) Redeliver(c *gin.Context) {
+	userID, projectID, id, ok := parseWebhookParams(c)
+	if !ok {
+		return
+	}
+	deliveryID, err := uuid.Parse(c.Param("delivery_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid delivery id"})
+		return
+	}
+
+	delivery, err := h.service.Redeliver(projectID, id, deliveryID, userID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, delivery)
+}
+
+func parseProjectParams(c *gin.Context) (uuid.UUID, uuid.UUID, bool) {
+	userID, err := uuid.Parse(c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user id"})
+		return uuid.Nil, uuid.Nil, false
+	}
+	projectID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid project ID"})
+		return uuid.Nil, uuid.Nil, false
+	}
+	return userID, projectID, true
+}
+
+func parseWebhookParams(c *gin.Context) (uuid.UUID, uuid.UUID, uuid.UUID, bool) {
+	userID, projectID, ok := parseProjectParams(c)
+	if !ok {
+		return uuid.Nil, uuid.Nil, uuid.Nil, false
+	}
+	id, err := uuid.Parse(c.Param("webhook_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid webhook id"})
+		return uuid.Nil, uuid.Nil, uuid.Nil, false
+	}
+	return userID, projectID, id, true
+}
@@ -0,0 +1,27 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"log"
+	"net/http"
+)
+
+// RequireAdmin пропускает только пользователей, для которых isAdmin вернул true.
+// Ставится после AuthMiddleware
+func RequireAdmin(isAdmin func(userID string) (bool, error)) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ok, err := isAdmin(c.GetString("user_id"))
+		if err != nil {
+			log.Printf("admin check failed: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+			c.Abort()
+			return
+		}
+		if !ok {
+			c.JSON(http.StatusForbidden, gin.H{"error": "admin access required"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,177 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/gin-gonic/gin"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"task-tracker/pkg/idempotency"
+	"time"
+)
+
+const (
+	IdempotencyKeyHeader = "Idempotency-Key"
+	maxIdempotencyKey    = 255
+
+	// Тело до этого размера держим в памяти, большее (вложения) — во временном файле
+	maxBufferedBody = 1 << 20
+)
+
+// Заголовки ответа, которые повторяются вместе с телом
+var replayedHeaders = []string{"Content-Type", "Location", "ETag", "Warning", "Link"}
+
+// Idempotency делает POST-запросы с заголовком Idempotency-Key безопасными для повтора:
+// первый запрос выполняется и его ответ сохраняется на ttl, повтор с тем же ключом и
+// тем же запросом получает сохранённый ответ, а с другим запросом — 422. Пока запрос
+// выполняется, ключ занят не дольше lock: после сбоя процесса повтор снова пройдёт.
+// Ключи действуют в пределах пользователя, поэтому middleware ставится после AuthMiddleware
+func Idempotency(store idempotency.Store, ttl, lock time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyKeyHeader)
+		if c.Request.Method != http.MethodPost || key == "" {
+			c.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKey {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Idempotency-Key is too long"})
+			return
+		}
+
+		fingerprint, cleanup, err := fingerprintRequest(c.Request)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Failed to read request body"})
+			return
+		}
+		defer cleanup()
+
+		ctx := c.Request.Context()
+		scope := c.GetString("user_id")
+		existing, token, err := store.Reserve(ctx, scope, key, fingerprint, lock)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if token == "" {
+			replay(c, existing, fingerprint)
+			return
+		}
+
+		recorder := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+		completed := false
+		defer func() {
+			// Ошибки сервера и паники не сохраняем: ключ освобождается, запрос можно повторить
+			if !completed {
+				logIdempotencyError(store.Release(context.WithoutCancel(ctx), scope, key, token))
+			}
+		}()
+
+		c.Next()
+
+		status := recorder.Status()
+		if status >= http.StatusInternalServerError {
+			return
+		}
+		header := make(map[string]string)
+		for _, name := range replayedHeaders {
+			if value := recorder.Header().Get(name); value != "" {
+				header[name] = value
+			}
+		}
+		if err := store.Complete(context.WithoutCancel(ctx), scope, key, token, status, header, recorder.body.Bytes(), ttl); err != nil {
+			logIdempotencyError(err)
+			return
+		}
+		completed = true
+	}
+}
+
+func replay(c *gin.Context, rec *idempotency.Record, fingerprint string) {
+	switch {
+	case rec.Fingerprint != fingerprint:
+		c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{"error": "Idempotency-Key was already used for a different request"})
+	case rec.Status == 0:
+		c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": "A request with this Idempotency-Key is still in progress"})
+	default:
+		for name, value := range rec.Header {
+			c.Header(name, value)
+		}
+		c.Header("Idempotent-Replayed", "true")
+		c.Status(rec.Status)
+		c.Writer.Write(rec.Body)
+		c.Abort()
+	}
+}
+
+// fingerprintRequest хэширует метод, путь с параметрами и тело запроса и подменяет тело
+// копией, чтобы обработчик прочитал его заново
+func fingerprintRequest(r *http.Request) (string, func(), error) {
+	hash := sha256.New()
+	io.WriteString(hash, r.Method+" "+r.URL.Path+"?"+r.URL.RawQuery+"\n")
+
+	cleanup := func() {}
+	if r.Body == nil || r.Body == http.NoBody {
+		return hex.EncodeToString(hash.Sum(nil)), cleanup, nil
+	}
+
+	var buf bytes.Buffer
+	n, err := io.Copy(io.MultiWriter(hash, &buf), io.LimitReader(r.Body, maxBufferedBody+1))
+	if err != nil {
+		return "", cleanup, err
+	}
+	if n <= maxBufferedBody {
+		r.Body = io.NopCloser(&buf)
+		return hex.EncodeToString(hash.Sum(nil)), cleanup, nil
+	}
+
+	spool, err := os.CreateTemp("", "idempotency-*")
+	if err != nil {
+		return "", cleanup, err
+	}
+	cleanup = func() {
+		spool.Close()
+		os.Remove(spool.Name())
+	}
+	// Начало тела уже учтено в хэше, дописываем его в файл как есть
+	if _, err := buf.WriteTo(spool); err != nil {
+		cleanup()
+		return "", func() {}, err
+	}
+	if _, err := io.Copy(io.MultiWriter(hash, spool), r.Body); err != nil {
+		cleanup()
+		return "", func() {}, err
+	}
+	if _, err := spool.Seek(0, io.SeekStart); err != nil {
+		cleanup()
+		return "", func() {}, err
+	}
+	r.Body = io.NopCloser(spool)
+	return hex.EncodeToString(hash.Sum(nil)), cleanup, nil
+}
+
+// responseRecorder копирует тело ответа, продолжая писать его клиенту
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *responseRecorder) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *responseRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+func logIdempotencyError(err error) {
+	if err != nil {
+		log.Printf("idempotency store error: %v", err)
+	}
+}
@@ -0,0 +1,76 @@
+package middleware
+
+import (
+	"context"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"task-tracker/pkg/idempotency"
+	"testing"
+	"time"
+)
+
+func newIdempotentRouter(store idempotency.Store, lock time.Duration, calls *int) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(func(c *gin.Context) { c.Set("user_id", "user") })
+	r.Use(Idempotency(store, time.Hour, lock))
+	r.POST("/tasks/bulk", func(c *gin.Context) {
+		*calls++
+		c.JSON(http.StatusOK, gin.H{"tz": c.Query("tz")})
+	})
+	return r
+}
+
+func postWithKey(r *gin.Engine, target string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, target, strings.NewReader(`{"ids":[]}`))
+	req.Header.Set(IdempotencyKeyHeader, "key-1")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestIdempotencyReplaysAndComparesQuery(t *testing.T) {
+	calls := 0
+	r := newIdempotentRouter(idempotency.NewMemoryStore(), time.Minute, &calls)
+
+	first := postWithKey(r, "/tasks/bulk?tz=Europe/Berlin")
+	replayed := postWithKey(r, "/tasks/bulk?tz=Europe/Berlin")
+	if calls != 1 || replayed.Header().Get("Idempotent-Replayed") != "true" || replayed.Body.String() != first.Body.String() {
+		t.Fatalf("calls = %d, replay = %d %q; want one call and the stored response", calls, replayed.Code, replayed.Body.String())
+	}
+
+	if w := postWithKey(r, "/tasks/bulk?tz=UTC"); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("same key with another query: status = %d, want 422", w.Code)
+	}
+}
+
+func TestIdempotencyAbandonedReservationExpires(t *testing.T) {
+	store := idempotency.NewMemoryStore()
+	fingerprint, cleanup, err := fingerprintRequest(httptest.NewRequest(http.MethodPost, "/tasks/bulk", strings.NewReader(`{"ids":[]}`)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cleanup()
+	// Ключ занял процесс, который упал, не успев ни сохранить ответ, ни освободить ключ
+	if _, token, err := store.Reserve(context.Background(), "user", "key-1", fingerprint, 10*time.Millisecond); err != nil || token == "" {
+		t.Fatalf("Reserve = %q, %v", token, err)
+	}
+
+	calls := 0
+	r := newIdempotentRouter(store, 10*time.Millisecond, &calls)
+	if w := postWithKey(r, "/tasks/bulk"); w.Code != http.StatusConflict {
+		t.Fatalf("retry during the lock: status = %d, want 409", w.Code)
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	if w := postWithKey(r, "/tasks/bulk"); w.Code != http.StatusOK || calls != 1 {
+		t.Fatalf("retry after the lock: status = %d, calls = %d; want the request executed", w.Code, calls)
+	}
+	// Сохранённый ответ живёт ttl, а не lock
+	time.Sleep(20 * time.Millisecond)
+	if w := postWithKey(r, "/tasks/bulk"); w.Header().Get("Idempotent-Replayed") != "true" || calls != 1 {
+		t.Errorf("completed response expired with the lock timeout")
+	}
+}
@@ -0,0 +1,44 @@
+package models
+
+import (
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"time"
+)
+
+type Attachment struct {
+	ID        uuid.UUID `gorm:"type:uuid;primaryKey;default:uuid_generate_v4()" json:"id"`
+	CreatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+
+	TaskID      uuid.UUID `gorm:"type:uuid;not null;index" json:"task_id"`
+	Task        *Task     `gorm:"constraint:OnDelete:CASCADE;" json:"-"`
+	UserID      uuid.UUID `gorm:"type:uuid;not null" json:"user_id"`
+	FileName    string    `gorm:"not null" json:"file_name"`
+	ContentType string    `gorm:"not null" json:"content_type"`
+	Size        int64     `gorm:"not null" json:"size"`
+	Hash        string    `gorm:"type:char(64);not null;index" json:"hash"`
+}
+
+func (a *Attachment) BeforeCreate(tx *gorm.DB) error {
+	if a.ID == uuid.Nil {
+		a.ID = uuid.New()
+	}
+	return nil
+}
+
+// Blob — содержимое файла, адресуемое по SHA-256; одно на все одинаковые вложения
+type Blob struct {
+	Hash        string    `gorm:"type:char(64);primaryKey" json:"hash"`
+	CreatedAt   time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+	Size        int64     `gorm:"not null" json:"size"`
+	ContentType string    `gorm:"not null" json:"content_type"`
+}
+
+// StorageKey возвращает ключ блоба в BlobStore
+func (b *Blob) StorageKey() string {
+	return BlobKey(b.Hash)
+}
+
+func BlobKey(hash string) string {
+	return "sha256/" + hash[:2] + "/" + hash
+}
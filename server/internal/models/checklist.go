@@ -0,0 +1,26 @@
+package models
+
+import (
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"time"
+)
+
+type ChecklistItem struct {
+	ID        uuid.UUID `gorm:"type:uuid;primaryKey;default:uuid_generate_v4()" json:"id"`
+	CreatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+	UpdatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"updated_at"`
+
+	TaskID   uuid.UUID `gorm:"type:uuid;not null;index" json:"task_id"`
+	Task     *Task     `gorm:"constraint:OnDelete:CASCADE;" json:"-"`
+	Title    string    `gorm:"not null" json:"title"`
+	Done     bool      `gorm:"not null;default:false" json:"done"`
+	Position int       `gorm:"not null;default:0" json:"position"`
+}
+
+func (i *ChecklistItem) BeforeCreate(tx *gorm.DB) error {
+	if i.ID == uuid.Nil {
+		i.ID = uuid.New()
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+package models
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+type ReminderMode string
+
+const (
+	RemindersOff        ReminderMode = "off"
+	RemindersIndividual ReminderMode = "individual" // письмо на каждую задачу
+	RemindersDigest     ReminderMode = "digest"     // одно письмо в день со списком
+)
+
+// NotificationPreferences — настройки напоминаний о сроках задач пользователя.
+// Пока строки нет, напоминания выключены
+type NotificationPreferences struct {
+	UserID    uuid.UUID `gorm:"type:uuid;primaryKey" json:"-"`
+	User      *User     `gorm:"constraint:OnDelete:CASCADE;" json:"-"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	Mode ReminderMode `gorm:"type:varchar(20);not null" json:"mode"`
+	// За сколько дней до срока напоминать; 0 — в день срока
+	DaysBefore int `gorm:"not null" json:"days_before"`
+	// Местное время отправки, "09:00"
+	RemindAt string `gorm:"type:varchar(5);not null" json:"remind_at"`
+	Timezone string `gorm:"type:varchar(64);not null" json:"timezone"`
+	// Напоминать и о просроченных задачах
+	Overdue bool `gorm:"not null" json:"overdue"`
+}
+
+// SentNotification — отправленное напоминание. Ключ описывает, о чём оно (задача и срок,
+// день дайджеста), и не даёт отправить то же напоминание второй раз
+type SentNotification struct {
+	UserID uuid.UUID `gorm:"type:uuid;primaryKey"`
+	User   *User     `gorm:"constraint:OnDelete:CASCADE;"`
+	Key    string    `gorm:"type:varchar(255);primaryKey"`
+	SentAt time.Time `gorm:"not null;index"`
+}
@@ -3,18 +3,89 @@ package models
 import (
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"regexp"
+	"strings"
 	"time"
+	"unicode"
+)
+
+type EstimateUnit string
+
+const (
+	EstimateHours  EstimateUnit = "hours"
+	EstimatePoints EstimateUnit = "points"
 )
 
 type Project struct {
 	ID          uuid.UUID `gorm:"type:uuid;primary_key;default:uuid_generate_v4()" json:"id"`
 	CreatedAt   time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
 	UpdatedAt   time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"updated_at"`
+	Version     int       `gorm:"not null;default:1" json:"version"`
 	Name        string    `gorm:"not null" json:"name"`
 	Description string    `json:"description"`
 	Color       string    `gorm:"type:varchar(7);default:'#4f46e5'" json:"color"`
 	UserID      uuid.UUID `gorm:"type:uuid;not null" json:"user_id"`
 	Tasks       []Task    `gorm:"foreignKey:ProjectID" json:"tasks"`
+
+	// Единица оценок задач проекта: часы или story points
+	EstimateUnit EstimateUnit `gorm:"type:varchar(10);default:'hours'" json:"estimate_unit"`
+
+	// Короткий ключ для ссылок на задачи (API-42) и счётчик выданных номеров
+	Key         string `gorm:"type:varchar(10);uniqueIndex" json:"key"`
+	TaskCounter int    `gorm:"not null;default:0" json:"-"`
+
+	// Лимиты незавершённой работы по колонкам доски; нет записи — нет лимита.
+	// WIPStrict запрещает перевод в заполненную колонку, иначе перевод проходит с предупреждением
+	WIPLimits map[TaskStatus]int `gorm:"column:wip_limits;type:jsonb;serializer:json" json:"wip_limits"`
+	WIPStrict bool               `gorm:"column:wip_strict;not null;default:false" json:"wip_strict"`
+}
+
+var projectKeyPattern = regexp.MustCompile(`^[A-Z][A-Z0-9]{1,9}$`)
+
+func ValidProjectKey(key string) bool {
+	return projectKeyPattern.MatchString(key)
+}
+
+var cyrillicTranslit = map[rune]string{
+	'А': "A", 'Б': "B", 'В': "V", 'Г': "G", 'Д': "D", 'Е': "E", 'Ё': "E", 'Ж': "ZH", 'З': "Z",
+	'И': "I", 'Й': "Y", 'К': "K", 'Л': "L", 'М': "M", 'Н': "N", 'О': "O", 'П': "P", 'Р': "R",
+	'С': "S", 'Т': "T", 'У': "U", 'Ф': "F", 'Х': "H", 'Ц': "C", 'Ч': "CH", 'Ш': "SH", 'Щ': "SCH",
+	'Ы': "Y", 'Э': "E", 'Ю': "YU", 'Я': "YA",
+}
+
+// ProjectKeyBase предлагает ключ по названию: инициалы нескольких слов ("Task Tracker" → "TT")
+// или начало единственного слова ("Backend" → "BACK"); кириллица транслитерируется
+func ProjectKeyBase(name string) string {
+	var words []string
+	for _, word := range strings.FieldsFunc(strings.ToUpper(name), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		var b strings.Builder
+		for _, r := range word {
+			switch {
+			case r < unicode.MaxASCII:
+				b.WriteRune(r)
+			case cyrillicTranslit[r] != "":
+				b.WriteString(cyrillicTranslit[r])
+			}
+		}
+		if b.Len() > 0 {
+			words = append(words, b.String())
+		}
+	}
+
+	key := ""
+	if len(words) > 1 {
+		for _, word := range words[:min(len(words), 4)] {
+			key += word[:1]
+		}
+	} else if len(words) == 1 {
+		key = words[0][:min(len(words[0]), 4)]
+	}
+	if !ValidProjectKey(key) {
+		return "PRJ"
+	}
+	return key
 }
 
 func (p *Project) BeforeCreate(tx *gorm.DB) error {
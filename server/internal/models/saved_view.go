@@ -0,0 +1,71 @@
+package models
+
+import (
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"time"
+)
+
+// ViewFilters — условия сохранённого представления; поля повторяют параметры GET /api/tasks.
+// Относительные даты («до конца недели») задаются через Query на языке taskql
+type ViewFilters struct {
+	Statuses    []TaskStatus   `json:"status,omitempty"`
+	Priorities  []TaskPriority `json:"priority,omitempty"`
+	ProjectID   *uuid.UUID     `json:"project_id,omitempty"`
+	NoProject   bool           `json:"no_project,omitempty"`
+	Search      string         `json:"search,omitempty"`
+	Query       string         `json:"q,omitempty"`
+	EstimateMin *float64       `json:"estimate_min,omitempty"`
+	EstimateMax *float64       `json:"estimate_max,omitempty"`
+	HasEstimate *bool          `json:"has_estimate,omitempty"`
+	HasDueDate  *bool          `json:"has_due_date,omitempty"`
+	Overdue     *bool          `json:"overdue,omitempty"`
+	DueFrom     string         `json:"due_from,omitempty"` // YYYY-MM-DD включительно
+	DueTo       string         `json:"due_to,omitempty"`
+}
+
+type ViewGrouping string
+
+const (
+	GroupNone     ViewGrouping = ""
+	GroupStatus   ViewGrouping = "status"
+	GroupPriority ViewGrouping = "priority"
+	GroupProject  ViewGrouping = "project"
+	GroupDueDate  ViewGrouping = "due_date"
+)
+
+// SavedView — сохранённый фильтр задач. Без проекта виден только владельцу,
+// с проектом — всем, кто видит этот проект
+type SavedView struct {
+	ID        uuid.UUID `gorm:"type:uuid;primaryKey;default:uuid_generate_v4()" json:"id"`
+	CreatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+	UpdatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"updated_at"`
+
+	Name      string     `gorm:"not null" json:"name"`
+	UserID    uuid.UUID  `gorm:"type:uuid;not null;index" json:"user_id"`
+	ProjectID *uuid.UUID `gorm:"type:uuid;index" json:"project_id"`
+	Project   *Project   `gorm:"constraint:OnDelete:CASCADE;" json:"-"`
+
+	Filters ViewFilters  `gorm:"type:jsonb;serializer:json;not null" json:"filters"`
+	Sort    string       `gorm:"type:varchar(255)" json:"sort"` // как параметр sort: "-priority,due_date"
+	GroupBy ViewGrouping `gorm:"type:varchar(20)" json:"group_by"`
+	// Pinned — закреплено ли представление у текущего пользователя; вычисляется по saved_view_pins
+	Pinned bool `gorm:"->;-:migration" json:"pinned"`
+}
+
+func (v *SavedView) BeforeCreate(tx *gorm.DB) error {
+	if v.ID == uuid.Nil {
+		v.ID = uuid.New()
+	}
+	return nil
+}
+
+// SavedViewPin — представление, закреплённое пользователем в боковой панели.
+// Закрепление личное: общее представление проекта каждый участник закрепляет сам
+type SavedViewPin struct {
+	UserID    uuid.UUID  `gorm:"type:uuid;primaryKey" json:"user_id"`
+	User      *User      `gorm:"constraint:OnDelete:CASCADE;" json:"-"`
+	ViewID    uuid.UUID  `gorm:"type:uuid;primaryKey;index" json:"view_id"`
+	View      *SavedView `gorm:"foreignKey:ViewID;constraint:OnDelete:CASCADE;" json:"-"`
+	CreatedAt time.Time  `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+}
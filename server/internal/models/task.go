@@ -3,6 +3,7 @@ package models
 import (
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"strconv"
 	"time"
 )
 
@@ -14,6 +15,9 @@ const (
 	StatusDone       TaskStatus = "done"
 )
 
+// TaskStatuses — все статусы в порядке прохождения задачи
+var TaskStatuses = []TaskStatus{StatusTodo, StatusInProgress, StatusDone}
+
 type TaskPriority string
 
 const (
@@ -22,10 +26,15 @@ const (
 	PriorityHigh   TaskPriority = "high"
 )
 
+// TaskPriorities — приоритеты по возрастанию, чтобы сравнивать их по индексу
+var TaskPriorities = []TaskPriority{PriorityLow, PriorityMedium, PriorityHigh}
+
 type Task struct {
 	ID        uuid.UUID `gorm:"type:uuid;primaryKey;default:uuid_generate_v4()" json:"id"`
 	CreatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
 	UpdatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP;constraint:onUpdate:CURRENT_TIMESTAMP" json:"updated_at"`
+	// Версия для оптимистичной блокировки: растёт при каждом сохранении, отдаётся как ETag
+	Version int `gorm:"not null;default:1" json:"version"`
 
 	Title       string `gorm:"not null" json:"title"`
 	Description string `json:"description"`
@@ -33,16 +42,125 @@ type Task struct {
 	Status   TaskStatus   `gorm:"type:varchar(20);default:'todo'" json:"status"`
 	Priority TaskPriority `gorm:"type:varchar(10);default:'medium'" json:"priority"`
 	DueDate  *time.Time   `json:"due_date"`
+	// Момент перевода в done; сбрасывается при возврате в работу
+	CompletedAt *time.Time `gorm:"index" json:"completed_at"`
+
+	// Оценка в единицах проекта (часы или story points) и оставшаяся работа
+	Estimate          *float64 `json:"estimate"`
+	RemainingEstimate *float64 `json:"remaining_estimate"`
+
+	// Повторение: правило RRULE, часовой пояс вычислений и DTSTART серии
+	Recurrence         string     `gorm:"type:varchar(255)" json:"recurrence,omitempty"`
+	RecurrenceTimezone string     `gorm:"type:varchar(64)" json:"recurrence_timezone,omitempty"`
+	RecurrenceStart    *time.Time `json:"recurrence_start,omitempty"`
 
 	UserID uuid.UUID `gorm:"type:uuid;not null" json:"user_id"`
 
 	ProjectID *uuid.UUID `gorm:"type:uuid" json:"project_id"`
 	Project   *Project   `gorm:"constraint:OnDelete:CASCADE;" json:"project,omitempty"`
+	// Номер задачи внутри проекта; вместе с ключом проекта даёт ключ вида API-42
+	Number *int   `json:"number,omitempty"`
+	Key    string `gorm:"->;-:migration" json:"key,omitempty"`
+
+	// Позиция в колонке доски (проект + статус, у задач без проекта — владелец + статус).
+	// Дробная: перестановка меняет одну строку, ранг берётся посередине между соседями
+	Rank float64 `gorm:"not null;default:0" json:"rank"`
+
+	ParentID *uuid.UUID `gorm:"type:uuid;index" json:"parent_id"`
+	Parent   *Task      `gorm:"constraint:OnDelete:CASCADE;" json:"-"`
+
+	Checklist []ChecklistItem `gorm:"foreignKey:TaskID" json:"checklist,omitempty"`
+
+	// Агрегаты, вычисляются в запросе
+	ChecklistTotal int     `gorm:"->;-:migration" json:"checklist_total"`
+	ChecklistDone  int     `gorm:"->;-:migration" json:"checklist_done"`
+	ChecklistRatio float64 `gorm:"->;-:migration" json:"checklist_ratio"`
+	TrackedSeconds int64   `gorm:"->;-:migration" json:"tracked_seconds"`
 }
 
 func (t *Task) BeforeCreate(tx *gorm.DB) error {
 	if t.ID == uuid.Nil {
 		t.ID = uuid.New()
 	}
+	if err := t.allocateNumber(tx); err != nil {
+		return err
+	}
+	return t.allocateRank(tx)
+}
+
+// BeforeUpdate выдаёт номер задаче, которую перенесли в проект (номер при переносе сбрасывается),
+// и ставит в конец колонки задачу со сброшенным рангом
+func (t *Task) BeforeUpdate(tx *gorm.DB) error {
+	if err := t.allocateNumber(tx); err != nil {
+		return err
+	}
+	return t.allocateRank(tx)
+}
+
+// Шаг между соседними рангами после выдачи и перебалансировки
+const RankStep = 1024.0
+
+// ColumnID — идентификатор колонки доски вместе со статусом: проект задачи или её владелец
+func (t *Task) ColumnID() uuid.UUID {
+	if t.ProjectID != nil {
+		return *t.ProjectID
+	}
+	return t.UserID
+}
+
+// allocateRank ставит задачу с нулевым рангом в конец её колонки
+func (t *Task) allocateRank(tx *gorm.DB) error {
+	if t.Rank != 0 || t.ID == uuid.Nil {
+		return nil
+	}
+	status := t.Status
+	if status == "" {
+		status = StatusTodo
+	}
+	return tx.Session(&gorm.Session{NewDB: true}).
+		Raw("SELECT COALESCE(MAX(rank), 0) + ? FROM tasks WHERE status = ? AND COALESCE(project_id, user_id) = ? AND id <> ?",
+			RankStep, status, t.ColumnID(), t.ID).
+		Scan(&t.Rank).Error
+}
+
+// allocateNumber берёт следующий номер из счётчика проекта. UPDATE блокирует строку проекта
+// до конца транзакции, поэтому параллельные вставки в один проект получают разные номера
+func (t *Task) allocateNumber(tx *gorm.DB) error {
+	if t.ProjectID == nil {
+		t.Number, t.Key = nil, ""
+		return nil
+	}
+	if t.Number != nil {
+		return nil
+	}
+
+	var counter struct {
+		TaskCounter int
+		Key         string
+	}
+	err := tx.Session(&gorm.Session{NewDB: true}).
+		Raw("UPDATE projects SET task_counter = task_counter + 1 WHERE id = ? RETURNING task_counter, key", *t.ProjectID).
+		Scan(&counter).Error
+	if err != nil {
+		return err
+	}
+	if counter.TaskCounter == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	t.Number = &counter.TaskCounter
+	t.Key = TaskKey(counter.Key, counter.TaskCounter)
 	return nil
 }
+
+func TaskKey(projectKey string, number int) string {
+	return projectKey + "-" + strconv.Itoa(number)
+}
+
+// TaskKeyAlias — прежний ключ задачи, переехавшей в другой проект; старые ссылки продолжают работать
+type TaskKeyAlias struct {
+	Key       string    `gorm:"type:varchar(24);primaryKey" json:"key"`
+	CreatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+	TaskID    uuid.UUID `gorm:"type:uuid;not null;index" json:"task_id"`
+	Task      *Task     `gorm:"constraint:OnDelete:CASCADE;" json:"-"`
+}
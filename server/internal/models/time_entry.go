@@ -0,0 +1,38 @@
+package models
+
+import (
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"time"
+)
+
+type TimeEntry struct {
+	ID        uuid.UUID `gorm:"type:uuid;primaryKey;default:uuid_generate_v4()" json:"id"`
+	CreatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+	UpdatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"updated_at"`
+
+	TaskID uuid.UUID `gorm:"type:uuid;not null;index" json:"task_id"`
+	Task   *Task     `gorm:"constraint:OnDelete:CASCADE;" json:"-"`
+	// У пользователя может быть только один запущенный таймер
+	UserID uuid.UUID `gorm:"type:uuid;not null;index;uniqueIndex:idx_time_entries_running,where:ended_at IS NULL" json:"user_id"`
+
+	StartedAt time.Time  `gorm:"not null;index" json:"started_at"`
+	EndedAt   *time.Time `json:"ended_at"` // nil — таймер запущен
+	Note      string     `json:"note"`
+	Manual    bool       `gorm:"not null;default:false" json:"manual"`
+}
+
+func (e *TimeEntry) BeforeCreate(tx *gorm.DB) error {
+	if e.ID == uuid.Nil {
+		e.ID = uuid.New()
+	}
+	return nil
+}
+
+// Duration возвращает учтённое время; для запущенного таймера — по текущий момент
+func (e *TimeEntry) Duration(now time.Time) time.Duration {
+	if e.EndedAt != nil {
+		return e.EndedAt.Sub(e.StartedAt)
+	}
+	return now.Sub(e.StartedAt)
+}
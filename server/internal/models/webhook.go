@@ -0,0 +1,76 @@
+package models
+
+import (
+	"encoding/json"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"time"
+)
+
+// Webhook — адрес, на который отправляются события проекта. Events — типы событий
+// ("task.created") или маски ("task.*", "*"). Вебхук переживает проект, чтобы успеть
+// доставить project.deleted; после этого его удаляет фоновая очистка
+type Webhook struct {
+	ID        uuid.UUID `gorm:"type:uuid;primaryKey;default:uuid_generate_v4()" json:"id"`
+	CreatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+	UpdatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"updated_at"`
+
+	ProjectID uuid.UUID `gorm:"type:uuid;not null;index" json:"project_id"`
+	URL       string    `gorm:"not null" json:"url"`
+	Events    []string  `gorm:"type:jsonb;serializer:json;not null" json:"events"`
+	// Ключ подписи HMAC-SHA256; показывается только при создании
+	Secret string `gorm:"not null" json:"-"`
+
+	// FailureCount — неудачные попытки подряд; при достижении порога вебхук отключается
+	Active         bool       `gorm:"not null" json:"active"`
+	FailureCount   int        `gorm:"not null;default:0" json:"failure_count"`
+	DisabledReason string     `json:"disabled_reason,omitempty"`
+	DisabledAt     *time.Time `json:"disabled_at,omitempty"`
+}
+
+func (w *Webhook) BeforeCreate(tx *gorm.DB) error {
+	if w.ID == uuid.Nil {
+		w.ID = uuid.New()
+	}
+	return nil
+}
+
+type DeliveryStatus string
+
+const (
+	DeliveryPending   DeliveryStatus = "pending"
+	DeliverySucceeded DeliveryStatus = "succeeded"
+	DeliveryFailed    DeliveryStatus = "failed"
+)
+
+// WebhookDelivery — отправка одного события на вебхук вместе с итогом последней попытки
+type WebhookDelivery struct {
+	ID        uuid.UUID `gorm:"type:uuid;primaryKey;default:uuid_generate_v4()" json:"id"`
+	CreatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP;index" json:"created_at"`
+
+	// Событие ставится в очередь вебхуку один раз, даже если outbox передаст его повторно
+	WebhookID uuid.UUID       `gorm:"type:uuid;not null;index;uniqueIndex:idx_webhook_deliveries_event,where:redelivery_of IS NULL" json:"webhook_id"`
+	Webhook   *Webhook        `gorm:"constraint:OnDelete:CASCADE;" json:"-"`
+	EventID   string          `gorm:"type:varchar(64);not null;uniqueIndex:idx_webhook_deliveries_event" json:"event_id"`
+	EventType string          `gorm:"type:varchar(50);not null" json:"event_type"`
+	Payload   json.RawMessage `gorm:"type:jsonb;serializer:json;not null" json:"payload"`
+	// Повторная отправка создаёт новую доставку со ссылкой на исходную
+	RedeliveryOf *uuid.UUID `gorm:"type:uuid" json:"redelivery_of,omitempty"`
+
+	Status        DeliveryStatus `gorm:"type:varchar(20);not null;default:'pending'" json:"status"`
+	Attempts      int            `gorm:"not null;default:0" json:"attempts"`
+	NextAttemptAt *time.Time     `gorm:"index" json:"next_attempt_at,omitempty"`
+	// Итог последней попытки
+	ResponseStatus int        `json:"response_status,omitempty"`
+	ResponseBody   string     `json:"response_body,omitempty"`
+	Error          string     `json:"error,omitempty"`
+	DurationMs     int64      `json:"duration_ms,omitempty"`
+	LastAttemptAt  *time.Time `json:"last_attempt_at,omitempty"`
+}
+
+func (d *WebhookDelivery) BeforeCreate(tx *gorm.DB) error {
+	if d.ID == uuid.Nil {
+		d.ID = uuid.New()
+	}
+	return nil
+}
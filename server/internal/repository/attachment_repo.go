@@ -0,0 +1,100 @@
+package repository
+
+import (
+	"errors"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"task-tracker/internal/models"
+)
+
+type AttachmentRepository interface {
+	// Create сохраняет вложение, под блокировкой строки блоба вызывая ensureContent,
+	// который должен гарантировать наличие содержимого в хранилище
+	Create(attachment *models.Attachment, blob *models.Blob, ensureContent func(isNew bool) error) error
+	FindByID(id uuid.UUID) (*models.Attachment, error)
+	ListByTask(taskID uuid.UUID) ([]models.Attachment, error)
+	Delete(id uuid.UUID) error
+	OrphanHashes() ([]string, error)
+	// ReleaseBlob удаляет блоб, если на него не осталось ссылок, вызывая remove под блокировкой
+	ReleaseBlob(hash string, remove func() error) error
+}
+
+type attachmentRepo struct {
+	db *gorm.DB
+}
+
+func NewAttachmentRepository(db *gorm.DB) AttachmentRepository {
+	return &attachmentRepo{db: db}
+}
+
+func (r *attachmentRepo) Create(attachment *models.Attachment, blob *models.Blob, ensureContent func(isNew bool) error) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		res := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(blob)
+		if res.Error != nil {
+			return res.Error
+		}
+
+		var locked models.Blob
+		if err := tx.Clauses(lockForUpdate).First(&locked, "hash = ?", blob.Hash).Error; err != nil {
+			return err
+		}
+
+		if err := ensureContent(res.RowsAffected == 1); err != nil {
+			return err
+		}
+		return tx.Create(attachment).Error
+	})
+}
+
+func (r *attachmentRepo) FindByID(id uuid.UUID) (*models.Attachment, error) {
+	var attachment models.Attachment
+	err := r.db.First(&attachment, "id = ?", id).Error
+	return &attachment, err
+}
+
+func (r *attachmentRepo) ListByTask(taskID uuid.UUID) ([]models.Attachment, error) {
+	var attachments []models.Attachment
+	err := r.db.Where("task_id = ?", taskID).
+		Order("created_at ASC").
+		Find(&attachments).Error
+	return attachments, err
+}
+
+func (r *attachmentRepo) Delete(id uuid.UUID) error {
+	return r.db.Delete(&models.Attachment{}, "id = ?", id).Error
+}
+
+func (r *attachmentRepo) OrphanHashes() ([]string, error) {
+	var hashes []string
+	err := r.db.Model(&models.Blob{}).
+		Where("NOT EXISTS (SELECT 1 FROM attachments WHERE attachments.hash = blobs.hash)").
+		Pluck("hash", &hashes).Error
+	return hashes, err
+}
+
+func (r *attachmentRepo) ReleaseBlob(hash string, remove func() error) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var blob models.Blob
+		err := tx.Clauses(lockForUpdate).First(&blob, "hash = ?", hash).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		var refs int64
+		if err := tx.Model(&models.Attachment{}).Where("hash = ?", hash).Count(&refs).Error; err != nil {
+			return err
+		}
+		if refs > 0 {
+			return nil
+		}
+
+		if err := tx.Delete(&blob).Error; err != nil {
+			return err
+		}
+		return remove()
+	})
+}
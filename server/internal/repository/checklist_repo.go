@@ -0,0 +1,109 @@
+package repository
+
+import (
+	"errors"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"task-tracker/internal/models"
+)
+
+var ErrChecklistMismatch = errors.New("item ids do not match task checklist")
+
+type ChecklistRepository interface {
+	Create(item *models.ChecklistItem) error
+	FindByID(id uuid.UUID) (*models.ChecklistItem, error)
+	Update(item *models.ChecklistItem) error
+	Delete(id uuid.UUID) error
+	ListByTask(taskID uuid.UUID) ([]models.ChecklistItem, error)
+	NextPosition(taskID uuid.UUID) (int, error)
+	Reorder(taskID uuid.UUID, itemIDs []uuid.UUID) error
+	Promote(item *models.ChecklistItem, subtask *models.Task) error
+}
+
+type checklistRepo struct {
+	db *gorm.DB
+}
+
+func NewChecklistRepository(db *gorm.DB) ChecklistRepository {
+	return &checklistRepo{db: db}
+}
+
+func (r *checklistRepo) Create(item *models.ChecklistItem) error {
+	return r.db.Create(item).Error
+}
+
+func (r *checklistRepo) FindByID(id uuid.UUID) (*models.ChecklistItem, error) {
+	var item models.ChecklistItem
+	err := r.db.First(&item, "id = ?", id).Error
+	return &item, err
+}
+
+func (r *checklistRepo) Update(item *models.ChecklistItem) error {
+	return r.db.Save(item).Error
+}
+
+func (r *checklistRepo) Delete(id uuid.UUID) error {
+	return r.db.Delete(&models.ChecklistItem{}, "id = ?", id).Error
+}
+
+func (r *checklistRepo) ListByTask(taskID uuid.UUID) ([]models.ChecklistItem, error) {
+	var items []models.ChecklistItem
+	err := r.db.Where("task_id = ?", taskID).
+		Order("position ASC, created_at ASC").
+		Find(&items).Error
+	return items, err
+}
+
+func (r *checklistRepo) NextPosition(taskID uuid.UUID) (int, error) {
+	var next int
+	err := r.db.Model(&models.ChecklistItem{}).
+		Select("COALESCE(MAX(position), -1) + 1").
+		Where("task_id = ?", taskID).
+		Scan(&next).Error
+	return next, err
+}
+
+func (r *checklistRepo) Reorder(taskID uuid.UUID, itemIDs []uuid.UUID) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var existing []uuid.UUID
+		if err := tx.Model(&models.ChecklistItem{}).
+			Where("task_id = ?", taskID).
+			Clauses(lockForUpdate).
+			Pluck("id", &existing).Error; err != nil {
+			return err
+		}
+
+		// Новый порядок должен содержать ровно те же элементы
+		if len(existing) != len(itemIDs) {
+			return ErrChecklistMismatch
+		}
+		known := make(map[uuid.UUID]bool, len(existing))
+		for _, id := range existing {
+			known[id] = true
+		}
+		for _, id := range itemIDs {
+			if !known[id] {
+				return ErrChecklistMismatch
+			}
+			delete(known, id)
+		}
+
+		for pos, id := range itemIDs {
+			if err := tx.Model(&models.ChecklistItem{}).
+				Where("id = ?", id).
+				Update("position", pos).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (r *checklistRepo) Promote(item *models.ChecklistItem, subtask *models.Task) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(subtask).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.ChecklistItem{}, "id = ?", item.ID).Error
+	})
+}
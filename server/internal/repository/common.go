@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"errors"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var lockForUpdate = clause.Locking{Strength: "UPDATE"}
+
+var ErrVersionConflict = errors.New("resource was modified concurrently")
+
+// saveVersioned сохраняет строку целиком (без ассоциаций), только если её версия не изменилась
+// с момента чтения, и увеличивает версию. В отличие от Save, при промахе не превращается во вставку
+func saveVersioned(db *gorm.DB, value any, version *int, omit ...string) error {
+	current := *version
+	*version = current + 1
+	result := db.Model(value).
+		Select("*").
+		Omit(append(omit, clause.Associations)...).
+		Where("version = ?", current).
+		Updates(value)
+	if result.Error == nil && result.RowsAffected == 0 {
+		result.Error = ErrVersionConflict
+	}
+	if result.Error != nil {
+		*version = current
+	}
+	return result.Error
+}
+
+// deleteVersioned удаляет строку по id; если version задана — только эту версию строки,
+// иначе запись, изменённая после проверки If-Match, была бы потеряна
+func deleteVersioned(db *gorm.DB, value any, id uuid.UUID, version *int) error {
+	query := db.Where("id = ?", id)
+	if version != nil {
+		query = query.Where("version = ?", *version)
+	}
+	result := query.Delete(value)
+	if result.Error == nil && version != nil && result.RowsAffected == 0 {
+		result.Error = ErrVersionConflict
+	}
+	return result.Error
+}
+
+// visibleTasks ограничивает выборку задачами, которые видит пользователь:
+// своими и задачами из его проектов
+func visibleTasks(userID uuid.UUID) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where(
+			"(tasks.user_id = ? OR tasks.project_id IN (SELECT id FROM projects WHERE projects.user_id = ?))",
+			userID, userID,
+		)
+	}
+}
+
+// visibleProjectIDs — подзапрос с проектами, которые видит пользователь:
+// своими и теми, где у него есть задачи. Ожидает userID дважды
+const visibleProjectIDs = `(SELECT id FROM projects WHERE projects.user_id = ?
+    UNION SELECT project_id FROM tasks WHERE tasks.user_id = ? AND project_id IS NOT NULL)`
+
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
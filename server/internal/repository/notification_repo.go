@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"task-tracker/internal/models"
+	"time"
+)
+
+type NotificationRepository interface {
+	FindPreferences(userID uuid.UUID) (*models.NotificationPreferences, error)
+	SavePreferences(prefs *models.NotificationPreferences) error
+	// ListEnabled возвращает включённые настройки вместе с пользователями
+	ListEnabled() ([]models.NotificationPreferences, error)
+
+	// MarkSent отмечает напоминание отправленным; false — оно уже было отправлено
+	MarkSent(userID uuid.UUID, key string) (bool, error)
+	// UnmarkSent снимает отметку, если напоминание отправить не удалось
+	UnmarkSent(userID uuid.UUID, key string) error
+	PurgeSent(before time.Time) (int64, error)
+}
+
+type notificationRepo struct {
+	db *gorm.DB
+}
+
+func NewNotificationRepository(db *gorm.DB) NotificationRepository {
+	return &notificationRepo{db: db}
+}
+
+func (r *notificationRepo) FindPreferences(userID uuid.UUID) (*models.NotificationPreferences, error) {
+	var prefs models.NotificationPreferences
+	err := r.db.First(&prefs, "user_id = ?", userID).Error
+	return &prefs, err
+}
+
+func (r *notificationRepo) SavePreferences(prefs *models.NotificationPreferences) error {
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"mode", "days_before", "remind_at", "timezone", "overdue", "updated_at"}),
+	}).Create(prefs).Error
+}
+
+func (r *notificationRepo) ListEnabled() ([]models.NotificationPreferences, error) {
+	var prefs []models.NotificationPreferences
+	err := r.db.Preload("User").
+		Where("mode <> ?", models.RemindersOff).
+		Order("user_id").
+		Find(&prefs).Error
+	return prefs, err
+}
+
+func (r *notificationRepo) MarkSent(userID uuid.UUID, key string) (bool, error) {
+	result := r.db.Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&models.SentNotification{UserID: userID, Key: key, SentAt: time.Now()})
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *notificationRepo) UnmarkSent(userID uuid.UUID, key string) error {
+	return r.db.Delete(&models.SentNotification{}, "user_id = ? AND key = ?", userID, key).Error
+}
+
+func (r *notificationRepo) PurgeSent(before time.Time) (int64, error) {
+	result := r.db.Delete(&models.SentNotification{}, "sent_at < ?", before)
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,276 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"slices"
+	"strconv"
+	"task-tracker/pkg/events"
+	"time"
+)
+
+// OutboxEvent — событие, записанное в той же транзакции, что и изменение, о котором оно
+// сообщает: событие не теряется, даже если процесс упадёт сразу после коммита
+type OutboxEvent struct {
+	ID            int64
+	Type          string
+	ProjectID     *uuid.UUID
+	Audience      []uuid.UUID     `gorm:"serializer:json"`
+	Data          json.RawMessage `gorm:"serializer:json"`
+	CreatedAt     time.Time
+	Attempts      int
+	NextAttemptAt time.Time
+	LastError     string
+	DispatchedAt  *time.Time
+	// XID — транзакция, записавшая событие; задаётся базой
+	XID int64 `gorm:"column:xid;->"`
+}
+
+func (OutboxEvent) TableName() string {
+	return "outbox_events"
+}
+
+// Event восстанавливает событие для обработчиков; номер в outbox становится его идентификатором
+func (o OutboxEvent) Event() events.Event {
+	e := events.Event{
+		ID:       strconv.FormatInt(o.ID, 10),
+		Type:     o.Type,
+		Audience: o.Audience,
+		Data:     o.Data,
+		Time:     o.CreatedAt,
+	}
+	if o.ProjectID != nil {
+		e.ProjectID = *o.ProjectID
+	}
+	return e
+}
+
+// OutboxPosition — место в ленте outbox: прочитано всё до транзакции и номера события
+// (XID, ID), кроме событий транзакций Pending, которые при чтении ещё не завершились.
+// Номера выдаются до коммита, поэтому по одному номеру читать нельзя: событие медленной
+// транзакции с меньшим номером появилось бы уже после курсора
+type OutboxPosition struct {
+	XID     int64
+	ID      int64
+	Pending []int64
+}
+
+type OutboxRepository interface {
+	// Claim выбирает до limit событий, которым пора уйти, и держит их заблокированными, пока
+	// работает fn. Другие обработчики, в том числе на других репликах, эти строки пропускают.
+	// fn возвращает ошибки по номерам событий, которые не удалось обработать: они уйдут
+	// повторно позже, остальные помечаются отправленными
+	Claim(limit int, fn func(events []OutboxEvent) map[int64]error) (int, error)
+	// Head — позиция, с которой видны только события, зафиксированные после этого вызова
+	Head() (OutboxPosition, error)
+	// Since возвращает события после after, независимо от их отправки, и позицию для следующего
+	// вызова. Читает каждая реплика сама: так событие доходит до всех, а не до одной
+	Since(after OutboxPosition, limit int) ([]OutboxEvent, OutboxPosition, error)
+	PurgeDispatched(before time.Time) (int64, error)
+}
+
+type outboxRepo struct {
+	db *gorm.DB
+}
+
+func NewOutboxRepository(db *gorm.DB) OutboxRepository {
+	return &outboxRepo{db: db}
+}
+
+func (r *outboxRepo) Claim(limit int, fn func(events []OutboxEvent) map[int64]error) (int, error) {
+	var claimed int
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var rows []OutboxEvent
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("dispatched_at IS NULL AND next_attempt_at <= now()").
+			Order("id ASC").
+			Limit(limit).
+			Find(&rows).Error
+		if err != nil || len(rows) == 0 {
+			return err
+		}
+		claimed = len(rows)
+
+		failed := fn(rows)
+		var done []int64
+		for _, row := range rows {
+			if _, ok := failed[row.ID]; !ok {
+				done = append(done, row.ID)
+			}
+		}
+		if len(done) > 0 {
+			err := tx.Model(&OutboxEvent{}).Where("id IN ?", done).
+				Updates(map[string]any{"dispatched_at": gorm.Expr("now()"), "last_error": ""}).Error
+			if err != nil {
+				return err
+			}
+		}
+		// Паузы между повторами растут вдвое, но не дольше часа
+		for id, cause := range failed {
+			err := tx.Model(&OutboxEvent{}).Where("id = ?", id).Updates(map[string]any{
+				"attempts":        gorm.Expr("attempts + 1"),
+				"next_attempt_at": gorm.Expr("now() + make_interval(secs => least(power(2, attempts), 3600))"),
+				"last_error":      cause.Error(),
+			}).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return claimed, err
+}
+
+func (r *outboxRepo) Head() (OutboxPosition, error) {
+	var head OutboxPosition
+	err := r.inSnapshot(func(tx *gorm.DB, snap outboxSnapshot) error {
+		head = OutboxPosition{XID: snap.xmax, Pending: snap.xip}
+		return nil
+	})
+	return head, err
+}
+
+// Since читает ленту по снимку базы, а не до самой старой незавершённой транзакции: долгая
+// или зависшая транзакция не останавливает поток событий, её события придут, когда она
+// завершится. События таких транзакций отдаются целиком, сверх limit
+func (r *outboxRepo) Since(after OutboxPosition, limit int) ([]OutboxEvent, OutboxPosition, error) {
+	var rows []OutboxEvent
+	var next OutboxPosition
+	err := r.inSnapshot(func(tx *gorm.DB, snap outboxSnapshot) error {
+		if finished := snap.finished(after.Pending); len(finished) > 0 {
+			if err := tx.Where("xid IN ?", finished).Order("xid ASC, id ASC").Find(&rows).Error; err != nil {
+				return err
+			}
+		}
+		query := tx.Where("(xid, id) > (?, ?) AND xid < ?", after.XID, after.ID, snap.xmax)
+		if len(snap.xip) > 0 {
+			query = query.Where("xid NOT IN ?", snap.xip)
+		}
+		var fresh []OutboxEvent
+		if err := query.Order("xid ASC, id ASC").Limit(limit).Find(&fresh).Error; err != nil {
+			return err
+		}
+		rows = append(rows, fresh...)
+		next = after.advance(snap, fresh, limit)
+		return nil
+	})
+	return rows, next, err
+}
+
+// outboxSnapshot — снимок базы: транзакции от xmax ещё не начались, из xip — ещё идут,
+// остальные завершены, и их события уже не изменятся
+type outboxSnapshot struct {
+	xmax int64
+	xip  []int64
+}
+
+// finished возвращает транзакции из pending, которые к снимку завершились
+func (snap outboxSnapshot) finished(pending []int64) []int64 {
+	var done []int64
+	for _, xid := range pending {
+		if !slices.Contains(snap.xip, xid) {
+			done = append(done, xid)
+		}
+	}
+	return done
+}
+
+// advance сдвигает позицию за события fresh, прочитанные по снимку snap. Если событий меньше
+// limit, прочитано всё до xmax. Незавершённые транзакции, через которые прошла позиция,
+// запоминаются в Pending
+func (p OutboxPosition) advance(snap outboxSnapshot, fresh []OutboxEvent, limit int) OutboxPosition {
+	next := OutboxPosition{XID: snap.xmax}
+	if len(fresh) > 0 && len(fresh) >= limit {
+		last := fresh[len(fresh)-1]
+		next.XID, next.ID = last.XID, last.ID
+	}
+	for _, xid := range p.Pending {
+		if slices.Contains(snap.xip, xid) {
+			next.Pending = append(next.Pending, xid)
+		}
+	}
+	for _, xid := range snap.xip {
+		if xid >= p.XID && xid < next.XID && !slices.Contains(next.Pending, xid) {
+			next.Pending = append(next.Pending, xid)
+		}
+	}
+	return next
+}
+
+// inSnapshot выполняет fn в транзакции REPEATABLE READ: снимок и прочитанные события
+// согласованы между собой
+func (r *outboxRepo) inSnapshot(fn func(tx *gorm.DB, snap outboxSnapshot) error) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var snap outboxSnapshot
+		if err := tx.Raw("SELECT txid_snapshot_xmax(txid_current_snapshot())").Scan(&snap.xmax).Error; err != nil {
+			return err
+		}
+		if err := tx.Raw("SELECT txid_snapshot_xip(txid_current_snapshot())").Scan(&snap.xip).Error; err != nil {
+			return err
+		}
+		return fn(tx, snap)
+	}, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+}
+
+func (r *outboxRepo) PurgeDispatched(before time.Time) (int64, error) {
+	result := r.db.Where("dispatched_at < ?", before).Delete(&OutboxEvent{})
+	return result.RowsAffected, result.Error
+}
+
+// writeOutbox записывает события в outbox через db — транзакцию, в которой идёт изменение
+func writeOutbox(db *gorm.DB, evts []events.Event) error {
+	if len(evts) == 0 {
+		return nil
+	}
+	now := time.Now()
+	rows := make([]OutboxEvent, len(evts))
+	for i, e := range evts {
+		rows[i] = OutboxEvent{
+			Type:          e.Type,
+			Audience:      e.Audience,
+			Data:          e.Data,
+			CreatedAt:     e.Time,
+			NextAttemptAt: now,
+		}
+		if e.ProjectID != uuid.Nil {
+			rows[i].ProjectID = &e.ProjectID
+		}
+		if rows[i].CreatedAt.IsZero() {
+			rows[i].CreatedAt = now
+		}
+	}
+	return db.Select("Type", "ProjectID", "Audience", "Data", "CreatedAt", "NextAttemptAt").Create(&rows).Error
+}
+
+// MigrateOutbox создаёт таблицу outbox. Частичный индекс держит выборку неотправленных
+// событий быстрой, сколько бы отправленных ни накопилось до очистки
+func MigrateOutbox(db *gorm.DB) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, statement := range []string{
+			`CREATE TABLE IF NOT EXISTS outbox_events (
+                id bigserial PRIMARY KEY,
+                type varchar(50) NOT NULL,
+                project_id uuid,
+                audience jsonb NOT NULL DEFAULT '[]',
+                data jsonb NOT NULL,
+                created_at timestamptz NOT NULL DEFAULT now(),
+                attempts int NOT NULL DEFAULT 0,
+                next_attempt_at timestamptz NOT NULL DEFAULT now(),
+                last_error text NOT NULL DEFAULT '',
+                dispatched_at timestamptz
+            )`,
+			"ALTER TABLE outbox_events ADD COLUMN IF NOT EXISTS xid bigint NOT NULL DEFAULT txid_current()",
+			"CREATE INDEX IF NOT EXISTS idx_outbox_events_xid ON outbox_events (xid, id)",
+			"CREATE INDEX IF NOT EXISTS idx_outbox_events_pending ON outbox_events (next_attempt_at, id) WHERE dispatched_at IS NULL",
+			"CREATE INDEX IF NOT EXISTS idx_outbox_events_dispatched ON outbox_events (dispatched_at) WHERE dispatched_at IS NOT NULL",
+		} {
+			if err := tx.Exec(statement).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
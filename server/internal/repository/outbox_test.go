@@ -0,0 +1,101 @@
+package repository
+
+import (
+	"slices"
+	"strconv"
+	"testing"
+)
+
+// outboxLog — лента outbox в памяти. Транзакции из running ещё идут, от next — не начинались;
+// since читает её так же, как Since читает таблицу
+type outboxLog struct {
+	rows    []OutboxEvent
+	running []int64
+	next    int64
+}
+
+func (l *outboxLog) snapshot() outboxSnapshot {
+	return outboxSnapshot{xmax: l.next, xip: slices.Clone(l.running)}
+}
+
+func (l *outboxLog) since(after OutboxPosition, limit int) ([]string, OutboxPosition) {
+	snap := l.snapshot()
+	var ids []string
+	for _, row := range l.rows {
+		if slices.Contains(snap.finished(after.Pending), row.XID) {
+			ids = append(ids, strconv.FormatInt(row.ID, 10))
+		}
+	}
+	var fresh []OutboxEvent
+	for _, row := range l.rows {
+		newer := row.XID > after.XID || row.XID == after.XID && row.ID > after.ID
+		if newer && row.XID < snap.xmax && !slices.Contains(snap.xip, row.XID) && len(fresh) < limit {
+			fresh = append(fresh, row)
+			ids = append(ids, strconv.FormatInt(row.ID, 10))
+		}
+	}
+	return ids, after.advance(snap, fresh, limit)
+}
+
+func (l *outboxLog) commit(xid int64) {
+	l.running = slices.DeleteFunc(l.running, func(x int64) bool { return x == xid })
+}
+
+func TestOutboxLongTransactionDoesNotStallFeed(t *testing.T) {
+	// Транзакция 10 идёт с самого начала и держит номер события 1
+	l := &outboxLog{rows: []OutboxEvent{{ID: 1, XID: 10}}, running: []int64{10}, next: 11}
+	position := OutboxPosition{XID: l.snapshot().xmax, Pending: l.snapshot().xip}
+
+	l.rows = append(l.rows, OutboxEvent{ID: 2, XID: 11}, OutboxEvent{ID: 3, XID: 12}, OutboxEvent{ID: 4, XID: 12})
+	l.running = append(l.running, 12)
+	l.next = 13
+	ids, position := l.since(position, 100)
+	if !slices.Equal(ids, []string{"2"}) {
+		t.Fatalf("with 10 and 12 running got %v, want [2]", ids)
+	}
+
+	l.commit(12)
+	l.rows = append(l.rows, OutboxEvent{ID: 5, XID: 13})
+	l.next = 14
+	ids, position = l.since(position, 100)
+	if !slices.Equal(ids, []string{"3", "4", "5"}) {
+		t.Fatalf("after 12 committed got %v, want [3 4 5] while 10 is still running", ids)
+	}
+	if !slices.Equal(position.Pending, []int64{10}) {
+		t.Fatalf("pending = %v, want [10]", position.Pending)
+	}
+
+	l.commit(10)
+	ids, position = l.since(position, 100)
+	if !slices.Equal(ids, []string{"1"}) || len(position.Pending) != 0 {
+		t.Fatalf("after 10 committed got %v, pending %v; want [1] and nothing pending", ids, position.Pending)
+	}
+	if ids, _ := l.since(position, 100); len(ids) != 0 {
+		t.Errorf("feed repeated %v", ids)
+	}
+}
+
+func TestOutboxPositionStopsAtLimit(t *testing.T) {
+	l := &outboxLog{
+		rows:    []OutboxEvent{{ID: 1, XID: 10}, {ID: 2, XID: 10}, {ID: 3, XID: 12}, {ID: 4, XID: 13}},
+		running: []int64{11},
+		next:    14,
+	}
+	ids, position := l.since(OutboxPosition{XID: 10}, 2)
+	if !slices.Equal(ids, []string{"1", "2"}) || position.XID != 10 || position.ID != 2 {
+		t.Fatalf("first page = %v at %+v", ids, position)
+	}
+	// Позиция ещё не прошла транзакцию 11, поэтому она не в Pending
+	if len(position.Pending) != 0 {
+		t.Fatalf("pending = %v before the position passed 11", position.Pending)
+	}
+	ids, position = l.since(position, 2)
+	if !slices.Equal(ids, []string{"3", "4"}) || !slices.Equal(position.Pending, []int64{11}) {
+		t.Fatalf("second page = %v, pending %v; want [3 4] and 11 pending", ids, position.Pending)
+	}
+	l.rows = append(l.rows, OutboxEvent{ID: 5, XID: 11})
+	l.commit(11)
+	if ids, _ := l.since(position, 2); !slices.Equal(ids, []string{"5"}) {
+		t.Errorf("after 11 committed got %v, want [5]", ids)
+	}
+}
@@ -0,0 +1,246 @@
+package repository
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"strconv"
+	"strings"
+	"task-tracker/internal/dto"
+	"time"
+)
+
+var ErrInvalidCursor = errors.New("invalid pagination cursor")
+
+type keyKind int
+
+const (
+	keyTime keyKind = iota
+	keyFloat
+	keyInt
+	keyText
+	keyUUID
+)
+
+// sortKey описывает один ключ сортировки списка строк типа T.
+// Пустые значения (NULL) всегда идут в конце, в любом направлении.
+type sortKey[T any] struct {
+	name     string
+	expr     string
+	kind     keyKind
+	desc     bool
+	nullable bool
+	value    func(*T) any // nil или указатель nil — NULL
+}
+
+type cursorPayload struct {
+	Sort   string    `json:"s"`
+	Values []*string `json:"v"`
+}
+
+// paginate применяет сортировку и выбирает страницу: по курсору (keyset)
+// или, если курсора нет, по смещению. Выбирается на одну строку больше,
+// чтобы узнать, есть ли следующая страница.
+func paginate[T any](query *gorm.DB, keys []sortKey[T], page dto.PageRequest) (*gorm.DB, error) {
+	for _, k := range keys {
+		direction := "ASC"
+		if k.desc {
+			direction = "DESC"
+		}
+		query = query.Order(k.expr + " " + direction + " NULLS LAST")
+	}
+
+	if page.Cursor != "" {
+		values, err := decodeCursor(keys, page.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		condition, args := keysetCondition(keys, values)
+		query = query.Where(condition, args...)
+	} else if page.Page > 1 {
+		query = query.Offset((page.Page - 1) * page.Limit)
+	}
+
+	return query.Limit(page.Limit + 1), nil
+}
+
+// buildPage обрезает лишнюю строку и заполняет информацию о странице
+func buildPage[T any](rows []T, total int64, keys []sortKey[T], page dto.PageRequest) *dto.ListResponse[T] {
+	info := dto.PageInfo{Limit: page.Limit}
+	if page.Cursor == "" {
+		info.Page = page.Page
+	}
+	if len(rows) > page.Limit {
+		rows = rows[:page.Limit]
+		info.HasMore = true
+		info.NextCursor = encodeCursor(keys, &rows[len(rows)-1])
+	}
+	if rows == nil {
+		rows = []T{}
+	}
+	return &dto.ListResponse[T]{
+		Items:    rows,
+		Total:    total,
+		PageInfo: info,
+	}
+}
+
+// keysetCondition строит условие "строка идёт после курсора" для лексикографического
+// порядка по ключам: (k1 > v1) OR (k1 = v1 AND k2 > v2) OR ...
+func keysetCondition[T any](keys []sortKey[T], values []any) (string, []any) {
+	var clauses []string
+	var args []any
+
+	for i, k := range keys {
+		after, afterArgs := keyAfter(k, values[i])
+		if after == "" {
+			continue
+		}
+
+		var parts []string
+		var partArgs []any
+		for j := 0; j < i; j++ {
+			eq, eqArgs := keyEqual(keys[j], values[j])
+			parts = append(parts, eq)
+			partArgs = append(partArgs, eqArgs...)
+		}
+		parts = append(parts, after)
+		partArgs = append(partArgs, afterArgs...)
+
+		clauses = append(clauses, "("+strings.Join(parts, " AND ")+")")
+		args = append(args, partArgs...)
+	}
+
+	if len(clauses) == 0 {
+		return "FALSE", nil
+	}
+	return "(" + strings.Join(clauses, " OR ") + ")", args
+}
+
+func keyEqual[T any](k sortKey[T], v any) (string, []any) {
+	if v == nil {
+		return k.expr + " IS NULL", nil
+	}
+	return k.expr + " = ?", []any{v}
+}
+
+// keyAfter возвращает условие "строго после v"; пустая строка — таких строк нет
+func keyAfter[T any](k sortKey[T], v any) (string, []any) {
+	if v == nil {
+		// NULL в конце: после него только такие же NULL, их разбирают следующие ключи
+		return "", nil
+	}
+	op := ">"
+	if k.desc {
+		op = "<"
+	}
+	if k.nullable {
+		return "(" + k.expr + " " + op + " ? OR " + k.expr + " IS NULL)", []any{v}
+	}
+	return k.expr + " " + op + " ?", []any{v}
+}
+
+func sortSignature[T any](keys []sortKey[T]) string {
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		parts[i] = k.name
+		if k.desc {
+			parts[i] = "-" + k.name
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+func encodeCursor[T any](keys []sortKey[T], row *T) string {
+	payload := cursorPayload{Sort: sortSignature(keys)}
+	for _, k := range keys {
+		payload.Values = append(payload.Values, formatKeyValue(k.value(row)))
+	}
+	data, _ := json.Marshal(payload)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor[T any](keys []sortKey[T], cursor string) ([]any, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var payload cursorPayload
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	// Курсор действителен только для того же порядка сортировки
+	if payload.Sort != sortSignature(keys) || len(payload.Values) != len(keys) {
+		return nil, fmt.Errorf("%w: sort order does not match", ErrInvalidCursor)
+	}
+
+	values := make([]any, len(keys))
+	for i, k := range keys {
+		if payload.Values[i] == nil {
+			if !k.nullable {
+				return nil, ErrInvalidCursor
+			}
+			continue
+		}
+		v, err := parseKeyValue(k.kind, *payload.Values[i])
+		if err != nil {
+			return nil, ErrInvalidCursor
+		}
+		values[i] = v
+	}
+	return values, nil
+}
+
+func formatKeyValue(v any) *string {
+	var s string
+	switch v := v.(type) {
+	case nil:
+		return nil
+	case *time.Time:
+		if v == nil {
+			return nil
+		}
+		s = v.UTC().Format(time.RFC3339Nano)
+	case time.Time:
+		s = v.UTC().Format(time.RFC3339Nano)
+	case *float64:
+		if v == nil {
+			return nil
+		}
+		s = strconv.FormatFloat(*v, 'g', -1, 64)
+	case float64:
+		s = strconv.FormatFloat(v, 'g', -1, 64)
+	case int:
+		s = strconv.Itoa(v)
+	case string:
+		s = v
+	case uuid.UUID:
+		s = v.String()
+	case *uuid.UUID:
+		if v == nil {
+			return nil
+		}
+		s = v.String()
+	default:
+		s = fmt.Sprint(v)
+	}
+	return &s
+}
+
+func parseKeyValue(kind keyKind, s string) (any, error) {
+	switch kind {
+	case keyTime:
+		return time.Parse(time.RFC3339Nano, s)
+	case keyFloat:
+		return strconv.ParseFloat(s, 64)
+	case keyInt:
+		return strconv.Atoi(s)
+	case keyUUID:
+		return uuid.Parse(s)
+	default:
+		return s, nil
+	}
+}
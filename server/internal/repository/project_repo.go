@@ -1,20 +1,35 @@
 package repository
 
 import (
+	"errors"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"slices"
+	"strconv"
 	"task-tracker/internal/dto"
 	"task-tracker/internal/models"
+	"task-tracker/pkg/events"
 )
 
 type ProjectRepository interface {
 	Create(project *models.Project) error
 	FindByID(id uuid.UUID) (*models.Project, error)
 	Update(project *models.Project) error
-	Delete(id uuid.UUID) error
-	List(userID uuid.UUID, limit, offset int) ([]dto.ListProjectsResponse, error)
+	// Delete удаляет проект с задачами; с version — только если проект не менялся, иначе ErrVersionConflict
+	Delete(id uuid.UUID, version *int) error
+	List(userID uuid.UUID, page dto.PageRequest) (*dto.ListResponse[dto.ListProjectsResponse], error)
+	FindByName(userID uuid.UUID, name string) ([]models.Project, error)
+	IsVisible(id, userID uuid.UUID) (bool, error)
+	AvailableKey(base string) (string, error)
+	Exists(id uuid.UUID) (bool, error)
+	ViewerIDs(id uuid.UUID) ([]uuid.UUID, error)
+	Transaction(fn func(repo ProjectRepository) error) error
+	// AddEvents пишет события в outbox; вызывается в транзакции изменения
+	AddEvents(evts []events.Event) error
 }
 
+var ErrProjectKeyTaken = errors.New("project key is already taken")
+
 type projectRepo struct {
 	db *gorm.DB
 }
@@ -24,7 +39,14 @@ func NewProjectRepository(db *gorm.DB) ProjectRepository {
 }
 
 func (r *projectRepo) Create(project *models.Project) error {
-	return r.db.Create(project).Error
+	// Точка сохранения внутри внешней транзакции: после занятого ключа её можно продолжать
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		return tx.Create(project).Error
+	})
+	if isUniqueViolation(err) {
+		return ErrProjectKeyTaken
+	}
+	return err
 }
 
 func (r *projectRepo) FindByID(id uuid.UUID) (*models.Project, error) {
@@ -34,44 +56,170 @@ func (r *projectRepo) FindByID(id uuid.UUID) (*models.Project, error) {
 }
 
 func (r *projectRepo) Update(project *models.Project) error {
-	return r.db.Save(project).Error
+	// Ключ неизменяем, а счётчиком номеров распоряжается только хук задачи
+	return saveVersioned(r.db, project, &project.Version, "key", "task_counter")
 }
 
-func (r *projectRepo) Delete(id uuid.UUID) error {
-	// Начинаем транзакцию
-	tx := r.db.Begin()
-
-	// Сначала удаляем все задачи проекта
-	if err := tx.Where("project_id = ?", id).Delete(&models.Task{}).Error; err != nil {
-		tx.Rollback()
-		return err
-	}
-
-	// Затем удаляем сам проект
-	if err := tx.Delete(&models.Project{}, "id = ?", id).Error; err != nil {
-		tx.Rollback()
-		return err
-	}
+func (r *projectRepo) Delete(id uuid.UUID, version *int) error {
+	// Внутри внешней транзакции (пакетный запрос) станет точкой сохранения
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tombstoneProject(tx, id); err != nil {
+			return err
+		}
+		if err := tombstoneTasks(tx, "project_id = ?", id); err != nil {
+			return err
+		}
 
-	// Коммитим транзакцию
-	return tx.Commit().Error
+		// Сначала удаляем все задачи проекта, затем сам проект; при конфликте версий
+		// транзакция откатывает и задачи
+		if err := tx.Where("project_id = ?", id).Delete(&models.Task{}).Error; err != nil {
+			return err
+		}
+		return deleteVersioned(tx, &models.Project{}, id, version)
+	})
 }
 
-func (r *projectRepo) List(userID uuid.UUID, limit, offset int) ([]dto.ListProjectsResponse, error) {
-	var projects []dto.ListProjectsResponse
+func (r *projectRepo) List(userID uuid.UUID, page dto.PageRequest) (*dto.ListResponse[dto.ListProjectsResponse], error) {
+	var total int64
+	if err := r.db.Model(&models.Project{}).Where("user_id = ?", userID).Count(&total).Error; err != nil {
+		return nil, err
+	}
 
-	err := r.db.Model(&models.Project{}).
+	query := r.db.Model(&models.Project{}).
 		Select(`
         projects.*,
         COUNT(tasks.id) as total_tasks,
-        SUM(CASE WHEN tasks.status = 'done' THEN 1 ELSE 0 END) as completed_tasks
+        SUM(CASE WHEN tasks.status = 'done' THEN 1 ELSE 0 END) as completed_tasks,
+        COALESCE(SUM(tasks.estimate), 0) as total_estimate,
+        COALESCE(SUM(CASE WHEN tasks.status <> 'done' THEN COALESCE(tasks.remaining_estimate, tasks.estimate) END), 0) as remaining_estimate
     `).
 		Joins("LEFT JOIN tasks ON tasks.project_id = projects.id").
 		Where("projects.user_id = ?", userID).
-		Group("projects.id").
-		Order("projects.created_at DESC").
-		Limit(limit).Offset(offset).
-		Scan(&projects).Error
+		Group("projects.id")
+
+	query, err := paginate(query, projectSortKeys, page)
+	if err != nil {
+		return nil, err
+	}
+
+	var projects []dto.ListProjectsResponse
+	if err := query.Scan(&projects).Error; err != nil {
+		return nil, err
+	}
+	return buildPage(projects, total, projectSortKeys, page), nil
+}
 
+// FindByName ищет без учёта регистра среди проектов пользователя и проектов, где у него есть задачи
+func (r *projectRepo) FindByName(userID uuid.UUID, name string) ([]models.Project, error) {
+	var projects []models.Project
+	err := r.db.
+		Where("lower(name) = lower(?)", name).
+		Where("id IN "+visibleProjectIDs, userID, userID).
+		Find(&projects).Error
 	return projects, err
 }
+
+func (r *projectRepo) IsVisible(id, userID uuid.UUID) (bool, error) {
+	var count int64
+	err := r.db.Model(&models.Project{}).
+		Where("id = ? AND id IN "+visibleProjectIDs, id, userID, userID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// Exists проверяет, занят ли идентификатор, без учёта видимости проекта
+func (r *projectRepo) Exists(id uuid.UUID) (bool, error) {
+	var count int64
+	err := r.db.Model(&models.Project{}).Where("id = ?", id).Count(&count).Error
+	return count > 0, err
+}
+
+// ViewerIDs возвращает пользователей, которые видят проект: владельца и всех, у кого в нём есть задачи
+func (r *projectRepo) ViewerIDs(id uuid.UUID) ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+	err := r.db.Raw(`SELECT user_id FROM projects WHERE id = ?
+        UNION SELECT user_id FROM tasks WHERE project_id = ?`, id, id).
+		Scan(&ids).Error
+	return ids, err
+}
+
+// Transaction выполняет fn с репозиторием, работающим внутри транзакции.
+// Вложенный вызов на таком репозитории открывает точку сохранения
+func (r *projectRepo) Transaction(fn func(repo ProjectRepository) error) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return fn(&projectRepo{db: tx})
+	})
+}
+
+func (r *projectRepo) AddEvents(evts []events.Event) error {
+	return writeOutbox(r.db, evts)
+}
+
+func (r *projectRepo) AvailableKey(base string) (string, error) {
+	return availableProjectKey(r.db, base)
+}
+
+// availableProjectKey возвращает base или, если он занят, base с наименьшим свободным номером (BACK2, BACK3…)
+func availableProjectKey(db *gorm.DB, base string) (string, error) {
+	var taken []string
+	if err := db.Model(&models.Project{}).Where("key LIKE ?", base+"%").Pluck("key", &taken).Error; err != nil {
+		return "", err
+	}
+
+	key := base
+	for n := 2; slices.Contains(taken, key); n++ {
+		suffix := strconv.Itoa(n)
+		key = base[:min(len(base), 10-len(suffix))] + suffix
+	}
+	return key, nil
+}
+
+// Проекты всегда идут от новых к старым
+var projectSortKeys = []sortKey[dto.ListProjectsResponse]{
+	{
+		name: "created_at", expr: "projects.created_at", kind: keyTime, desc: true,
+		value: func(p *dto.ListProjectsResponse) any { return p.CreatedAt },
+	},
+	{
+		name: "id", expr: "projects.id", kind: keyUUID, desc: true,
+		value: func(p *dto.ListProjectsResponse) any { return p.ID },
+	},
+}
+
+// MigrateTaskKeys выдаёт ключи проектам и номера задачам, созданным до появления ключей
+func MigrateTaskKeys(db *gorm.DB) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("CREATE UNIQUE INDEX IF NOT EXISTS idx_tasks_project_number ON tasks (project_id, number)").Error; err != nil {
+			return err
+		}
+
+		var projects []models.Project
+		if err := tx.Where("key IS NULL OR key = ''").Order("created_at ASC").Find(&projects).Error; err != nil {
+			return err
+		}
+		for _, p := range projects {
+			key, err := availableProjectKey(tx, models.ProjectKeyBase(p.Name))
+			if err != nil {
+				return err
+			}
+			if err := tx.Model(&models.Project{}).Where("id = ?", p.ID).Update("key", key).Error; err != nil {
+				return err
+			}
+		}
+
+		err := tx.Exec(`WITH numbered AS (
+            SELECT tasks.id, projects.task_counter + row_number() OVER (
+                PARTITION BY tasks.project_id ORDER BY tasks.created_at, tasks.id
+            ) as number
+            FROM tasks JOIN projects ON projects.id = tasks.project_id
+            WHERE tasks.number IS NULL
+        )
+        UPDATE tasks SET number = numbered.number FROM numbered WHERE tasks.id = numbered.id`).Error
+		if err != nil {
+			return err
+		}
+		return tx.Exec(`UPDATE projects SET task_counter = numbers.max
+            FROM (SELECT project_id, MAX(number) as max FROM tasks WHERE project_id IS NOT NULL GROUP BY project_id) numbers
+            WHERE projects.id = numbers.project_id AND projects.task_counter < numbers.max`).Error
+	})
+}
@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"task-tracker/internal/models"
+)
+
+type SavedViewRepository interface {
+	Create(view *models.SavedView) error
+	// FindByID загружает представление; Pinned заполняется для userID
+	FindByID(id, userID uuid.UUID) (*models.SavedView, error)
+	Update(view *models.SavedView) error
+	Delete(id uuid.UUID) error
+	ListVisible(userID uuid.UUID, projectID *uuid.UUID, pinnedOnly bool) ([]models.SavedView, error)
+	SetPinned(id, userID uuid.UUID, pinned bool) error
+}
+
+// viewPinned — закреплено ли представление у пользователя из параметра запроса
+const viewPinned = "EXISTS (SELECT 1 FROM saved_view_pins WHERE saved_view_pins.view_id = saved_views.id AND saved_view_pins.user_id = ?)"
+
+type savedViewRepo struct {
+	db *gorm.DB
+}
+
+func NewSavedViewRepository(db *gorm.DB) SavedViewRepository {
+	return &savedViewRepo{db: db}
+}
+
+func (r *savedViewRepo) Create(view *models.SavedView) error {
+	return r.db.Create(view).Error
+}
+
+func (r *savedViewRepo) FindByID(id, userID uuid.UUID) (*models.SavedView, error) {
+	var view models.SavedView
+	err := r.withPinned(userID).First(&view, "saved_views.id = ?", id).Error
+	return &view, err
+}
+
+func (r *savedViewRepo) Update(view *models.SavedView) error {
+	return r.db.Omit(clause.Associations).Save(view).Error
+}
+
+func (r *savedViewRepo) Delete(id uuid.UUID) error {
+	return r.db.Delete(&models.SavedView{}, "id = ?", id).Error
+}
+
+// ListVisible возвращает личные представления пользователя и общие представления видимых ему проектов
+func (r *savedViewRepo) ListVisible(userID uuid.UUID, projectID *uuid.UUID, pinnedOnly bool) ([]models.SavedView, error) {
+	query := r.withPinned(userID).Where(
+		"(saved_views.project_id IS NULL AND saved_views.user_id = ?) OR saved_views.project_id IN "+visibleProjectIDs,
+		userID, userID, userID,
+	)
+	if projectID != nil {
+		query = query.Where("saved_views.project_id = ?", *projectID)
+	}
+	if pinnedOnly {
+		query = query.Where(viewPinned, userID)
+	}
+
+	views := []models.SavedView{}
+	err := query.Order("saved_views.name ASC, saved_views.created_at ASC").Find(&views).Error
+	return views, err
+}
+
+func (r *savedViewRepo) SetPinned(id, userID uuid.UUID, pinned bool) error {
+	if !pinned {
+		return r.db.Delete(&models.SavedViewPin{}, "view_id = ? AND user_id = ?", id, userID).Error
+	}
+	return r.db.Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&models.SavedViewPin{ViewID: id, UserID: userID}).Error
+}
+
+func (r *savedViewRepo) withPinned(userID uuid.UUID) *gorm.DB {
+	return r.db.Select("saved_views.*, "+viewPinned+" AS pinned", userID)
+}
+
+// MigrateSavedViewPins переносит закрепления из прежнего флага saved_views.pinned,
+// общего для всех участников проекта, в личные закрепления автора
+func MigrateSavedViewPins(db *gorm.DB) error {
+	if !db.Migrator().HasColumn(&models.SavedView{}, "pinned") {
+		return nil
+	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Exec(`INSERT INTO saved_view_pins (user_id, view_id)
+            SELECT user_id, id FROM saved_views WHERE pinned
+            ON CONFLICT DO NOTHING`).Error
+		if err != nil {
+			return err
+		}
+		return tx.Exec("ALTER TABLE saved_views DROP COLUMN pinned").Error
+	})
+}
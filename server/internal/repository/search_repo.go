@@ -0,0 +1,140 @@
+package repository
+
+import (
+	"database/sql"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"strings"
+	"task-tracker/internal/dto"
+	"task-tracker/internal/models"
+)
+
+const (
+	SearchTasks    = "task"
+	SearchProjects = "project"
+)
+
+type SearchRepository interface {
+	Search(userID uuid.UUID, query string, types []string, limit int) ([]dto.SearchHit, error)
+}
+
+type searchRepo struct {
+	db *gorm.DB
+}
+
+func NewSearchRepository(db *gorm.DB) SearchRepository {
+	return &searchRepo{db: db}
+}
+
+// MigrateSearch добавляет поисковые векторы и индексы, которые AutoMigrate описать не умеет.
+// Конфигурация russian разбирает латиницу английским стеммером, english добавлена ради его стоп-слов
+func MigrateSearch(db *gorm.DB) error {
+	statements := []string{
+		`ALTER TABLE tasks ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (` +
+			weightedVector("title", "description") + `) STORED`,
+		`ALTER TABLE projects ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (` +
+			weightedVector("name", "description") + `) STORED`,
+		`CREATE INDEX IF NOT EXISTS idx_tasks_search_vector ON tasks USING GIN (search_vector)`,
+		`CREATE INDEX IF NOT EXISTS idx_projects_search_vector ON projects USING GIN (search_vector)`,
+		// Триграммы ловят опечатки и подстроки, которых нет в словаре
+		`CREATE INDEX IF NOT EXISTS idx_tasks_title_trgm ON tasks USING GIN (title gin_trgm_ops)`,
+		`CREATE INDEX IF NOT EXISTS idx_tasks_description_trgm ON tasks USING GIN (description gin_trgm_ops)`,
+		`CREATE INDEX IF NOT EXISTS idx_projects_name_trgm ON projects USING GIN (name gin_trgm_ops)`,
+	}
+	for _, stmt := range statements {
+		if err := db.Exec(stmt).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// weightedVector — заголовок с весом A, описание с весом B
+func weightedVector(title, description string) string {
+	var parts []string
+	for _, config := range []string{"russian", "english"} {
+		parts = append(parts,
+			"setweight(to_tsvector('"+config+"', coalesce("+title+", '')), 'A')",
+			"setweight(to_tsvector('"+config+"', coalesce("+description+", '')), 'B')",
+		)
+	}
+	return strings.Join(parts, " || ")
+}
+
+// tsQuery понимает синтаксис веб-поиска: "фраза", or, -исключение
+const tsQuery = "(websearch_to_tsquery('russian', @q) || websearch_to_tsquery('english', @q))"
+
+// searchMatch — совпадение по словарю, по похожести слова из заголовка (опечатки) или по подстроке
+func searchMatch(table, title, description string) string {
+	return "(" + table + ".search_vector @@ " + tsQuery +
+		" OR @q <% " + table + "." + title +
+		" OR " + table + "." + title + " ILIKE @like" +
+		" OR " + table + "." + description + " ILIKE @like )"
+}
+
+func searchRank(table, title string) string {
+	return "ts_rank(" + table + ".search_vector, " + tsQuery + ") + word_similarity(@q, " + table + "." + title + ")"
+}
+
+// headline подсвечивает совпадения тегом <mark>; исходный текст экранируется,
+// поэтому результат можно вставлять как HTML
+func headline(column, options string) string {
+	escaped := "replace(replace(replace(coalesce(" + column + ", ''), '&', '&amp;'), '<', '&lt;'), '>', '&gt;')"
+	return "ts_headline('russian', " + escaped + ", " + tsQuery + ", 'StartSel=<mark>, StopSel=</mark>, " + options + "')"
+}
+
+const (
+	titleHeadline   = "HighlightAll=true"
+	snippetHeadline = "MaxFragments=2, MaxWords=20, MinWords=5, FragmentDelimiter=\" … \""
+)
+
+// Подстрока ищется без кавычек: они нужны только синтаксису websearch_to_tsquery
+func searchArgs(query string) []any {
+	plain := strings.Join(strings.Fields(strings.ReplaceAll(query, `"`, " ")), " ")
+	return []any{sql.Named("q", query), sql.Named("like", likePattern(plain))}
+}
+
+// likePattern экранирует спецсимволы LIKE, чтобы они искались буквально
+func likePattern(s string) string {
+	s = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+	return "%" + s + "%"
+}
+
+func (r *searchRepo) Search(userID uuid.UUID, query string, types []string, limit int) ([]dto.SearchHit, error) {
+	args := searchArgs(query)
+
+	var parts []any
+	for _, t := range types {
+		switch t {
+		case SearchTasks:
+			parts = append(parts, r.db.Model(&models.Task{}).
+				Select(`'task' as type, tasks.id, tasks.project_id, tasks.title, tasks.status,
+                    `+headline("tasks.title", titleHeadline)+` as highlight,
+                    `+headline("tasks.description", snippetHeadline)+` as snippet,
+                    `+searchRank("tasks", "title")+` as rank`, args...).
+				Scopes(visibleTasks(userID)).
+				Where(searchMatch("tasks", "title", "description"), args...))
+		case SearchProjects:
+			parts = append(parts, r.db.Model(&models.Project{}).
+				Select(`'project' as type, projects.id, NULL::uuid as project_id, projects.name as title, NULL::varchar as status,
+                    `+headline("projects.name", titleHeadline)+` as highlight,
+                    `+headline("projects.description", snippetHeadline)+` as snippet,
+                    `+searchRank("projects", "name")+` as rank`, args...).
+				Where("projects.user_id = ?", userID).
+				Where(searchMatch("projects", "name", "description"), args...))
+		}
+	}
+
+	hits := []dto.SearchHit{}
+	if len(parts) == 0 {
+		return hits, nil
+	}
+
+	union := strings.TrimSuffix(strings.Repeat("(?) UNION ALL ", len(parts)), " UNION ALL ")
+	err := r.db.Raw("SELECT * FROM ("+union+") hits ORDER BY rank DESC, title ASC LIMIT ?", append(parts, limit)...).
+		Scan(&hits).Error
+	if err != nil {
+		return nil, err
+	}
+	return hits, nil
+}
@@ -0,0 +1,27 @@
+package repository
+
+import (
+	"database/sql"
+	"testing"
+)
+
+func TestSearchArgs(t *testing.T) {
+	tests := []struct {
+		query, like string
+	}{
+		{`login bug`, `%login bug%`},
+		{`"login bug"`, `%login bug%`},
+		{`"login  bug" crash`, `%login bug crash%`},
+		{`100% _done_ C:\tmp`, `%100\% \_done\_ C:\\tmp%`},
+	}
+	for _, tt := range tests {
+		args := searchArgs(tt.query)
+		q, like := args[0].(sql.NamedArg), args[1].(sql.NamedArg)
+		if q.Value != tt.query {
+			t.Errorf("searchArgs(%q) q = %q, want the query unchanged", tt.query, q.Value)
+		}
+		if like.Value != tt.like {
+			t.Errorf("searchArgs(%q) like = %q, want %q", tt.query, like.Value, tt.like)
+		}
+	}
+}
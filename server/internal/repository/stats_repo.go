@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"task-tracker/internal/dto"
+	"task-tracker/internal/models"
+)
+
+type StatsRepository interface {
+	Overview(userID uuid.UUID, bounds dto.StatsBounds) (*dto.StatsOverview, error)
+}
+
+type statsRepo struct {
+	db *gorm.DB
+}
+
+func NewStatsRepository(db *gorm.DB) StatsRepository {
+	return &statsRepo{db: db}
+}
+
+// taskCountsSelect считает все счётчики dto.TaskCounts за один проход по задачам
+const taskCountsSelect = `
+        COUNT(*) as total,
+        COUNT(*) FILTER (WHERE tasks.status = 'todo') as todo,
+        COUNT(*) FILTER (WHERE tasks.status = 'in_progress') as in_progress,
+        COUNT(*) FILTER (WHERE tasks.status = 'done') as done,
+        COUNT(*) FILTER (WHERE tasks.priority = 'low') as low_priority,
+        COUNT(*) FILTER (WHERE tasks.priority = 'medium') as medium_priority,
+        COUNT(*) FILTER (WHERE tasks.priority = 'high') as high_priority,
+        COUNT(*) FILTER (WHERE tasks.status <> 'done' AND tasks.due_date < ?) as overdue,
+        COUNT(*) FILTER (WHERE tasks.status <> 'done' AND tasks.due_date >= ? AND tasks.due_date < ?) as due_today,
+        COUNT(*) FILTER (WHERE tasks.status <> 'done' AND tasks.due_date >= ? AND tasks.due_date < ?) as due_this_week,
+        COUNT(*) FILTER (WHERE tasks.status = 'done' AND COALESCE(tasks.completed_at, tasks.updated_at) >= ?) as completed_last7_days,
+        COUNT(*) FILTER (WHERE tasks.status = 'done' AND COALESCE(tasks.completed_at, tasks.updated_at) >= ?) as completed_last30_days`
+
+func taskCountsArgs(b dto.StatsBounds) []any {
+	return []any{
+		b.Today,
+		b.Today, b.Tomorrow,
+		b.Today, b.WeekEnd,
+		b.CompletedSince7,
+		b.CompletedSince30,
+	}
+}
+
+func (r *statsRepo) Overview(userID uuid.UUID, bounds dto.StatsBounds) (*dto.StatsOverview, error) {
+	var overview dto.StatsOverview
+	err := r.db.Model(&models.Task{}).
+		Select(taskCountsSelect, taskCountsArgs(bounds)...).
+		Scopes(visibleTasks(userID)).
+		Scan(&overview.TaskCounts).Error
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.db.Model(&models.Project{}).Where("user_id = ?", userID).Count(&overview.TotalProjects).Error; err != nil {
+		return nil, err
+	}
+
+	// Активный проект — тот, в котором есть видимая незавершённая задача
+	err = r.db.Model(&models.Task{}).
+		Scopes(visibleTasks(userID)).
+		Where("tasks.project_id IS NOT NULL AND tasks.status <> ?", models.StatusDone).
+		Distinct("tasks.project_id").
+		Count(&overview.ActiveProjects).Error
+	if err != nil {
+		return nil, err
+	}
+
+	overview.Projects = []dto.ProjectStats{}
+	err = r.db.Model(&models.Task{}).
+		Select("tasks.project_id, projects.name as project_name, projects.color,"+taskCountsSelect, taskCountsArgs(bounds)...).
+		Joins("LEFT JOIN projects ON projects.id = tasks.project_id").
+		Scopes(visibleTasks(userID)).
+		Group("tasks.project_id, projects.name, projects.color").
+		Order("projects.name ASC NULLS LAST").
+		Scan(&overview.Projects).Error
+	if err != nil {
+		return nil, err
+	}
+	return &overview, nil
+}
@@ -0,0 +1,272 @@
+package repository
+
+import (
+	"errors"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"task-tracker/internal/models"
+	"time"
+)
+
+// Изменения из-за удалённого надгробия клиент уже не получит: нужна полная синхронизация
+var ErrSyncPositionExpired = errors.New("sync position is older than retained deletions")
+
+// SyncPosition — место в журнале изменений: идентификатор транзакции, записавшей строку,
+// и номер записи внутри неё. Строки задач, проектов и надгробий упорядочены по этой паре.
+// TombstonesFrom — граница, с которой клиенту нужны удаления: при первой выгрузке это
+// момент её начала, ведь объектов, удалённых раньше, у клиента нет
+type SyncPosition struct {
+	XID            int64
+	Seq            int64
+	TombstonesFrom int64
+}
+
+type SyncChange struct {
+	Resource string // task или project
+	ID       uuid.UUID
+	Deleted  bool
+	Position SyncPosition
+}
+
+type SyncRepository interface {
+	// Watermark — граница уже зафиксированных транзакций, начало первой выгрузки
+	Watermark() (int64, error)
+	// Changes возвращает до limit изменений после after, видимых пользователю
+	Changes(userID uuid.UUID, after SyncPosition, limit int) ([]SyncChange, error)
+	FindTasks(ids []uuid.UUID) ([]models.Task, error)
+	FindProjects(ids []uuid.UUID) ([]models.Project, error)
+	PurgeTombstones(before time.Time) (int64, error)
+}
+
+type syncRepo struct {
+	db *gorm.DB
+}
+
+func NewSyncRepository(db *gorm.DB) SyncRepository {
+	return &syncRepo{db: db}
+}
+
+func (r *syncRepo) Watermark() (int64, error) {
+	var xmin int64
+	err := r.db.Raw("SELECT txid_snapshot_xmin(txid_current_snapshot())").Scan(&xmin).Error
+	return xmin, err
+}
+
+// Changes отдаёт только строки транзакций старше самой старой из ещё не завершённых:
+// всё, что ниже этой границы, уже зафиксировано, и новые записи за неё не попадут.
+// Так курсор не проскочит изменения медленной транзакции, которая зафиксируется позже
+func (r *syncRepo) Changes(userID uuid.UUID, after SyncPosition, limit int) ([]SyncChange, error) {
+	var purged struct {
+		PurgedXID int64 `gorm:"column:purged_xid"`
+		PurgedSeq int64 `gorm:"column:purged_seq"`
+	}
+	if err := r.db.Raw("SELECT purged_xid, purged_seq FROM sync_state WHERE id = 1").Scan(&purged).Error; err != nil {
+		return nil, err
+	}
+	// Нужные клиенту удаления начинаются с позиции курсора, но не раньше TombstonesFrom
+	needed := after
+	if needed.XID < after.TombstonesFrom {
+		needed = SyncPosition{XID: after.TombstonesFrom}
+	}
+	if needed.XID < purged.PurgedXID || (needed.XID == purged.PurgedXID && needed.Seq < purged.PurgedSeq) {
+		return nil, ErrSyncPositionExpired
+	}
+
+	var rows []struct {
+		Resource  string
+		ID        uuid.UUID
+		Deleted   bool
+		ChangeXID int64 `gorm:"column:change_xid"`
+		ChangeSeq int64 `gorm:"column:change_seq"`
+	}
+	err := r.db.Raw(`SELECT * FROM (
+            SELECT 'task' as resource, tasks.id, false as deleted, tasks.change_xid, tasks.change_seq
+            FROM tasks
+            WHERE (tasks.user_id = @user OR tasks.project_id IN (SELECT id FROM projects WHERE projects.user_id = @user))
+            UNION ALL
+            SELECT 'project', projects.id, false, projects.change_xid, projects.change_seq
+            FROM projects
+            WHERE projects.id IN (SELECT id FROM projects WHERE projects.user_id = @user
+                UNION SELECT project_id FROM tasks WHERE tasks.user_id = @user AND project_id IS NOT NULL)
+            UNION ALL
+            SELECT resource, resource_id, true, change_xid, change_seq
+            FROM sync_tombstones
+            WHERE @user = ANY(visible_to) AND change_xid >= @from
+        ) changes
+        WHERE (change_xid, change_seq) > (@xid, @seq)
+            AND change_xid < txid_snapshot_xmin(txid_current_snapshot())
+        ORDER BY change_xid, change_seq
+        LIMIT @limit`,
+		map[string]any{"user": userID, "xid": after.XID, "seq": after.Seq, "from": after.TombstonesFrom, "limit": limit},
+	).Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	changes := make([]SyncChange, len(rows))
+	for i, row := range rows {
+		changes[i] = SyncChange{
+			Resource: row.Resource,
+			ID:       row.ID,
+			Deleted:  row.Deleted,
+			Position: SyncPosition{XID: row.ChangeXID, Seq: row.ChangeSeq},
+		}
+	}
+	return changes, nil
+}
+
+func (r *syncRepo) FindTasks(ids []uuid.UUID) ([]models.Task, error) {
+	var tasks []models.Task
+	if len(ids) == 0 {
+		return tasks, nil
+	}
+	err := (&taskRepo{db: r.db}).withAggregates(r.db).
+		Preload("Checklist", func(db *gorm.DB) *gorm.DB {
+			return db.Order("position ASC")
+		}).
+		Where("tasks.id IN ?", ids).
+		Find(&tasks).Error
+	return tasks, err
+}
+
+func (r *syncRepo) FindProjects(ids []uuid.UUID) ([]models.Project, error) {
+	var projects []models.Project
+	if len(ids) == 0 {
+		return projects, nil
+	}
+	err := r.db.Where("id IN ?", ids).Find(&projects).Error
+	return projects, err
+}
+
+// PurgeTombstones удаляет надгробия старше before и запоминает позицию последнего из них:
+// курсоры до неё больше не дают полной картины удалений
+func (r *syncRepo) PurgeTombstones(before time.Time) (int64, error) {
+	result := r.db.Exec(`WITH purged AS (
+            DELETE FROM sync_tombstones WHERE created_at < ? RETURNING change_xid, change_seq
+        ), last AS (
+            SELECT change_xid, change_seq FROM purged ORDER BY change_xid DESC, change_seq DESC LIMIT 1
+        )
+        UPDATE sync_state SET purged_xid = last.change_xid, purged_seq = last.change_seq
+        FROM last
+        WHERE sync_state.id = 1 AND (last.change_xid, last.change_seq) > (sync_state.purged_xid, sync_state.purged_seq)`,
+		before)
+	return result.RowsAffected, result.Error
+}
+
+// tombstoneTasks записывает надгробия задач, выбранных условием where, и их подзадач,
+// которые база удалит каскадно. Надгробие видят владелец задачи и владелец её проекта.
+// Вызывается в транзакции удаления до самого удаления
+func tombstoneTasks(tx *gorm.DB, where string, args ...any) error {
+	return tx.Exec(`WITH RECURSIVE doomed AS (
+            SELECT id, user_id, project_id FROM tasks WHERE `+where+`
+            UNION
+            SELECT tasks.id, tasks.user_id, tasks.project_id FROM tasks JOIN doomed ON tasks.parent_id = doomed.id
+        )
+        INSERT INTO sync_tombstones (resource, resource_id, visible_to)
+        SELECT 'task', doomed.id, array_remove(ARRAY[doomed.user_id, projects.user_id], NULL)
+        FROM doomed LEFT JOIN projects ON projects.id = doomed.project_id`, args...).Error
+}
+
+// tombstoneProject записывает надгробие проекта для его владельца и всех, у кого в нём есть задачи
+func tombstoneProject(tx *gorm.DB, id uuid.UUID) error {
+	return tx.Exec(`INSERT INTO sync_tombstones (resource, resource_id, visible_to)
+        SELECT 'project', projects.id, ARRAY(
+            SELECT projects.user_id UNION SELECT tasks.user_id FROM tasks WHERE tasks.project_id = projects.id
+        )
+        FROM projects WHERE projects.id = ?`, id).Error
+}
+
+// MigrateSync готовит журнал изменений для дельта-синхронизации: каждая запись в tasks и
+// projects получает позицию (транзакция, номер) триггером, поэтому её не пропустят ни
+// сохранения через gorm, ни точечные UPDATE (ранги, счётчик номеров). Удаления и потеря
+// видимости оставляют надгробия в sync_tombstones
+func MigrateSync(db *gorm.DB) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		statements := []string{
+			"CREATE SEQUENCE IF NOT EXISTS sync_change_seq",
+			`CREATE OR REPLACE FUNCTION sync_stamp() RETURNS trigger AS $$
+            DECLARE
+                ignored text[] := COALESCE(TG_ARGV, '{}') || ARRAY['change_xid', 'change_seq'];
+            BEGIN
+                -- Изменение только служебных колонок клиентам не интересно
+                IF TG_OP = 'UPDATE' AND (to_jsonb(NEW) - ignored) = (to_jsonb(OLD) - ignored) THEN
+                    RETURN NEW;
+                END IF;
+                NEW.change_xid := txid_current();
+                NEW.change_seq := nextval('sync_change_seq');
+                RETURN NEW;
+            END $$ LANGUAGE plpgsql`,
+			`CREATE TABLE IF NOT EXISTS sync_tombstones (
+                resource varchar(20) NOT NULL,
+                resource_id uuid NOT NULL,
+                visible_to uuid[] NOT NULL,
+                change_xid bigint NOT NULL DEFAULT txid_current(),
+                change_seq bigint NOT NULL DEFAULT nextval('sync_change_seq'),
+                created_at timestamptz NOT NULL DEFAULT now()
+            )`,
+			"CREATE INDEX IF NOT EXISTS idx_sync_tombstones_change ON sync_tombstones (change_xid, change_seq)",
+			"CREATE INDEX IF NOT EXISTS idx_sync_tombstones_created_at ON sync_tombstones (created_at)",
+			`CREATE TABLE IF NOT EXISTS sync_state (
+                id int PRIMARY KEY,
+                purged_xid bigint NOT NULL DEFAULT 0,
+                purged_seq bigint NOT NULL DEFAULT 0
+            )`,
+			"INSERT INTO sync_state (id) VALUES (1) ON CONFLICT DO NOTHING",
+		}
+		// Счётчик номеров задач меняется при каждом создании задачи в проекте — сам проект от этого не меняется
+		for _, t := range []struct{ table, ignored string }{{"tasks", ""}, {"projects", "'task_counter'"}} {
+			table, ignored := t.table, t.ignored
+			statements = append(statements,
+				"ALTER TABLE "+table+" ADD COLUMN IF NOT EXISTS change_xid bigint NOT NULL DEFAULT 0",
+				"ALTER TABLE "+table+" ADD COLUMN IF NOT EXISTS change_seq bigint NOT NULL DEFAULT 0",
+				"DROP TRIGGER IF EXISTS "+table+"_sync_stamp ON "+table,
+				"CREATE TRIGGER "+table+"_sync_stamp BEFORE INSERT OR UPDATE ON "+table+
+					" FOR EACH ROW EXECUTE FUNCTION sync_stamp("+ignored+")",
+				// Строки, созданные до журнала, получают позиции один раз
+				"UPDATE "+table+" SET change_xid = txid_current(), change_seq = nextval('sync_change_seq') WHERE change_seq = 0",
+				"CREATE INDEX IF NOT EXISTS idx_"+table+"_change ON "+table+" (change_xid, change_seq)",
+			)
+		}
+		statements = append(statements,
+			// Задача, которую переназначили или перенесли в чужой проект, пропадает из выгрузки
+			// прежних владельцев, а проект — у автора, унёсшего из него последнюю задачу.
+			// Для них это удаление, иначе у клиента останется устаревшая копия
+			`CREATE OR REPLACE FUNCTION sync_visibility_lost() RETURNS trigger AS $$
+            DECLARE
+                old_owner uuid;
+                new_owner uuid;
+                lost uuid[];
+            BEGIN
+                SELECT user_id INTO old_owner FROM projects WHERE id = OLD.project_id;
+                IF TG_OP = 'UPDATE' THEN
+                    SELECT user_id INTO new_owner FROM projects WHERE id = NEW.project_id;
+                    lost := ARRAY(
+                        SELECT DISTINCT u FROM unnest(ARRAY[OLD.user_id, old_owner]) u
+                        WHERE u IS NOT NULL AND u IS DISTINCT FROM NEW.user_id AND u IS DISTINCT FROM new_owner
+                    );
+                    IF cardinality(lost) > 0 THEN
+                        INSERT INTO sync_tombstones (resource, resource_id, visible_to) VALUES ('task', OLD.id, lost);
+                    END IF;
+                END IF;
+                -- Надгробия удалённых задач пишет tombstoneTasks, здесь — только потеря проекта
+                IF old_owner IS NOT NULL AND OLD.user_id <> old_owner
+                    AND NOT EXISTS (SELECT 1 FROM tasks WHERE project_id = OLD.project_id AND user_id = OLD.user_id) THEN
+                    INSERT INTO sync_tombstones (resource, resource_id, visible_to) VALUES ('project', OLD.project_id, ARRAY[OLD.user_id]);
+                END IF;
+                RETURN NULL;
+            END $$ LANGUAGE plpgsql`,
+			"DROP TRIGGER IF EXISTS tasks_sync_visibility ON tasks",
+			`CREATE TRIGGER tasks_sync_visibility AFTER UPDATE ON tasks FOR EACH ROW
+                WHEN (OLD.user_id IS DISTINCT FROM NEW.user_id OR OLD.project_id IS DISTINCT FROM NEW.project_id)
+                EXECUTE FUNCTION sync_visibility_lost()`,
+			"DROP TRIGGER IF EXISTS tasks_sync_visibility_delete ON tasks",
+			"CREATE TRIGGER tasks_sync_visibility_delete AFTER DELETE ON tasks FOR EACH ROW EXECUTE FUNCTION sync_visibility_lost()",
+		)
+		for _, statement := range statements {
+			if err := tx.Exec(statement).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,212 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"task-tracker/internal/models"
+)
+
+var ErrNeighborNotInColumn = errors.New("neighbor task is not in the target column")
+
+// RankRebalanceGap — зазор, при котором колонку раздвигают в фоне: после ~20 делений шага пополам
+const RankRebalanceGap = models.RankStep / (1 << 20)
+
+// Зазор, меньше которого середина между соседями уже не различима: колонку пора перебалансировать
+const minRankGap = 1e-9
+
+// columnExpr совпадает с Task.ColumnID: колонка — проект задачи или её владелец (для задач без проекта)
+const columnExpr = "COALESCE(tasks.project_id, tasks.user_id)"
+
+func inColumn(columnID uuid.UUID, status models.TaskStatus) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("tasks.status = ? AND "+columnExpr+" = ?", status, columnID)
+	}
+}
+
+// PlaceRank выбирает ранг задачи между соседями в её колонке: сразу после afterID или
+// сразу перед beforeID; без соседей — в конец. Если места между соседями не осталось,
+// колонка перебалансируется в той же транзакции
+func (r *taskRepo) PlaceRank(task *models.Task, afterID, beforeID *uuid.UUID) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		rank, err := placeRank(tx, task, afterID, beforeID)
+		if errors.Is(err, errNoRankGap) {
+			if err := rebalanceColumn(tx, task.ColumnID(), task.Status); err != nil {
+				return err
+			}
+			rank, err = placeRank(tx, task, afterID, beforeID)
+		}
+		if err != nil {
+			return err
+		}
+		task.Rank = rank
+		return nil
+	})
+}
+
+var errNoRankGap = errors.New("no room between neighbor ranks")
+
+func placeRank(tx *gorm.DB, task *models.Task, afterID, beforeID *uuid.UUID) (float64, error) {
+	column := tx.Model(&models.Task{}).
+		Scopes(inColumn(task.ColumnID(), task.Status)).
+		Where("tasks.id <> ?", task.ID)
+
+	neighborRank := func(id uuid.UUID) (float64, error) {
+		var ranks []float64
+		if err := column.Session(&gorm.Session{}).Where("tasks.id = ?", id).
+			Clauses(lockForUpdate).Pluck("tasks.rank", &ranks).Error; err != nil {
+			return 0, err
+		}
+		if len(ranks) == 0 {
+			return 0, ErrNeighborNotInColumn
+		}
+		return ranks[0], nil
+	}
+
+	// prev и next — ранги соседей сверху и снизу; Valid=false — соседа нет
+	var prev, next sql.NullFloat64
+	switch {
+	case afterID != nil:
+		rank, err := neighborRank(*afterID)
+		if err != nil {
+			return 0, err
+		}
+		prev = sql.NullFloat64{Float64: rank, Valid: true}
+		if err := column.Session(&gorm.Session{}).Where("tasks.rank > ?", rank).
+			Select("MIN(tasks.rank)").Scan(&next).Error; err != nil {
+			return 0, err
+		}
+	case beforeID != nil:
+		rank, err := neighborRank(*beforeID)
+		if err != nil {
+			return 0, err
+		}
+		next = sql.NullFloat64{Float64: rank, Valid: true}
+		if err := column.Session(&gorm.Session{}).Where("tasks.rank < ?", rank).
+			Select("MAX(tasks.rank)").Scan(&prev).Error; err != nil {
+			return 0, err
+		}
+	default:
+		if err := column.Session(&gorm.Session{}).Select("MAX(tasks.rank)").Scan(&prev).Error; err != nil {
+			return 0, err
+		}
+	}
+
+	if !next.Valid {
+		return prev.Float64 + models.RankStep, nil
+	}
+	// Без верхнего соседа берём середину между нулём и next: ранги остаются
+	// положительными, ноль означает «ранг не выдан»
+	if next.Float64-prev.Float64 < minRankGap {
+		return 0, errNoRankGap
+	}
+	return (prev.Float64 + next.Float64) / 2, nil
+}
+
+// rebalanceColumn раскладывает ранги колонки заново с шагом RankStep, сохраняя порядок;
+// задачи без ранга уходят в конец
+func rebalanceColumn(tx *gorm.DB, columnID uuid.UUID, status models.TaskStatus) error {
+	return tx.Exec(`UPDATE tasks SET rank = ordered.n * ?
+        FROM (
+            SELECT id, row_number() OVER (ORDER BY rank = 0, rank, created_at, id) as n
+            FROM tasks WHERE status = ? AND COALESCE(project_id, user_id) = ?
+        ) ordered
+        WHERE tasks.id = ordered.id`, models.RankStep, status, columnID).Error
+}
+
+// RebalanceRanks перебалансирует колонки, где соседние ранги сблизились меньше чем на minGap
+// или остались задачи без ранга. Возвращает число обработанных колонок
+func (r *taskRepo) RebalanceRanks(minGap float64) (int, error) {
+	var columns []struct {
+		ColumnID uuid.UUID
+		Status   models.TaskStatus
+	}
+	err := r.db.Raw(`SELECT DISTINCT column_id, status FROM (
+            SELECT `+columnExpr+` as column_id, tasks.status, tasks.rank,
+                tasks.rank - lag(tasks.rank) OVER (
+                    PARTITION BY tasks.status, `+columnExpr+` ORDER BY tasks.rank
+                ) as gap
+            FROM tasks
+        ) ranks
+        WHERE rank = 0 OR gap < ?`, minGap).Scan(&columns).Error
+	if err != nil {
+		return 0, err
+	}
+
+	for _, c := range columns {
+		err := r.db.Transaction(func(tx *gorm.DB) error {
+			return rebalanceColumn(tx, c.ColumnID, c.Status)
+		})
+		if err != nil {
+			return 0, err
+		}
+	}
+	return len(columns), nil
+}
+
+// MigrateTaskRanks создаёт индекс для сортировки колонок и выдаёт ранги задачам,
+// созданным до их появления (в порядке создания)
+func MigrateTaskRanks(db *gorm.DB) error {
+	err := db.Exec("CREATE INDEX IF NOT EXISTS idx_tasks_column_rank ON tasks (status, (" + columnExpr + "), rank)").Error
+	if err != nil {
+		return err
+	}
+	_, err = (&taskRepo{db: db}).RebalanceRanks(0)
+	return err
+}
+
+// CountInColumn считает все задачи колонки, кроме excludeID, — для проверки WIP-лимита
+func (r *taskRepo) CountInColumn(columnID uuid.UUID, status models.TaskStatus, excludeID uuid.UUID) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.Task{}).
+		Scopes(inColumn(columnID, status)).
+		Where("tasks.id <> ?", excludeID).
+		Count(&count).Error
+	return count, err
+}
+
+func (r *taskRepo) LockProject(id uuid.UUID) (*models.Project, error) {
+	var project models.Project
+	err := r.db.Clauses(lockForUpdate).First(&project, "id = ?", id).Error
+	return &project, err
+}
+
+// BoardTasks возвращает видимые пользователю задачи проекта, не больше perColumn
+// в каждой колонке, в порядке ранга
+func (r *taskRepo) BoardTasks(projectID, userID uuid.UUID, perColumn int) ([]models.Task, error) {
+	ranked := r.db.Model(&models.Task{}).
+		Select("tasks.id, row_number() OVER (PARTITION BY tasks.status ORDER BY tasks.rank, tasks.id) as n").
+		Scopes(visibleTasks(userID)).
+		Where("tasks.project_id = ?", projectID)
+	top := r.db.Table("(?) as ranked", ranked).Select("id").Where("n <= ?", perColumn)
+
+	var tasks []models.Task
+	err := r.withAggregates(r.db).
+		Where("tasks.id IN (?)", top).
+		Order("tasks.rank ASC, tasks.id ASC").
+		Find(&tasks).Error
+	return tasks, err
+}
+
+func (r *taskRepo) BoardCounts(projectID, userID uuid.UUID) (map[models.TaskStatus]int64, error) {
+	var rows []struct {
+		Status models.TaskStatus
+		Count  int64
+	}
+	err := r.db.Model(&models.Task{}).
+		Select("tasks.status, COUNT(*) as count").
+		Scopes(visibleTasks(userID)).
+		Where("tasks.project_id = ?", projectID).
+		Group("tasks.status").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[models.TaskStatus]int64, len(rows))
+	for _, row := range rows {
+		counts[row.Status] = row.Count
+	}
+	return counts, nil
+}
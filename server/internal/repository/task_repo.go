@@ -1,18 +1,121 @@
 package repository
 
 import (
+	"errors"
+	"fmt"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"sort"
+	"strings"
 	"task-tracker/internal/dto"
 	"task-tracker/internal/models"
+	"task-tracker/pkg/events"
+	"time"
 )
 
 type TaskRepository interface {
 	Create(task *models.Task) error
 	FindByID(id uuid.UUID) (*models.Task, error)
 	Update(task *models.Task) error
-	Delete(id uuid.UUID) error
-	List(filter dto.TaskFilter, limit, offset int) ([]models.Task, error)
+	// Delete удаляет задачу; с version — только если она не менялась, иначе ErrVersionConflict
+	Delete(id uuid.UUID, version *int) error
+	List(filter dto.TaskFilter, page dto.PageRequest) (*dto.ListResponse[models.Task], error)
+	CompleteRecurring(task *models.Task, next *models.Task) error
+	FindIDByKey(projectKey string, number int) (uuid.UUID, error)
+	AddKeyAlias(taskID uuid.UUID, key string) error
+	PlaceRank(task *models.Task, afterID, beforeID *uuid.UUID) error
+	RebalanceRanks(minGap float64) (int, error)
+	CountInColumn(columnID uuid.UUID, status models.TaskStatus, excludeID uuid.UUID) (int64, error)
+	// LockProject блокирует строку проекта до конца транзакции, чтобы проверки WIP-лимита шли по очереди
+	LockProject(id uuid.UUID) (*models.Project, error)
+	BoardTasks(projectID, userID uuid.UUID, perColumn int) ([]models.Task, error)
+	BoardCounts(projectID, userID uuid.UUID) (map[models.TaskStatus]int64, error)
+	ListIDs(filter dto.TaskFilter, limit int) ([]uuid.UUID, error)
+	Transaction(fn func(repo TaskRepository) error) error
+	Exists(id uuid.UUID) (bool, error)
+	// AddEvents пишет события в outbox; вызывается в транзакции изменения
+	AddEvents(evts []events.Event) error
+}
+
+var ErrUnknownSortField = errors.New("unknown sort field")
+
+// Поля, по которым можно сортировать задачи
+var taskSortFields = map[string]sortKey[models.Task]{
+	"id": {
+		name: "id", expr: "tasks.id", kind: keyUUID,
+		value: func(t *models.Task) any { return t.ID },
+	},
+	"created_at": {
+		name: "created_at", expr: "tasks.created_at", kind: keyTime,
+		value: func(t *models.Task) any { return t.CreatedAt },
+	},
+	"estimate": {
+		name: "estimate", expr: "tasks.estimate", kind: keyFloat, nullable: true,
+		value: func(t *models.Task) any { return t.Estimate },
+	},
+	"remaining_estimate": {
+		name: "remaining_estimate", expr: "tasks.remaining_estimate", kind: keyFloat, nullable: true,
+		value: func(t *models.Task) any { return t.RemainingEstimate },
+	},
+	"project_id": {
+		name: "project_id", expr: "tasks.project_id", kind: keyUUID, nullable: true,
+		value: func(t *models.Task) any { return t.ProjectID },
+	},
+	"updated_at": {
+		name: "updated_at", expr: "tasks.updated_at", kind: keyTime,
+		value: func(t *models.Task) any { return t.UpdatedAt },
+	},
+	"due_date": {
+		name: "due_date", expr: "tasks.due_date", kind: keyTime, nullable: true,
+		value: func(t *models.Task) any { return t.DueDate },
+	},
+	"rank": {
+		name: "rank", expr: "tasks.rank", kind: keyFloat,
+		value: func(t *models.Task) any { return t.Rank },
+	},
+	"title": {
+		name: "title", expr: "tasks.title", kind: keyText,
+		value: func(t *models.Task) any { return t.Title },
+	},
+	// Приоритет и статус сортируются по смыслу, а не по алфавиту
+	"priority": {
+		name: "priority", expr: rankExpr("tasks.priority", priorityRank), kind: keyInt,
+		value: func(t *models.Task) any { return priorityRank[string(t.Priority)] },
+	},
+	"status": {
+		name: "status", expr: rankExpr("tasks.status", statusRank), kind: keyInt,
+		value: func(t *models.Task) any { return statusRank[string(t.Status)] },
+	},
+}
+
+var priorityRank = map[string]int{
+	string(models.PriorityLow):    1,
+	string(models.PriorityMedium): 2,
+	string(models.PriorityHigh):   3,
+}
+
+var statusRank = map[string]int{
+	string(models.StatusTodo):       1,
+	string(models.StatusInProgress): 2,
+	string(models.StatusDone):       3,
+}
+
+// rankExpr строит CASE, переводящий значение колонки в ранг; неизвестные значения получают 0
+func rankExpr(column string, ranks map[string]int) string {
+	values := make([]string, 0, len(ranks))
+	for value := range ranks {
+		values = append(values, value)
+	}
+	sort.Strings(values)
+
+	var b strings.Builder
+	b.WriteString("CASE " + column)
+	for _, value := range values {
+		fmt.Fprintf(&b, " WHEN '%s' THEN %d", value, ranks[value])
+	}
+	b.WriteString(" ELSE 0 END")
+	return b.String()
 }
 
 type taskRepo struct {
@@ -29,47 +132,261 @@ func (r *taskRepo) Create(task *models.Task) error {
 
 func (r *taskRepo) FindByID(id uuid.UUID) (*models.Task, error) {
 	var task models.Task
-	err := r.db.Preload("Project").First(&task, "id = ?", id).Error
+	err := r.withAggregates(r.db).
+		Preload("Project").
+		Preload("Checklist", func(db *gorm.DB) *gorm.DB {
+			return db.Order("position ASC")
+		}).
+		First(&task, "tasks.id = ?", id).Error
 	return &task, err
 }
 
 func (r *taskRepo) Update(task *models.Task) error {
-	return r.db.Save(task).Error
+	return saveVersioned(r.db, task, &task.Version)
 }
 
-func (r *taskRepo) Delete(id uuid.UUID) error {
-	return r.db.Delete(&models.Task{}, "id = ?", id).Error
+func (r *taskRepo) Delete(id uuid.UUID, version *int) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tombstoneTasks(tx, "id = ?", id); err != nil {
+			return err
+		}
+		return deleteVersioned(tx, &models.Task{}, id, version)
+	})
 }
 
-func (r *taskRepo) List(filter dto.TaskFilter, limit, offset int) ([]models.Task, error) {
+func (r *taskRepo) List(filter dto.TaskFilter, page dto.PageRequest) (*dto.ListResponse[models.Task], error) {
+	keys, err := taskSortKeys(filter.Sort)
+	if err != nil {
+		return nil, err
+	}
+
+	var total int64
+	if err := r.applyFilter(r.db.Model(&models.Task{}), filter).Count(&total).Error; err != nil {
+		return nil, err
+	}
+
+	query, err := paginate(r.applyFilter(r.withAggregates(r.db), filter).Preload("Project"), keys, page)
+	if err != nil {
+		return nil, err
+	}
+
 	var tasks []models.Task
-	query := r.db.Preload("Project")
+	if err := query.Find(&tasks).Error; err != nil {
+		return nil, err
+	}
+	return buildPage(tasks, total, keys, page), nil
+}
 
+func (r *taskRepo) applyFilter(query *gorm.DB, filter dto.TaskFilter) *gorm.DB {
+	if filter.ViewerID != nil {
+		query = query.Scopes(visibleTasks(*filter.ViewerID))
+	}
 	if filter.UserID != nil {
-		query = query.Where("user_id = ?", *filter.UserID)
+		query = query.Where("tasks.user_id = ?", *filter.UserID)
 	}
 	if filter.ProjectID != nil {
-		query = query.Where("project_id = ?", *filter.ProjectID)
+		query = query.Where("tasks.project_id = ?", *filter.ProjectID)
+	}
+	if len(filter.ProjectIDs) > 0 {
+		query = query.Where("tasks.project_id IN ?", filter.ProjectIDs)
+	}
+	if filter.NoProject {
+		query = query.Where("tasks.project_id IS NULL")
+	}
+	if filter.ParentID != nil {
+		query = query.Where("tasks.parent_id = ?", *filter.ParentID)
 	}
-	if filter.Status != "" {
-		query = query.Where("status = ?", filter.Status)
+	if len(filter.Statuses) > 0 {
+		query = query.Where("tasks.status IN ?", filter.Statuses)
 	}
-	if filter.Priority != "" {
-		query = query.Where("priority = ?", filter.Priority)
+	if len(filter.Priorities) > 0 {
+		query = query.Where("tasks.priority IN ?", filter.Priorities)
 	}
 	if filter.Search != "" {
-		query = query.Where(
-			"title ILIKE ? OR description ILIKE ?",
-			"%"+filter.Search+"%",
-			"%"+filter.Search+"%",
-		)
+		query = query.Where(searchMatch("tasks", "title", "description"), searchArgs(filter.Search)...)
 	}
+	if filter.EstimateMin != nil {
+		query = query.Where("tasks.estimate >= ?", *filter.EstimateMin)
+	}
+	if filter.EstimateMax != nil {
+		query = query.Where("tasks.estimate <= ?", *filter.EstimateMax)
+	}
+	if filter.HasEstimate != nil {
+		if *filter.HasEstimate {
+			query = query.Where("tasks.estimate IS NOT NULL")
+		} else {
+			query = query.Where("tasks.estimate IS NULL")
+		}
+	}
+	query = whereRange(query, "tasks.due_date", filter.DueFrom, filter.DueTo)
+	query = whereRange(query, "tasks.created_at", filter.CreatedFrom, filter.CreatedTo)
+	query = whereRange(query, "tasks.updated_at", filter.UpdatedFrom, filter.UpdatedTo)
+	if filter.HasDueDate != nil {
+		if *filter.HasDueDate {
+			query = query.Where("tasks.due_date IS NOT NULL")
+		} else {
+			query = query.Where("tasks.due_date IS NULL")
+		}
+	}
+	if filter.Overdue != nil {
+		overdue := "tasks.status <> ? AND tasks.due_date IS NOT NULL AND tasks.due_date < ?"
+		if *filter.Overdue {
+			query = query.Where(overdue, models.StatusDone, filter.Today)
+		} else {
+			query = query.Not(overdue, models.StatusDone, filter.Today)
+		}
+	}
+	return query
+}
 
-	err := query.
-		Order("created_at DESC").
+// whereRange ограничивает колонку полуинтервалом [from, to)
+func whereRange(query *gorm.DB, column string, from, to *time.Time) *gorm.DB {
+	if from != nil {
+		query = query.Where(column+" >= ?", *from)
+	}
+	if to != nil {
+		query = query.Where(column+" < ?", *to)
+	}
+	return query
+}
+
+// ValidateTaskSort проверяет, что все поля сортировки известны
+func ValidateTaskSort(sort []dto.SortField) error {
+	_, err := taskSortKeys(sort)
+	return err
+}
+
+// taskSortKeys переводит запрошенную сортировку в ключи; id замыкает порядок,
+// чтобы он был однозначным и курсор не пропускал и не повторял строки
+func taskSortKeys(sort []dto.SortField) ([]sortKey[models.Task], error) {
+	if len(sort) == 0 {
+		sort = []dto.SortField{{Field: "created_at", Desc: true}}
+	}
+
+	keys := make([]sortKey[models.Task], 0, len(sort)+1)
+	last := false
+	for _, f := range sort {
+		key, ok := taskSortFields[f.Field]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownSortField, f.Field)
+		}
+		key.desc = f.Desc
+		keys = append(keys, key)
+		last = f.Desc
+	}
+
+	id := taskSortFields["id"]
+	id.desc = last
+	return append(keys, id), nil
+}
+
+// CompleteRecurring сохраняет выполненную задачу и создаёт следующее вхождение серии
+// вместе с копией чек-листа (все пункты снова не отмечены)
+func (r *taskRepo) CompleteRecurring(task *models.Task, next *models.Task) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := saveVersioned(tx, task, &task.Version); err != nil {
+			return err
+		}
+		if err := tx.Omit(clause.Associations).Create(next).Error; err != nil {
+			return err
+		}
+
+		var items []models.ChecklistItem
+		if err := tx.Where("task_id = ?", task.ID).Order("position ASC").Find(&items).Error; err != nil {
+			return err
+		}
+		if len(items) == 0 {
+			return nil
+		}
+
+		copies := make([]models.ChecklistItem, len(items))
+		for i, item := range items {
+			copies[i] = models.ChecklistItem{
+				TaskID:   next.ID,
+				Title:    item.Title,
+				Position: item.Position,
+			}
+		}
+		return tx.Create(&copies).Error
+	})
+}
+
+// ListIDs возвращает идентификаторы задач по фильтру, не больше limit
+func (r *taskRepo) ListIDs(filter dto.TaskFilter, limit int) ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+	err := r.applyFilter(r.db.Model(&models.Task{}), filter).
+		Order("tasks.created_at ASC, tasks.id ASC").
 		Limit(limit).
-		Offset(offset).
-		Find(&tasks).Error
+		Pluck("tasks.id", &ids).Error
+	return ids, err
+}
+
+// Transaction выполняет fn с репозиторием, работающим внутри транзакции.
+// Вложенный вызов на таком репозитории открывает точку сохранения
+func (r *taskRepo) Transaction(fn func(repo TaskRepository) error) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return fn(&taskRepo{db: tx})
+	})
+}
+
+func (r *taskRepo) AddEvents(evts []events.Event) error {
+	return writeOutbox(r.db, evts)
+}
+
+// Exists проверяет, занят ли идентификатор, без учёта видимости задачи
+func (r *taskRepo) Exists(id uuid.UUID) (bool, error) {
+	var count int64
+	err := r.db.Model(&models.Task{}).Where("id = ?", id).Count(&count).Error
+	return count > 0, err
+}
+
+// FindIDByKey находит задачу по ключу вида API-42: сначала среди текущих ключей, затем среди
+// прежних, оставшихся после переноса задачи в другой проект
+func (r *taskRepo) FindIDByKey(projectKey string, number int) (uuid.UUID, error) {
+	var ids []uuid.UUID
+	err := r.db.Model(&models.Task{}).
+		Joins("JOIN projects ON projects.id = tasks.project_id").
+		Where("projects.key = ? AND tasks.number = ?", projectKey, number).
+		Pluck("tasks.id", &ids).Error
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if len(ids) > 0 {
+		return ids[0], nil
+	}
+
+	var alias models.TaskKeyAlias
+	if err := r.db.First(&alias, "key = ?", models.TaskKey(projectKey, number)).Error; err != nil {
+		return uuid.Nil, err
+	}
+	return alias.TaskID, nil
+}
+
+func (r *taskRepo) AddKeyAlias(taskID uuid.UUID, key string) error {
+	return r.db.Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&models.TaskKeyAlias{Key: key, TaskID: taskID}).Error
+}
 
-	return tasks, err
+// withAggregates добавляет к выборке задач вычисляемые поля (прогресс чек-листа, учтённое время)
+func (r *taskRepo) withAggregates(db *gorm.DB) *gorm.DB {
+	return db.Model(&models.Task{}).
+		Select(`
+        tasks.*,
+        cl.total as checklist_total,
+        cl.done as checklist_done,
+        COALESCE(cl.done::float8 / NULLIF(cl.total, 0), 0) as checklist_ratio,
+        te.seconds as tracked_seconds,
+        COALESCE(task_project.key || '-' || tasks.number, '') as key
+    `).
+		Joins("LEFT JOIN projects task_project ON task_project.id = tasks.project_id").
+		Joins(`LEFT JOIN LATERAL (
+            SELECT COUNT(*) as total, COUNT(*) FILTER (WHERE done) as done
+            FROM checklist_items
+            WHERE checklist_items.task_id = tasks.id
+        ) cl ON true`).
+		Joins(`LEFT JOIN LATERAL (
+            SELECT COALESCE(SUM(EXTRACT(EPOCH FROM (COALESCE(ended_at, now()) - started_at))), 0)::bigint as seconds
+            FROM time_entries
+            WHERE time_entries.task_id = tasks.id
+        ) te ON true`)
 }
@@ -0,0 +1,147 @@
+package repository
+
+import (
+	"errors"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"strings"
+	"task-tracker/internal/dto"
+	"task-tracker/internal/models"
+)
+
+var ErrTimerAlreadyRunning = errors.New("another timer is already running")
+
+type TimeEntryRepository interface {
+	Create(entry *models.TimeEntry) error
+	FindByID(id uuid.UUID) (*models.TimeEntry, error)
+	Update(entry *models.TimeEntry) error
+	Delete(id uuid.UUID) error
+	ListByTask(taskID uuid.UUID) ([]models.TimeEntry, error)
+	FindRunning(userID uuid.UUID) (*models.TimeEntry, error)
+	// StartTimer останавливает запущенный таймер пользователя (если есть) и запускает новый
+	StartTimer(entry *models.TimeEntry) (*models.TimeEntry, error)
+	Report(filter dto.TimeReportFilter) ([]dto.TimeReportRow, error)
+}
+
+type timeEntryRepo struct {
+	db *gorm.DB
+}
+
+func NewTimeEntryRepository(db *gorm.DB) TimeEntryRepository {
+	return &timeEntryRepo{db: db}
+}
+
+func (r *timeEntryRepo) Create(entry *models.TimeEntry) error {
+	return r.db.Create(entry).Error
+}
+
+func (r *timeEntryRepo) FindByID(id uuid.UUID) (*models.TimeEntry, error) {
+	var entry models.TimeEntry
+	err := r.db.First(&entry, "id = ?", id).Error
+	return &entry, err
+}
+
+func (r *timeEntryRepo) Update(entry *models.TimeEntry) error {
+	return r.db.Save(entry).Error
+}
+
+func (r *timeEntryRepo) Delete(id uuid.UUID) error {
+	return r.db.Delete(&models.TimeEntry{}, "id = ?", id).Error
+}
+
+func (r *timeEntryRepo) ListByTask(taskID uuid.UUID) ([]models.TimeEntry, error) {
+	var entries []models.TimeEntry
+	err := r.db.Where("task_id = ?", taskID).
+		Order("started_at DESC").
+		Find(&entries).Error
+	return entries, err
+}
+
+func (r *timeEntryRepo) FindRunning(userID uuid.UUID) (*models.TimeEntry, error) {
+	var entry models.TimeEntry
+	err := r.db.First(&entry, "user_id = ? AND ended_at IS NULL", userID).Error
+	return &entry, err
+}
+
+func (r *timeEntryRepo) StartTimer(entry *models.TimeEntry) (*models.TimeEntry, error) {
+	var stopped *models.TimeEntry
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var running models.TimeEntry
+		err := tx.Clauses(lockForUpdate).
+			First(&running, "user_id = ? AND ended_at IS NULL", entry.UserID).Error
+		switch {
+		case err == nil:
+			running.EndedAt = &entry.StartedAt
+			if err := tx.Save(&running).Error; err != nil {
+				return err
+			}
+			stopped = &running
+		case !errors.Is(err, gorm.ErrRecordNotFound):
+			return err
+		}
+
+		return tx.Create(entry).Error
+	})
+	if isUniqueViolation(err) {
+		return nil, ErrTimerAlreadyRunning
+	}
+	return stopped, err
+}
+
+func (r *timeEntryRepo) Report(filter dto.TimeReportFilter) ([]dto.TimeReportRow, error) {
+	var rows []dto.TimeReportRow
+
+	var columns, groups []string
+	var args []interface{}
+	if filter.GroupByProject {
+		columns = append(columns, "projects.id as project_id", "projects.name as project_name")
+		groups = append(groups, "projects.id", "projects.name")
+	}
+	if filter.GroupByUser {
+		columns = append(columns, "users.id as user_id", "users.first_name || ' ' || users.last_name as user_name")
+		groups = append(groups, "users.id", "users.first_name", "users.last_name")
+	}
+	if filter.GroupByDay {
+		columns = append(columns, "to_char(time_entries.started_at AT TIME ZONE ?, 'YYYY-MM-DD') as day")
+		args = append(args, filter.Timezone)
+		groups = append(groups, "day")
+	}
+	columns = append(columns,
+		"SUM(EXTRACT(EPOCH FROM (COALESCE(time_entries.ended_at, now()) - time_entries.started_at)))::bigint as seconds",
+		"COUNT(*) as entries",
+	)
+
+	query := r.db.Model(&models.TimeEntry{}).
+		Select(strings.Join(columns, ", "), args...).
+		Joins("JOIN tasks ON tasks.id = time_entries.task_id").
+		Joins("LEFT JOIN projects ON projects.id = tasks.project_id").
+		Joins("JOIN users ON users.id = time_entries.user_id").
+		Where(
+			"(time_entries.user_id = ? OR tasks.user_id = ? OR projects.user_id = ?)",
+			filter.ViewerID, filter.ViewerID, filter.ViewerID,
+		).
+		Where("time_entries.started_at >= ? AND time_entries.started_at < ?", filter.From, filter.To)
+
+	if filter.ProjectID != nil {
+		query = query.Where("tasks.project_id = ?", *filter.ProjectID)
+	}
+	if filter.UserID != nil {
+		query = query.Where("time_entries.user_id = ?", *filter.UserID)
+	}
+	if len(groups) > 0 {
+		query = query.Group(strings.Join(groups, ", "))
+	}
+	if filter.GroupByDay {
+		query = query.Order("day")
+	}
+	if filter.GroupByProject {
+		query = query.Order("projects.name")
+	}
+	if filter.GroupByUser {
+		query = query.Order("users.first_name, users.last_name")
+	}
+
+	err := query.Scan(&rows).Error
+	return rows, err
+}
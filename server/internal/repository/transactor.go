@@ -0,0 +1,22 @@
+package repository
+
+import "gorm.io/gorm"
+
+// Transactor выполняет работу с задачами и проектами в одной транзакции
+type Transactor interface {
+	Transaction(fn func(tasks TaskRepository, projects ProjectRepository) error) error
+}
+
+type transactor struct {
+	db *gorm.DB
+}
+
+func NewTransactor(db *gorm.DB) Transactor {
+	return &transactor{db: db}
+}
+
+func (t *transactor) Transaction(fn func(tasks TaskRepository, projects ProjectRepository) error) error {
+	return t.db.Transaction(func(tx *gorm.DB) error {
+		return fn(&taskRepo{db: tx}, &projectRepo{db: tx})
+	})
+}
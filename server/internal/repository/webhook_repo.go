@@ -0,0 +1,172 @@
+package repository
+
+import (
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"task-tracker/internal/models"
+	"time"
+)
+
+type WebhookRepository interface {
+	Create(webhook *models.Webhook) error
+	FindByID(id uuid.UUID) (*models.Webhook, error)
+	Update(webhook *models.Webhook) error
+	Delete(id uuid.UUID) error
+	ListByProject(projectID uuid.UUID) ([]models.Webhook, error)
+	ListActive(projectID uuid.UUID) ([]models.Webhook, error)
+
+	CreateDeliveries(deliveries []models.WebhookDelivery) error
+	FindDelivery(id uuid.UUID) (*models.WebhookDelivery, error)
+	ListDeliveries(webhookID uuid.UUID, status models.DeliveryStatus, limit int) ([]models.WebhookDelivery, error)
+	// ClaimDue забирает до limit доставок, которым пора уйти, вместе с вебхуками
+	ClaimDue(limit int, lease time.Duration) ([]models.WebhookDelivery, error)
+	SaveDelivery(delivery *models.WebhookDelivery) error
+	// RecordSuccess сбрасывает счётчик неудач; RecordFailure увеличивает его, отключает вебхук
+	// на пороге threshold и возвращает новое значение
+	RecordSuccess(webhookID uuid.UUID) error
+	RecordFailure(webhookID uuid.UUID, threshold int, reason string) (int, error)
+	PurgeDeliveries(before time.Time) (int64, error)
+}
+
+type webhookRepo struct {
+	db *gorm.DB
+}
+
+func NewWebhookRepository(db *gorm.DB) WebhookRepository {
+	return &webhookRepo{db: db}
+}
+
+func (r *webhookRepo) Create(webhook *models.Webhook) error {
+	return r.db.Create(webhook).Error
+}
+
+func (r *webhookRepo) FindByID(id uuid.UUID) (*models.Webhook, error) {
+	var webhook models.Webhook
+	err := r.db.First(&webhook, "id = ?", id).Error
+	return &webhook, err
+}
+
+func (r *webhookRepo) Update(webhook *models.Webhook) error {
+	return r.db.Omit(clause.Associations).Save(webhook).Error
+}
+
+func (r *webhookRepo) Delete(id uuid.UUID) error {
+	return r.db.Delete(&models.Webhook{}, "id = ?", id).Error
+}
+
+func (r *webhookRepo) ListByProject(projectID uuid.UUID) ([]models.Webhook, error) {
+	webhooks := []models.Webhook{}
+	err := r.db.Where("project_id = ?", projectID).Order("created_at ASC").Find(&webhooks).Error
+	return webhooks, err
+}
+
+func (r *webhookRepo) ListActive(projectID uuid.UUID) ([]models.Webhook, error) {
+	var webhooks []models.Webhook
+	err := r.db.Where("project_id = ? AND active", projectID).Find(&webhooks).Error
+	return webhooks, err
+}
+
+func (r *webhookRepo) CreateDeliveries(deliveries []models.WebhookDelivery) error {
+	if len(deliveries) == 0 {
+		return nil
+	}
+	return r.db.Omit(clause.Associations).Clauses(clause.OnConflict{DoNothing: true}).Create(&deliveries).Error
+}
+
+func (r *webhookRepo) FindDelivery(id uuid.UUID) (*models.WebhookDelivery, error) {
+	var delivery models.WebhookDelivery
+	err := r.db.First(&delivery, "id = ?", id).Error
+	return &delivery, err
+}
+
+// ListDeliveries возвращает журнал доставок вебхука, новые первыми
+func (r *webhookRepo) ListDeliveries(webhookID uuid.UUID, status models.DeliveryStatus, limit int) ([]models.WebhookDelivery, error) {
+	query := r.db.Where("webhook_id = ?", webhookID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	deliveries := []models.WebhookDelivery{}
+	err := query.Order("created_at DESC, id DESC").Limit(limit).Find(&deliveries).Error
+	return deliveries, err
+}
+
+// ClaimDue отодвигает срок следующей попытки выбранных доставок на lease: пока идёт отправка,
+// их не заберёт другой обработчик, а если процесс упадёт, доставки вернутся в очередь сами.
+// SKIP LOCKED позволяет нескольким репликам разбирать очередь, не мешая друг другу
+func (r *webhookRepo) ClaimDue(limit int, lease time.Duration) ([]models.WebhookDelivery, error) {
+	var ids []uuid.UUID
+	err := r.db.Raw(`UPDATE webhook_deliveries SET next_attempt_at = now() + make_interval(secs => @lease)
+        WHERE id IN (
+            SELECT webhook_deliveries.id FROM webhook_deliveries
+            JOIN webhooks ON webhooks.id = webhook_deliveries.webhook_id
+            WHERE webhook_deliveries.status = @pending AND webhook_deliveries.next_attempt_at <= now() AND webhooks.active
+            ORDER BY webhook_deliveries.next_attempt_at
+            LIMIT @limit
+            FOR UPDATE OF webhook_deliveries SKIP LOCKED
+        )
+        RETURNING id`,
+		map[string]any{"lease": lease.Seconds(), "pending": models.DeliveryPending, "limit": limit},
+	).Scan(&ids).Error
+	if err != nil || len(ids) == 0 {
+		return nil, err
+	}
+
+	var deliveries []models.WebhookDelivery
+	err = r.db.Preload("Webhook").Where("id IN ?", ids).Order("next_attempt_at ASC").Find(&deliveries).Error
+	return deliveries, err
+}
+
+func (r *webhookRepo) SaveDelivery(delivery *models.WebhookDelivery) error {
+	return r.db.Omit(clause.Associations).Save(delivery).Error
+}
+
+func (r *webhookRepo) RecordSuccess(webhookID uuid.UUID) error {
+	return r.db.Model(&models.Webhook{}).
+		Where("id = ? AND failure_count > 0", webhookID).
+		UpdateColumn("failure_count", 0).Error
+}
+
+func (r *webhookRepo) RecordFailure(webhookID uuid.UUID, threshold int, reason string) (int, error) {
+	var failures int
+	err := r.db.Raw(`UPDATE webhooks SET
+            failure_count = failure_count + 1,
+            active = active AND failure_count + 1 < @threshold,
+            disabled_at = CASE WHEN active AND failure_count + 1 >= @threshold THEN now() ELSE disabled_at END,
+            disabled_reason = CASE WHEN active AND failure_count + 1 >= @threshold THEN @reason ELSE disabled_reason END
+        WHERE id = @id
+        RETURNING failure_count`,
+		map[string]any{"id": webhookID, "threshold": threshold, "reason": reason},
+	).Scan(&failures).Error
+	return failures, err
+}
+
+// PurgeDeliveries удаляет завершённые доставки старше before и вебхуки удалённых проектов,
+// которым больше нечего доставлять. Событие project.deleted пишется в outbox вместе с удалением
+// проекта, а доставки по нему появляются раньше, чем событие помечается отправленным: пока
+// в outbox есть неотправленные события проекта, вебхук ждёт их, иначе подписчики не узнали бы
+// об удалении
+func (r *webhookRepo) PurgeDeliveries(before time.Time) (int64, error) {
+	var purged int64
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("status <> ? AND created_at < ?", models.DeliveryPending, before).
+			Delete(&models.WebhookDelivery{})
+		if result.Error != nil {
+			return result.Error
+		}
+		purged = result.RowsAffected
+
+		return tx.Exec(`DELETE FROM webhooks
+            WHERE NOT EXISTS (SELECT 1 FROM projects WHERE projects.id = webhooks.project_id)
+                AND (NOT webhooks.active OR (
+                    NOT EXISTS (
+                        SELECT 1 FROM outbox_events
+                        WHERE outbox_events.project_id = webhooks.project_id AND outbox_events.dispatched_at IS NULL
+                    ) AND NOT EXISTS (
+                        SELECT 1 FROM webhook_deliveries
+                        WHERE webhook_deliveries.webhook_id = webhooks.id AND webhook_deliveries.status = ?
+                    )
+                ))`, models.DeliveryPending).Error
+	})
+	return purged, err
+}
@@ -0,0 +1,263 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"github.com/google/uuid"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"task-tracker/internal/models"
+	"task-tracker/internal/repository"
+	"task-tracker/pkg/storage"
+	"unicode"
+)
+
+var (
+	ErrAttachmentNotFound   = errors.New("attachment not found")
+	ErrAttachmentTooLarge   = errors.New("attachment exceeds size limit")
+	ErrUnsupportedMediaType = errors.New("attachment type is not allowed")
+)
+
+type AttachmentConfig struct {
+	MaxSize      int64
+	AllowedTypes []string
+}
+
+var DefaultAttachmentConfig = AttachmentConfig{
+	MaxSize: 10 << 20,
+	AllowedTypes: []string{
+		"image/png",
+		"image/jpeg",
+		"image/gif",
+		"image/webp",
+		"application/pdf",
+		"text/plain",
+		"application/zip",
+		"application/x-gzip",
+	},
+}
+
+// BlobCollector удаляет содержимое вложений, на которое больше нет ссылок
+type BlobCollector interface {
+	DeleteOrphanBlobs(ctx context.Context) error
+}
+
+type AttachmentService interface {
+	BlobCollector
+
+	Upload(ctx context.Context, taskID, userID uuid.UUID, fileName string, r io.Reader) (*models.Attachment, error)
+	List(taskID uuid.UUID) ([]models.Attachment, error)
+	Open(ctx context.Context, taskID, id uuid.UUID) (*models.Attachment, io.ReadCloser, error)
+	Delete(ctx context.Context, taskID, id uuid.UUID) error
+	MaxSize() int64
+}
+
+type attachmentService struct {
+	repo     repository.AttachmentRepository
+	taskRepo repository.TaskRepository
+	store    storage.BlobStore
+	config   AttachmentConfig
+}
+
+func NewAttachmentService(repo repository.AttachmentRepository, taskRepo repository.TaskRepository, store storage.BlobStore, config AttachmentConfig) AttachmentService {
+	if config.MaxSize <= 0 {
+		config.MaxSize = DefaultAttachmentConfig.MaxSize
+	}
+	if len(config.AllowedTypes) == 0 {
+		config.AllowedTypes = DefaultAttachmentConfig.AllowedTypes
+	}
+	return &attachmentService{
+		repo:     repo,
+		taskRepo: taskRepo,
+		store:    store,
+		config:   config,
+	}
+}
+
+func (s *attachmentService) MaxSize() int64 {
+	return s.config.MaxSize
+}
+
+func (s *attachmentService) Upload(ctx context.Context, taskID, userID uuid.UUID, fileName string, r io.Reader) (*models.Attachment, error) {
+	if _, err := s.taskRepo.FindByID(taskID); err != nil {
+		return nil, err
+	}
+
+	// Сначала сохраняем файл во временный, попутно считая хеш и проверяя размер
+	tmp, err := os.CreateTemp("", "attachment-*")
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		tmp.Close()
+		os.Remove(tmp.Name())
+	}()
+
+	hasher := sha256.New()
+	size, err := io.Copy(io.MultiWriter(tmp, hasher), io.LimitReader(r, s.config.MaxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if size > s.config.MaxSize {
+		return nil, ErrAttachmentTooLarge
+	}
+
+	contentType, err := sniffContentType(tmp)
+	if err != nil {
+		return nil, err
+	}
+	if !s.allowed(contentType) {
+		return nil, ErrUnsupportedMediaType
+	}
+
+	hash := hex.EncodeToString(hasher.Sum(nil))
+	blob := &models.Blob{
+		Hash:        hash,
+		Size:        size,
+		ContentType: contentType,
+	}
+	attachment := &models.Attachment{
+		TaskID:      taskID,
+		UserID:      userID,
+		FileName:    sanitizeFileName(fileName),
+		ContentType: contentType,
+		Size:        size,
+		Hash:        hash,
+	}
+
+	err = s.repo.Create(attachment, blob, func(isNew bool) error {
+		if !isNew {
+			exists, err := s.store.Exists(ctx, blob.StorageKey())
+			if err != nil || exists {
+				return err
+			}
+		}
+		if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		return s.store.Put(ctx, blob.StorageKey(), tmp, size, contentType)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return attachment, nil
+}
+
+func (s *attachmentService) List(taskID uuid.UUID) ([]models.Attachment, error) {
+	if _, err := s.taskRepo.FindByID(taskID); err != nil {
+		return nil, err
+	}
+	return s.repo.ListByTask(taskID)
+}
+
+func (s *attachmentService) Open(ctx context.Context, taskID, id uuid.UUID) (*models.Attachment, io.ReadCloser, error) {
+	attachment, err := s.find(taskID, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	body, err := s.store.Get(ctx, models.BlobKey(attachment.Hash))
+	if err != nil {
+		return nil, nil, err
+	}
+	return attachment, body, nil
+}
+
+func (s *attachmentService) Delete(ctx context.Context, taskID, id uuid.UUID) error {
+	attachment, err := s.find(taskID, id)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.Delete(id); err != nil {
+		return err
+	}
+	return s.releaseBlob(ctx, attachment.Hash)
+}
+
+// DeleteOrphanBlobs удаляет содержимое, на которое не ссылается ни одно вложение
+// (например, после каскадного удаления задач)
+func (s *attachmentService) DeleteOrphanBlobs(ctx context.Context) error {
+	hashes, err := s.repo.OrphanHashes()
+	if err != nil {
+		return err
+	}
+	for _, hash := range hashes {
+		if err := s.releaseBlob(ctx, hash); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *attachmentService) releaseBlob(ctx context.Context, hash string) error {
+	return s.repo.ReleaseBlob(hash, func() error {
+		return s.store.Delete(ctx, models.BlobKey(hash))
+	})
+}
+
+func (s *attachmentService) find(taskID, id uuid.UUID) (*models.Attachment, error) {
+	attachment, err := s.repo.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if attachment.TaskID != taskID {
+		return nil, ErrAttachmentNotFound
+	}
+	return attachment, nil
+}
+
+func (s *attachmentService) allowed(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	for _, t := range s.config.AllowedTypes {
+		if strings.EqualFold(t, mediaType) {
+			return true
+		}
+	}
+	return false
+}
+
+// sniffContentType определяет тип по содержимому, а не по имени файла или заголовкам клиента
+func sniffContentType(f *os.File) (string, error) {
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	head := make([]byte, 512)
+	n, err := io.ReadFull(f, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+	return http.DetectContentType(head[:n]), nil
+}
+
+func sanitizeFileName(name string) string {
+	name = filepath.Base(strings.ReplaceAll(name, "\\", "/"))
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, name)
+	name = strings.TrimSpace(name)
+	if name == "" || name == "." || name == "/" {
+		name = "file"
+	}
+	if runes := []rune(name); len(runes) > 255 {
+		name = string(runes[:255])
+	}
+	return name
+}
+
+func logCleanupError(err error) {
+	if err != nil {
+		log.Println("Failed to clean up attachment blobs:", err)
+	}
+}
@@ -0,0 +1,238 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"io"
+	"strings"
+	"task-tracker/internal/models"
+	"task-tracker/pkg/storage"
+	"testing"
+)
+
+// fakeAttachmentRepo повторяет семантику attachmentRepo: блоб создаётся один раз на хеш
+// и удаляется только когда на него не осталось вложений
+type fakeAttachmentRepo struct {
+	attachments map[uuid.UUID]*models.Attachment
+	blobs       map[string]*models.Blob
+}
+
+func newFakeAttachmentRepo() *fakeAttachmentRepo {
+	return &fakeAttachmentRepo{attachments: map[uuid.UUID]*models.Attachment{}, blobs: map[string]*models.Blob{}}
+}
+
+func (r *fakeAttachmentRepo) Create(attachment *models.Attachment, blob *models.Blob, ensureContent func(isNew bool) error) error {
+	_, exists := r.blobs[blob.Hash]
+	if err := ensureContent(!exists); err != nil {
+		return err
+	}
+	if !exists {
+		r.blobs[blob.Hash] = blob
+	}
+	attachment.ID = uuid.New()
+	r.attachments[attachment.ID] = attachment
+	return nil
+}
+
+func (r *fakeAttachmentRepo) FindByID(id uuid.UUID) (*models.Attachment, error) {
+	attachment, ok := r.attachments[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return attachment, nil
+}
+
+func (r *fakeAttachmentRepo) ListByTask(taskID uuid.UUID) ([]models.Attachment, error) {
+	var result []models.Attachment
+	for _, attachment := range r.attachments {
+		if attachment.TaskID == taskID {
+			result = append(result, *attachment)
+		}
+	}
+	return result, nil
+}
+
+func (r *fakeAttachmentRepo) Delete(id uuid.UUID) error {
+	delete(r.attachments, id)
+	return nil
+}
+
+func (r *fakeAttachmentRepo) OrphanHashes() ([]string, error) {
+	var hashes []string
+	for hash := range r.blobs {
+		if r.refs(hash) == 0 {
+			hashes = append(hashes, hash)
+		}
+	}
+	return hashes, nil
+}
+
+func (r *fakeAttachmentRepo) ReleaseBlob(hash string, remove func() error) error {
+	if _, ok := r.blobs[hash]; !ok || r.refs(hash) > 0 {
+		return nil
+	}
+	delete(r.blobs, hash)
+	return remove()
+}
+
+func (r *fakeAttachmentRepo) refs(hash string) int {
+	n := 0
+	for _, attachment := range r.attachments {
+		if attachment.Hash == hash {
+			n++
+		}
+	}
+	return n
+}
+
+// countingStore считает обращения к хранилищу
+type countingStore struct {
+	storage.BlobStore
+	puts, deletes int
+}
+
+func (s *countingStore) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	s.puts++
+	return s.BlobStore.Put(ctx, key, r, size, contentType)
+}
+
+func (s *countingStore) Delete(ctx context.Context, key string) error {
+	s.deletes++
+	return s.BlobStore.Delete(ctx, key)
+}
+
+func newTestAttachmentService(t *testing.T) (AttachmentService, *fakeAttachmentRepo, *countingStore, uuid.UUID) {
+	t.Helper()
+	local, err := storage.NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	task := &models.Task{ID: uuid.New(), Title: "task"}
+	repo := newFakeAttachmentRepo()
+	store := &countingStore{BlobStore: local}
+	return NewAttachmentService(repo, newFakeTaskRepo(task), store, AttachmentConfig{MaxSize: 64}), repo, store, task.ID
+}
+
+func TestAttachmentUploadDeduplicatesContent(t *testing.T) {
+	svc, repo, store, taskID := newTestAttachmentService(t)
+	ctx := context.Background()
+	userID := uuid.New()
+
+	first, err := svc.Upload(ctx, taskID, userID, "a.txt", strings.NewReader("same content"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := svc.Upload(ctx, taskID, userID, "../../b.txt", strings.NewReader("same content"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if first.Hash != second.Hash {
+		t.Fatalf("hashes differ: %s vs %s", first.Hash, second.Hash)
+	}
+	if store.puts != 1 {
+		t.Errorf("store.Put called %d times, want 1", store.puts)
+	}
+	if len(repo.blobs) != 1 {
+		t.Errorf("blobs = %d, want 1", len(repo.blobs))
+	}
+	if second.FileName != "b.txt" {
+		t.Errorf("file name = %q, want sanitized b.txt", second.FileName)
+	}
+
+	// Удаление одного из двух вложений не трогает общее содержимое
+	if err := svc.Delete(ctx, taskID, first.ID); err != nil {
+		t.Fatal(err)
+	}
+	if store.deletes != 0 {
+		t.Errorf("blob deleted while still referenced")
+	}
+	_, body, err := svc.Open(ctx, taskID, second.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, _ := io.ReadAll(body)
+	body.Close()
+	if string(content) != "same content" {
+		t.Errorf("content = %q", content)
+	}
+
+	if err := svc.Delete(ctx, taskID, second.ID); err != nil {
+		t.Fatal(err)
+	}
+	if store.deletes != 1 || len(repo.blobs) != 0 {
+		t.Errorf("after last delete: store deletes = %d, blobs = %d; want 1, 0", store.deletes, len(repo.blobs))
+	}
+}
+
+func TestAttachmentUploadRestoresMissingContent(t *testing.T) {
+	svc, _, store, taskID := newTestAttachmentService(t)
+	ctx := context.Background()
+
+	first, err := svc.Upload(ctx, taskID, uuid.New(), "a.txt", strings.NewReader("content"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Содержимое пропало из хранилища, но строка блоба осталась
+	if err := store.BlobStore.Delete(ctx, models.BlobKey(first.Hash)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.Upload(ctx, taskID, uuid.New(), "a.txt", strings.NewReader("content")); err != nil {
+		t.Fatal(err)
+	}
+	if store.puts != 2 {
+		t.Errorf("store.Put called %d times, want 2", store.puts)
+	}
+}
+
+func TestAttachmentDeleteOrphanBlobs(t *testing.T) {
+	svc, repo, store, taskID := newTestAttachmentService(t)
+	ctx := context.Background()
+
+	kept, err := svc.Upload(ctx, taskID, uuid.New(), "kept.txt", strings.NewReader("kept"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	orphan, err := svc.Upload(ctx, taskID, uuid.New(), "orphan.txt", strings.NewReader("orphan"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Каскадное удаление задачи убирает строки вложений в обход сервиса
+	delete(repo.attachments, orphan.ID)
+
+	if err := svc.DeleteOrphanBlobs(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if store.deletes != 1 {
+		t.Errorf("store.Delete called %d times, want 1", store.deletes)
+	}
+	if _, err := store.Get(ctx, models.BlobKey(orphan.Hash)); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("orphan content still stored: %v", err)
+	}
+	if _, ok := repo.blobs[kept.Hash]; !ok {
+		t.Error("referenced blob was removed")
+	}
+}
+
+func TestAttachmentUploadLimits(t *testing.T) {
+	svc, _, store, taskID := newTestAttachmentService(t)
+	ctx := context.Background()
+
+	_, err := svc.Upload(ctx, taskID, uuid.New(), "big.txt", bytes.NewReader(bytes.Repeat([]byte("a"), 65)))
+	if !errors.Is(err, ErrAttachmentTooLarge) {
+		t.Errorf("oversized upload error = %v, want ErrAttachmentTooLarge", err)
+	}
+	_, err = svc.Upload(ctx, taskID, uuid.New(), "x.txt", bytes.NewReader([]byte{0x7f, 'E', 'L', 'F', 2, 1, 1, 0}))
+	if !errors.Is(err, ErrUnsupportedMediaType) {
+		t.Errorf("binary upload error = %v, want ErrUnsupportedMediaType", err)
+	}
+	if store.puts != 0 {
+		t.Errorf("rejected uploads reached the store")
+	}
+	if _, err := svc.Upload(ctx, uuid.New(), uuid.New(), "a.txt", strings.NewReader("x")); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("upload to missing task error = %v", err)
+	}
+}
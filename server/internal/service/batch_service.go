@@ -0,0 +1,405 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"regexp"
+	"slices"
+	"strings"
+	"task-tracker/internal/dto"
+	"task-tracker/internal/models"
+	"task-tracker/internal/repository"
+	"time"
+)
+
+var (
+	ErrInvalidBatch = errors.New("invalid batch operation")
+	ErrIDTaken      = errors.New("id is already used")
+)
+
+// Сколько операций можно выполнить одним пакетом
+const maxBatchOperations = 100
+
+var batchRefPattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_-]{0,63}$`)
+
+// BatchOpError — ошибка операции Index, из-за которой пакет откатился целиком
+type BatchOpError struct {
+	Index int
+	Err   error
+}
+
+func (e *BatchOpError) Error() string {
+	return fmt.Sprintf("operation %d: %v", e.Index, e.Err)
+}
+
+func (e *BatchOpError) Unwrap() error {
+	return e.Err
+}
+
+type BatchService interface {
+	Execute(userID uuid.UUID, ops []dto.BatchOperation) (*dto.BatchResult, error)
+}
+
+type batchService struct {
+	tx    repository.Transactor
+	users repository.UserRepository
+	views repository.SavedViewRepository
+	blobs BlobCollector
+}
+
+func NewBatchService(tx repository.Transactor, users repository.UserRepository, views repository.SavedViewRepository, blobs BlobCollector) BatchService {
+	return &batchService{
+		tx:    tx,
+		users: users,
+		views: views,
+		blobs: blobs,
+	}
+}
+
+// Execute выполняет операции по порядку в одной транзакции. Первая ошибка откатывает
+// весь пакет: вместе с результатом возвращается *BatchOpError
+func (s *batchService) Execute(userID uuid.UUID, ops []dto.BatchOperation) (*dto.BatchResult, error) {
+	if len(ops) == 0 {
+		return nil, fmt.Errorf("%w: no operations", ErrInvalidBatch)
+	}
+	if len(ops) > maxBatchOperations {
+		return nil, fmt.Errorf("%w: at most %d operations per batch", ErrInvalidBatch, maxBatchOperations)
+	}
+
+	result := &dto.BatchResult{Results: make([]dto.BatchOpResult, len(ops))}
+	for i, op := range ops {
+		result.Results[i] = dto.BatchOpResult{Index: i, Ref: op.Ref, Result: dto.BatchSkipped}
+	}
+
+	deleted := false
+	err := s.tx.Transaction(func(tasks repository.TaskRepository, projects repository.ProjectRepository) error {
+		run := s.newRun(userID, tasks, projects)
+		for i, op := range ops {
+			item := &result.Results[i]
+			id, data, err := run.execute(op)
+			if err != nil {
+				item.Result, item.Error = dto.BatchFailed, err.Error()
+				return &BatchOpError{Index: i, Err: err}
+			}
+			item.Result, item.ID, item.Data = dto.BatchOK, &id, data
+			deleted = deleted || op.Method == dto.BatchDelete
+		}
+		return nil
+	})
+
+	var opErr *BatchOpError
+	if errors.As(err, &opErr) {
+		// Созданные в откатившемся пакете объекты не существуют, их данные не отдаём
+		for i := range opErr.Index {
+			item := &result.Results[i]
+			item.Result, item.ID, item.Data = dto.BatchRolledBack, nil, nil
+		}
+		return result, err
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	result.Committed = true
+	if deleted {
+		logCleanupError(s.blobs.DeleteOrphanBlobs(context.Background()))
+	}
+	return result, nil
+}
+
+// newRun собирает сервисы задач и проектов поверх репозиториев транзакции. Их события
+// пишутся в outbox той же транзакцией и откатываются вместе с пакетом
+func (s *batchService) newRun(userID uuid.UUID, tasks repository.TaskRepository, projects repository.ProjectRepository) *batchRun {
+	return &batchRun{
+		userID:   userID,
+		tasks:    &taskService{repo: tasks, projects: projects, blobs: deferredBlobCleanup{}},
+		projects: &projectService{repo: projects, userRepo: s.users, tasks: tasks, views: s.views, blobs: deferredBlobCleanup{}},
+		refs:     make(map[string]batchRef),
+	}
+}
+
+type batchRef struct {
+	resource dto.BatchResource
+	id       uuid.UUID
+}
+
+// batchRun — сервисы, работающие внутри транзакции пакета, и созданные им объекты
+type batchRun struct {
+	userID   uuid.UUID
+	tasks    *taskService
+	projects *projectService
+	refs     map[string]batchRef
+}
+
+func (r *batchRun) execute(op dto.BatchOperation) (uuid.UUID, any, error) {
+	if op.Ref != "" {
+		switch {
+		case op.Method != dto.BatchCreate:
+			return uuid.Nil, nil, fmt.Errorf("%w: ref is allowed only for create", ErrInvalidBatch)
+		case !batchRefPattern.MatchString(op.Ref):
+			return uuid.Nil, nil, fmt.Errorf("%w: ref must start with a letter and contain only letters, digits, _ and -", ErrInvalidBatch)
+		}
+		if _, taken := r.refs[op.Ref]; taken {
+			return uuid.Nil, nil, fmt.Errorf("%w: ref %q is already used", ErrInvalidBatch, op.Ref)
+		}
+	}
+	if op.Method != dto.BatchCreate && op.ID == "" {
+		return uuid.Nil, nil, fmt.Errorf("%w: id is required for update and delete", ErrInvalidBatch)
+	}
+
+	switch {
+	case op.Resource == dto.BatchTask && op.Method == dto.BatchCreate:
+		return r.createTask(op)
+	case op.Resource == dto.BatchTask && op.Method == dto.BatchUpdate:
+		return r.updateTask(op)
+	case op.Resource == dto.BatchTask && op.Method == dto.BatchDelete:
+		return r.deleteTask(op)
+	case op.Resource == dto.BatchProject && op.Method == dto.BatchCreate:
+		return r.createProject(op)
+	case op.Resource == dto.BatchProject && op.Method == dto.BatchUpdate:
+		return r.updateProject(op)
+	case op.Resource == dto.BatchProject && op.Method == dto.BatchDelete:
+		return r.deleteProject(op)
+	}
+	return uuid.Nil, nil, fmt.Errorf("%w: unknown operation %q on %q", ErrInvalidBatch, op.Method, op.Resource)
+}
+
+func (r *batchRun) createTask(op dto.BatchOperation) (uuid.UUID, any, error) {
+	var body dto.TaskCreate
+	if err := r.decodeBody(op.Body, &body); err != nil {
+		return uuid.Nil, nil, err
+	}
+	if strings.TrimSpace(body.Title) == "" {
+		return uuid.Nil, nil, ErrEmptyTitle
+	}
+	if !slices.Contains(models.TaskPriorities, body.Priority) {
+		return uuid.Nil, nil, fmt.Errorf("%w: priority must be low, medium or high", ErrInvalidBatch)
+	}
+	var dueDate *time.Time
+	if body.DueDate != nil {
+		due, err := dto.ParseDueDate(*body.DueDate)
+		if err != nil {
+			return uuid.Nil, nil, fmt.Errorf("%w: due_date must be YYYY-MM-DD or RFC 3339", ErrInvalidBatch)
+		}
+		dueDate = &due
+	}
+	id, err := r.newID(op.ID, r.tasks.repo.Exists)
+	if err != nil {
+		return uuid.Nil, nil, err
+	}
+	task, err := r.tasks.Create(CreateTaskRequest{
+		ID:                 id,
+		Title:              body.Title,
+		Description:        body.Description,
+		Priority:           body.Priority,
+		DueDate:            dueDate,
+		ProjectID:          body.ProjectID,
+		Recurrence:         body.Recurrence,
+		RecurrenceTimezone: body.RecurrenceTimezone,
+		Estimate:           body.Estimate,
+		RemainingEstimate:  body.RemainingEstimate,
+	}, r.userID)
+	if err != nil {
+		return uuid.Nil, nil, err
+	}
+	r.remember(op.Ref, dto.BatchTask, task.ID)
+	// Перечитываем, чтобы вернуть ключ задачи и агрегаты
+	created, err := r.tasks.GetByID(task.ID)
+	return task.ID, created, err
+}
+
+func (r *batchRun) updateTask(op dto.BatchOperation) (uuid.UUID, any, error) {
+	task, err := r.findTask(op.ID)
+	if err != nil {
+		return uuid.Nil, nil, err
+	}
+	var patch dto.TaskPatch
+	if err := r.decodeBody(op.Body, &patch); err != nil {
+		return uuid.Nil, nil, err
+	}
+	task, err = r.tasks.Patch(task.ID, r.userID, patch, op.IfMatch)
+	if err != nil {
+		return uuid.Nil, nil, err
+	}
+	return task.ID, task, nil
+}
+
+func (r *batchRun) deleteTask(op dto.BatchOperation) (uuid.UUID, any, error) {
+	task, err := r.findTask(op.ID)
+	if err != nil {
+		return uuid.Nil, nil, err
+	}
+	return task.ID, nil, r.tasks.Delete(task.ID, op.IfMatch)
+}
+
+func (r *batchRun) createProject(op dto.BatchOperation) (uuid.UUID, any, error) {
+	var body dto.ProjectCreate
+	if err := r.decodeBody(op.Body, &body); err != nil {
+		return uuid.Nil, nil, err
+	}
+	if strings.TrimSpace(body.Name) == "" {
+		return uuid.Nil, nil, fmt.Errorf("%w: name is required", ErrInvalidBatch)
+	}
+
+	id, err := r.newID(op.ID, r.projects.repo.Exists)
+	if err != nil {
+		return uuid.Nil, nil, err
+	}
+
+	project, err := r.projects.Create(CreateProjectRequest{
+		ID:           id,
+		Name:         body.Name,
+		Key:          body.Key,
+		Description:  body.Description,
+		Color:        body.Color,
+		EstimateUnit: body.EstimateUnit,
+		WIPLimits:    body.WIPLimits,
+		WIPStrict:    body.WIPStrict,
+	}, r.userID)
+	if err != nil {
+		return uuid.Nil, nil, err
+	}
+	r.remember(op.Ref, dto.BatchProject, project.ID)
+	return project.ID, project, nil
+}
+
+func (r *batchRun) updateProject(op dto.BatchOperation) (uuid.UUID, any, error) {
+	project, err := r.findProject(op.ID)
+	if err != nil {
+		return uuid.Nil, nil, err
+	}
+	var patch dto.ProjectPatch
+	if err := r.decodeBody(op.Body, &patch); err != nil {
+		return uuid.Nil, nil, err
+	}
+	project, err = r.projects.Patch(project.ID, patch, op.IfMatch)
+	if err != nil {
+		return uuid.Nil, nil, err
+	}
+	return project.ID, project, nil
+}
+
+func (r *batchRun) deleteProject(op dto.BatchOperation) (uuid.UUID, any, error) {
+	project, err := r.findProject(op.ID)
+	if err != nil {
+		return uuid.Nil, nil, err
+	}
+	return project.ID, nil, r.projects.Delete(project.ID, op.IfMatch)
+}
+
+// findTask находит задачу, которую пользователь может менять; чужие — «не найдены»
+func (r *batchRun) findTask(value string) (*models.Task, error) {
+	id, err := r.resolve(value, dto.BatchTask)
+	if err != nil {
+		return nil, err
+	}
+	task, err := r.tasks.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if !canModifyTask(task, r.userID) {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return task, nil
+}
+
+// findProject находит проект пользователя: менять и удалять проект может только владелец
+func (r *batchRun) findProject(value string) (*models.Project, error) {
+	id, err := r.resolve(value, dto.BatchProject)
+	if err != nil {
+		return nil, err
+	}
+	project, err := r.projects.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if project.UserID != r.userID {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return project, nil
+}
+
+// newID проверяет идентификатор, заданный клиентом для нового объекта; пустой — сгенерировать
+func (r *batchRun) newID(value string, exists func(uuid.UUID) (bool, error)) (uuid.UUID, error) {
+	if value == "" {
+		return uuid.Nil, nil
+	}
+	id, err := uuid.Parse(value)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("%w: id of a new object must be a UUID", ErrInvalidBatch)
+	}
+	taken, err := exists(id)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if taken {
+		return uuid.Nil, ErrIDTaken
+	}
+	return id, nil
+}
+
+func (r *batchRun) remember(ref string, resource dto.BatchResource, id uuid.UUID) {
+	if ref != "" {
+		r.refs[ref] = batchRef{resource: resource, id: id}
+	}
+}
+
+// resolve разбирает UUID или ссылку "$имя" на объект, созданный раньше в этом пакете
+func (r *batchRun) resolve(value string, resource dto.BatchResource) (uuid.UUID, error) {
+	if name, ok := strings.CutPrefix(value, "$"); ok {
+		ref, found := r.refs[name]
+		if !found || ref.resource != resource {
+			return uuid.Nil, fmt.Errorf("%w: unknown %s reference %q", ErrInvalidBatch, resource, value)
+		}
+		return ref.id, nil
+	}
+	id, err := uuid.Parse(value)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("%w: invalid %s id %q", ErrInvalidBatch, resource, value)
+	}
+	return id, nil
+}
+
+// decodeBody подставляет ссылку в project_id и строго разбирает тело операции:
+// неизвестные поля отклоняются, как и в PATCH
+func (r *batchRun) decodeBody(body json.RawMessage, v any) error {
+	var fields map[string]json.RawMessage
+	if len(body) > 0 {
+		if err := json.Unmarshal(body, &fields); err != nil {
+			return fmt.Errorf("%w: body must be a JSON object", ErrInvalidBatch)
+		}
+	}
+
+	var projectRef string
+	if raw, ok := fields["project_id"]; ok && json.Unmarshal(raw, &projectRef) == nil && strings.HasPrefix(projectRef, "$") {
+		id, err := r.resolve(projectRef, dto.BatchProject)
+		if err != nil {
+			return err
+		}
+		fields["project_id"], _ = json.Marshal(id)
+	}
+
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidBatch, err)
+	}
+	return nil
+}
+
+// deferredBlobCleanup не чистит содержимое вложений внутри транзакции пакета: удаление
+// ещё не зафиксировано, поэтому чистка выполняется после коммита
+type deferredBlobCleanup struct{}
+
+func (deferredBlobCleanup) DeleteOrphanBlobs(context.Context) error {
+	return nil
+}
@@ -0,0 +1,158 @@
+package service
+
+import (
+	"errors"
+	"github.com/google/uuid"
+	"strings"
+	"task-tracker/internal/models"
+	"task-tracker/internal/repository"
+	"time"
+)
+
+var (
+	ErrChecklistItemNotFound = errors.New("checklist item not found")
+	ErrEmptyTitle            = errors.New("title must not be empty")
+)
+
+type UpdateChecklistItemRequest struct {
+	Title *string
+	Done  *bool
+}
+
+type ChecklistService interface {
+	List(taskID uuid.UUID) ([]models.ChecklistItem, error)
+	Create(taskID uuid.UUID, title string) (*models.ChecklistItem, error)
+	Update(taskID, itemID uuid.UUID, req UpdateChecklistItemRequest) (*models.ChecklistItem, error)
+	Toggle(taskID, itemID uuid.UUID) (*models.ChecklistItem, error)
+	Delete(taskID, itemID uuid.UUID) error
+	Reorder(taskID uuid.UUID, itemIDs []uuid.UUID) ([]models.ChecklistItem, error)
+	Promote(taskID, itemID, userID uuid.UUID) (*models.Task, error)
+}
+
+type checklistService struct {
+	repo     repository.ChecklistRepository
+	taskRepo repository.TaskRepository
+}
+
+func NewChecklistService(repo repository.ChecklistRepository, taskRepo repository.TaskRepository) ChecklistService {
+	return &checklistService{
+		repo:     repo,
+		taskRepo: taskRepo,
+	}
+}
+
+func (s *checklistService) List(taskID uuid.UUID) ([]models.ChecklistItem, error) {
+	if _, err := s.taskRepo.FindByID(taskID); err != nil {
+		return nil, err
+	}
+	return s.repo.ListByTask(taskID)
+}
+
+func (s *checklistService) Create(taskID uuid.UUID, title string) (*models.ChecklistItem, error) {
+	title = strings.TrimSpace(title)
+	if title == "" {
+		return nil, ErrEmptyTitle
+	}
+	if _, err := s.taskRepo.FindByID(taskID); err != nil {
+		return nil, err
+	}
+
+	pos, err := s.repo.NextPosition(taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	item := &models.ChecklistItem{
+		TaskID:   taskID,
+		Title:    title,
+		Position: pos,
+	}
+	return item, s.repo.Create(item)
+}
+
+func (s *checklistService) Update(taskID, itemID uuid.UUID, req UpdateChecklistItemRequest) (*models.ChecklistItem, error) {
+	item, err := s.find(taskID, itemID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Title != nil {
+		title := strings.TrimSpace(*req.Title)
+		if title == "" {
+			return nil, ErrEmptyTitle
+		}
+		item.Title = title
+	}
+	if req.Done != nil {
+		item.Done = *req.Done
+	}
+
+	return item, s.repo.Update(item)
+}
+
+func (s *checklistService) Toggle(taskID, itemID uuid.UUID) (*models.ChecklistItem, error) {
+	item, err := s.find(taskID, itemID)
+	if err != nil {
+		return nil, err
+	}
+	item.Done = !item.Done
+	return item, s.repo.Update(item)
+}
+
+func (s *checklistService) Delete(taskID, itemID uuid.UUID) error {
+	if _, err := s.find(taskID, itemID); err != nil {
+		return err
+	}
+	return s.repo.Delete(itemID)
+}
+
+func (s *checklistService) Reorder(taskID uuid.UUID, itemIDs []uuid.UUID) ([]models.ChecklistItem, error) {
+	if err := s.repo.Reorder(taskID, itemIDs); err != nil {
+		return nil, err
+	}
+	return s.repo.ListByTask(taskID)
+}
+
+// Promote превращает пункт чек-листа в подзадачу, наследующую проект и приоритет родителя
+func (s *checklistService) Promote(taskID, itemID, userID uuid.UUID) (*models.Task, error) {
+	item, err := s.find(taskID, itemID)
+	if err != nil {
+		return nil, err
+	}
+	parent, err := s.taskRepo.FindByID(taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	status := models.StatusTodo
+	var completedAt *time.Time
+	if item.Done {
+		status = models.StatusDone
+		completedAt = &item.UpdatedAt
+	}
+
+	subtask := &models.Task{
+		Title:       item.Title,
+		Priority:    parent.Priority,
+		Status:      status,
+		CompletedAt: completedAt,
+		UserID:      userID,
+		ProjectID:   parent.ProjectID,
+		ParentID:    &parent.ID,
+	}
+	if err := s.repo.Promote(item, subtask); err != nil {
+		return nil, err
+	}
+	return subtask, nil
+}
+
+func (s *checklistService) find(taskID, itemID uuid.UUID) (*models.ChecklistItem, error) {
+	item, err := s.repo.FindByID(itemID)
+	if err != nil {
+		return nil, err
+	}
+	if item.TaskID != taskID {
+		return nil, ErrChecklistItemNotFound
+	}
+	return item, nil
+}
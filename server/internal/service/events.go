@@ -0,0 +1,113 @@
+package service
+
+import (
+	"encoding/json"
+	"github.com/google/uuid"
+	"log"
+	"slices"
+	"task-tracker/internal/models"
+	"task-tracker/pkg/events"
+	"time"
+)
+
+// Типы событий об изменениях задач и проектов
+const (
+	EventTaskCreated = "task.created"
+	EventTaskUpdated = "task.updated"
+	EventTaskDeleted = "task.deleted"
+	// Дополнительно к task.updated, когда задача сменила колонку
+	EventTaskStatusChanged = "task.status_changed"
+	EventProjectCreated    = "project.created"
+	EventProjectUpdated    = "project.updated"
+	EventProjectDeleted    = "project.deleted"
+)
+
+// EventPublisher принимает события об изменениях; реализуется шиной events.Bus
+type EventPublisher interface {
+	Publish(e events.Event)
+}
+
+// pendingEvents копит события изменения, пока идёт его транзакция; перед коммитом они
+// записываются в outbox
+type pendingEvents struct {
+	events []events.Event
+}
+
+func (p *pendingEvents) Publish(e events.Event) {
+	p.events = append(p.events, e)
+}
+
+func newEvent(kind string, projectID *uuid.UUID, audience []uuid.UUID, data any) events.Event {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		log.Printf("Failed to encode %s event: %v", kind, err)
+	}
+	audience = slices.Clone(audience)
+	slices.SortFunc(audience, func(a, b uuid.UUID) int { return slices.Compare(a[:], b[:]) })
+	e := events.Event{Type: kind, Audience: slices.Compact(audience), Data: raw, Time: time.Now()}
+	if projectID != nil {
+		e.ProjectID = *projectID
+	}
+	return e
+}
+
+// taskAudience — кто видит задачу: её владелец и владелец её проекта
+func taskAudience(task *models.Task) []uuid.UUID {
+	audience := []uuid.UUID{task.UserID}
+	if task.Project != nil {
+		audience = append(audience, task.Project.UserID)
+	}
+	return audience
+}
+
+// publishTask сообщает о созданной или изменённой задаче всем, кто видит её сейчас,
+// и тем, кто видел её до изменения (previous), — иначе они не узнают, что задача ушла
+func (s *taskService) publishTask(kind string, id uuid.UUID, previous []uuid.UUID) {
+	task, err := s.repo.FindByID(id)
+	if err != nil {
+		log.Printf("Failed to load task %s for %s event: %v", id, kind, err)
+		return
+	}
+	s.events.Publish(newEvent(kind, task.ProjectID, append(previous, taskAudience(task)...), task))
+}
+
+// publishTaskUpdated сообщает об изменённой задаче, а если она сменила статус — ещё и task.status_changed
+func (s *taskService) publishTaskUpdated(id uuid.UUID, previous []uuid.UUID, previousStatus models.TaskStatus) {
+	task, err := s.repo.FindByID(id)
+	if err != nil {
+		log.Printf("Failed to load task %s for %s event: %v", id, EventTaskUpdated, err)
+		return
+	}
+	audience := append(previous, taskAudience(task)...)
+	s.events.Publish(newEvent(EventTaskUpdated, task.ProjectID, audience, task))
+	if task.Status != previousStatus {
+		s.events.Publish(newEvent(EventTaskStatusChanged, task.ProjectID, audience, map[string]any{
+			"task":            task,
+			"previous_status": previousStatus,
+		}))
+	}
+}
+
+func (s *taskService) publishTaskDeleted(task *models.Task) {
+	s.events.Publish(newEvent(EventTaskDeleted, task.ProjectID, taskAudience(task), map[string]any{
+		"id":         task.ID,
+		"project_id": task.ProjectID,
+	}))
+}
+
+// publishProject сообщает об изменении проекту: владельцу и всем, у кого в нём есть задачи.
+// Задачи проекта в событие не входят
+func (s *projectService) publishProject(kind string, id uuid.UUID) {
+	project, err := s.repo.FindByID(id)
+	if err != nil {
+		log.Printf("Failed to load project %s for %s event: %v", id, kind, err)
+		return
+	}
+	audience, err := s.repo.ViewerIDs(id)
+	if err != nil {
+		log.Printf("Failed to load viewers of project %s: %v", id, err)
+		return
+	}
+	project.Tasks = nil
+	s.events.Publish(newEvent(kind, &id, audience, project))
+}
@@ -0,0 +1,160 @@
+package service
+
+import (
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"slices"
+	"strings"
+	"task-tracker/internal/dto"
+	"task-tracker/internal/models"
+	"task-tracker/internal/repository"
+	"task-tracker/pkg/events"
+)
+
+// fakeTaskRepo хранит задачи в памяти; методы, не нужные тестам, паникуют через встроенный nil-интерфейс
+type fakeTaskRepo struct {
+	repository.TaskRepository
+	tasks  map[uuid.UUID]*models.Task
+	events []events.Event
+	// projects нужны LockProject; locks — какие проекты блокировались
+	projects *fakeProjectRepo
+	locks    []uuid.UUID
+}
+
+func newFakeTaskRepo(tasks ...*models.Task) *fakeTaskRepo {
+	r := &fakeTaskRepo{tasks: map[uuid.UUID]*models.Task{}}
+	for _, task := range tasks {
+		r.tasks[task.ID] = task
+	}
+	return r
+}
+
+func (r *fakeTaskRepo) FindByID(id uuid.UUID) (*models.Task, error) {
+	task, ok := r.tasks[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	copied := *task
+	return &copied, nil
+}
+
+// List понимает только фильтры напоминаний: владелец, статусы и срок [DueFrom, DueTo); сортирует по сроку
+func (r *fakeTaskRepo) List(filter dto.TaskFilter, page dto.PageRequest) (*dto.ListResponse[models.Task], error) {
+	items := []models.Task{}
+	for _, task := range r.tasks {
+		switch {
+		case filter.UserID != nil && task.UserID != *filter.UserID,
+			len(filter.Statuses) > 0 && !slices.Contains(filter.Statuses, task.Status),
+			(filter.DueFrom != nil || filter.DueTo != nil) && task.DueDate == nil,
+			filter.DueFrom != nil && task.DueDate.Before(*filter.DueFrom),
+			filter.DueTo != nil && !task.DueDate.Before(*filter.DueTo):
+			continue
+		}
+		items = append(items, *task)
+	}
+	slices.SortFunc(items, func(a, b models.Task) int {
+		return a.DueDate.Compare(*b.DueDate)
+	})
+	if page.Limit > 0 && len(items) > page.Limit {
+		items = items[:page.Limit]
+	}
+	return &dto.ListResponse[models.Task]{Items: items, Total: int64(len(items))}, nil
+}
+
+func (r *fakeTaskRepo) Create(task *models.Task) error {
+	if task.ID == uuid.Nil {
+		task.ID = uuid.New()
+	}
+	copied := *task
+	r.tasks[task.ID] = &copied
+	return nil
+}
+
+func (r *fakeTaskRepo) Update(task *models.Task) error {
+	if _, ok := r.tasks[task.ID]; !ok {
+		return gorm.ErrRecordNotFound
+	}
+	copied := *task
+	r.tasks[task.ID] = &copied
+	return nil
+}
+
+func (r *fakeTaskRepo) Delete(id uuid.UUID, version *int) error {
+	task, ok := r.tasks[id]
+	if version != nil && (!ok || task.Version != *version) {
+		return repository.ErrVersionConflict
+	}
+	delete(r.tasks, id)
+	return nil
+}
+
+func (r *fakeTaskRepo) CompleteRecurring(task *models.Task, next *models.Task) error {
+	next.ID = uuid.New()
+	r.tasks[next.ID] = next
+	return r.Update(task)
+}
+
+// Transaction не откатывает изменения: тестам достаточно, что fn выполняется
+func (r *fakeTaskRepo) Transaction(fn func(repo repository.TaskRepository) error) error {
+	return fn(r)
+}
+
+func (r *fakeTaskRepo) CountInColumn(columnID uuid.UUID, status models.TaskStatus, excludeID uuid.UUID) (int64, error) {
+	var count int64
+	for _, task := range r.tasks {
+		if task.ColumnID() == columnID && task.Status == status && task.ID != excludeID {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (r *fakeTaskRepo) LockProject(id uuid.UUID) (*models.Project, error) {
+	r.locks = append(r.locks, id)
+	return r.projects.FindByID(id)
+}
+
+func (r *fakeTaskRepo) AddEvents(evts []events.Event) error {
+	r.events = append(r.events, evts...)
+	return nil
+}
+
+// fakeProjectRepo хранит проекты в памяти; видимость задаётся явно
+type fakeProjectRepo struct {
+	repository.ProjectRepository
+	projects map[uuid.UUID]*models.Project
+	visible  map[uuid.UUID]bool
+	events   []events.Event
+}
+
+func newFakeProjectRepo(projects ...*models.Project) *fakeProjectRepo {
+	r := &fakeProjectRepo{projects: map[uuid.UUID]*models.Project{}, visible: map[uuid.UUID]bool{}}
+	for _, project := range projects {
+		r.projects[project.ID] = project
+		r.visible[project.ID] = true
+	}
+	return r
+}
+
+func (r *fakeProjectRepo) FindByID(id uuid.UUID) (*models.Project, error) {
+	project, ok := r.projects[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	copied := *project
+	return &copied, nil
+}
+
+func (r *fakeProjectRepo) FindByName(userID uuid.UUID, name string) ([]models.Project, error) {
+	var result []models.Project
+	for _, project := range r.projects {
+		if r.visible[project.ID] && strings.EqualFold(project.Name, name) {
+			result = append(result, *project)
+		}
+	}
+	return result, nil
+}
+
+func (r *fakeProjectRepo) IsVisible(id, userID uuid.UUID) (bool, error) {
+	return r.visible[id], nil
+}
@@ -0,0 +1,132 @@
+package service
+
+import (
+	"fmt"
+	"log"
+	"slices"
+	"task-tracker/internal/repository"
+	"task-tracker/pkg/events"
+	"time"
+)
+
+const outboxBatchSize = 100
+
+// Сколько хранятся отправленные события outbox
+const OutboxRetention = 7 * 24 * time.Hour
+
+// Через сколько ожидание незавершённой транзакции попадает в лог
+const outboxPendingWarning = time.Minute
+
+// EventHandler получает событие из outbox. Ошибка оставляет событие в outbox: позже его
+// получат все обработчики снова, поэтому обработчики должны переносить повтор
+type EventHandler func(e events.Event) error
+
+// OutboxDispatcher разбирает outbox и передаёт каждое событие обработчикам один раз на
+// все реплики — для доставки, которая должна случиться однажды (вебхуки)
+type OutboxDispatcher interface {
+	// Dispatch обрабатывает порцию событий и возвращает её размер
+	Dispatch() (int, error)
+	Purge() (int64, error)
+}
+
+type outboxDispatcher struct {
+	repo     repository.OutboxRepository
+	handlers []EventHandler
+}
+
+func NewOutboxDispatcher(repo repository.OutboxRepository, handlers ...EventHandler) OutboxDispatcher {
+	return &outboxDispatcher{repo: repo, handlers: handlers}
+}
+
+// Dispatch передаёт события обработчикам по порядку записи. Событие, на котором ошибся
+// обработчик, откладывается, но не задерживает следующие
+func (d *outboxDispatcher) Dispatch() (int, error) {
+	return d.repo.Claim(outboxBatchSize, func(rows []repository.OutboxEvent) map[int64]error {
+		failed := make(map[int64]error)
+		for _, row := range rows {
+			e := row.Event()
+			for _, handle := range d.handlers {
+				if err := handle(e); err != nil {
+					log.Printf("Outbox event %d (%s) failed: %v", row.ID, row.Type, err)
+					failed[row.ID] = fmt.Errorf("attempt %d: %w", row.Attempts+1, err)
+					break
+				}
+			}
+		}
+		return failed
+	})
+}
+
+func (d *outboxDispatcher) Purge() (int64, error) {
+	return d.repo.PurgeDispatched(time.Now().Add(-OutboxRetention))
+}
+
+// OutboxFollower читает ленту outbox на каждой реплике и публикует все события локально —
+// в шину потока событий, к которой подключены клиенты именно этой реплики. Долгая или
+// зависшая транзакция в базе ленту не останавливает: её события публикуются, когда она
+// завершится, а ожидание дольше outboxPendingWarning попадает в лог
+type OutboxFollower interface {
+	// Follow публикует порцию новых событий и возвращает её размер
+	Follow() (int, error)
+}
+
+type outboxFollower struct {
+	repo repository.OutboxRepository
+	to   EventPublisher
+	// position — последнее опубликованное событие; до первого вызова не задана
+	position *repository.OutboxPosition
+	// waits — незавершённые транзакции из позиции и с какого момента лента их ждёт
+	waits map[int64]*pendingWait
+}
+
+type pendingWait struct {
+	since  time.Time
+	warned bool
+}
+
+// NewOutboxFollower начинает с событий, зафиксированных после первого Follow: история
+// до запуска реплики её клиентам не нужна
+func NewOutboxFollower(repo repository.OutboxRepository, to EventPublisher) OutboxFollower {
+	return &outboxFollower{repo: repo, to: to, waits: make(map[int64]*pendingWait)}
+}
+
+func (f *outboxFollower) Follow() (int, error) {
+	if f.position == nil {
+		head, err := f.repo.Head()
+		if err != nil {
+			return 0, err
+		}
+		f.position = &head
+	}
+	rows, next, err := f.repo.Since(*f.position, outboxBatchSize)
+	if err != nil {
+		return 0, err
+	}
+	for _, row := range rows {
+		f.to.Publish(row.Event())
+	}
+	f.position = &next
+	f.watchPending(time.Now())
+	return len(rows), nil
+}
+
+// watchPending предупреждает о транзакциях, чьих событий лента ждёт дольше outboxPendingWarning:
+// поток событий они не задерживают, но их события придут клиентам с опозданием
+func (f *outboxFollower) watchPending(now time.Time) {
+	for xid := range f.waits {
+		if !slices.Contains(f.position.Pending, xid) {
+			delete(f.waits, xid)
+		}
+	}
+	for _, xid := range f.position.Pending {
+		w, ok := f.waits[xid]
+		if !ok {
+			f.waits[xid] = &pendingWait{since: now}
+			continue
+		}
+		if !w.warned && now.Sub(w.since) > outboxPendingWarning {
+			log.Printf("Outbox follower has waited %s for transaction %d; its events will be late", now.Sub(w.since).Round(time.Second), xid)
+			w.warned = true
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package service
+
+import (
+	"task-tracker/internal/repository"
+	"task-tracker/pkg/events"
+	"testing"
+)
+
+// fakeOutboxRepo отдаёт заранее заданные порции ленты и запоминает, с каких позиций читали
+type fakeOutboxRepo struct {
+	repository.OutboxRepository
+	head    repository.OutboxPosition
+	batches []fakeOutboxBatch
+	reads   []repository.OutboxPosition
+}
+
+type fakeOutboxBatch struct {
+	rows []repository.OutboxEvent
+	next repository.OutboxPosition
+}
+
+func (r *fakeOutboxRepo) Head() (repository.OutboxPosition, error) {
+	return r.head, nil
+}
+
+func (r *fakeOutboxRepo) Since(after repository.OutboxPosition, limit int) ([]repository.OutboxEvent, repository.OutboxPosition, error) {
+	r.reads = append(r.reads, after)
+	if len(r.batches) == 0 {
+		return nil, after, nil
+	}
+	batch := r.batches[0]
+	r.batches = r.batches[1:]
+	return batch.rows, batch.next, nil
+}
+
+type recordingPublisher struct {
+	ids []string
+}
+
+func (p *recordingPublisher) Publish(e events.Event) {
+	p.ids = append(p.ids, e.ID)
+}
+
+func TestOutboxFollowerReadsFromHeadAndKeepsPosition(t *testing.T) {
+	head := repository.OutboxPosition{XID: 10, Pending: []int64{9}}
+	second := repository.OutboxPosition{XID: 12, Pending: []int64{9}}
+	third := repository.OutboxPosition{XID: 13}
+	repo := &fakeOutboxRepo{head: head, batches: []fakeOutboxBatch{
+		{rows: []repository.OutboxEvent{{ID: 4, XID: 10}, {ID: 3, XID: 11}}, next: second},
+		{rows: []repository.OutboxEvent{{ID: 1, XID: 9}}, next: third},
+	}}
+	published := &recordingPublisher{}
+	f := NewOutboxFollower(repo, published)
+	for range 3 {
+		if _, err := f.Follow(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	assertEqual(t, published.ids, []string{"4", "3", "1"})
+	assertEqual(t, repo.reads, []repository.OutboxPosition{head, second, third})
+}
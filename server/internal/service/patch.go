@@ -0,0 +1,37 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"task-tracker/internal/dto"
+	"task-tracker/internal/repository"
+)
+
+var ErrInvalidPatch = errors.New("invalid patch")
+
+// requiredField возвращает значение поля, которое нельзя очистить через null
+func requiredField[T any](f dto.Field[T], name string) (T, error) {
+	if f.Value == nil {
+		var zero T
+		return zero, fmt.Errorf("%w: %s cannot be null", ErrInvalidPatch, name)
+	}
+	return *f.Value, nil
+}
+
+// valueOrZero возвращает значение поля или нулевое значение для null
+func valueOrZero[T any](f dto.Field[T]) T {
+	if f.Value == nil {
+		var zero T
+		return zero
+	}
+	return *f.Value
+}
+
+// checkVersion сверяет версию из If-Match с текущей. Само сохранение тоже условно
+// по версии, так что параллельная запись между чтением и записью не потеряется
+func checkVersion(current int, ifMatch *int) error {
+	if ifMatch != nil && *ifMatch != current {
+		return repository.ErrVersionConflict
+	}
+	return nil
+}
@@ -1,54 +1,156 @@
 package service
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
 	"task-tracker/internal/dto"
 	"task-tracker/internal/models"
 	"task-tracker/internal/repository"
 )
 
+var (
+	ErrInvalidEstimateUnit = errors.New("estimate unit must be hours or points")
+	ErrInvalidProjectKey   = errors.New("project key must be 2-10 latin letters or digits starting with a letter")
+	ErrInvalidWIPLimit     = errors.New("WIP limits must be positive and set for known statuses")
+)
+
+const defaultProjectColor = "#4f46e5"
+
+var projectColorPattern = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+const (
+	defaultBoardColumnSize = 50
+	maxBoardColumnSize     = 200
+)
+
 type CreateProjectRequest struct {
-	Name        string
-	Description string
-	Color       string
+	ID           uuid.UUID // задан клиентом (офлайн-создание); пустой — сгенерировать
+	Name         string
+	Key          string // пусто — сгенерировать по названию
+	Description  string
+	Color        string
+	EstimateUnit models.EstimateUnit
+	WIPLimits    map[models.TaskStatus]int
+	WIPStrict    bool
 }
 
 type UpdateProjectRequest struct {
-	Name        string
-	Description string
-	Color       string
+	Name         string
+	Description  string
+	Color        string
+	EstimateUnit models.EstimateUnit
+	WIPLimits    map[models.TaskStatus]int // nil — не менять, пустой — снять лимиты
+	WIPStrict    *bool
+	IfMatch      *int
 }
 
 type ProjectService interface {
 	Create(req CreateProjectRequest, userID uuid.UUID) (*models.Project, error)
 	GetByID(id uuid.UUID) (*models.Project, error)
 	Update(id uuid.UUID, req UpdateProjectRequest) error
-	Delete(id uuid.UUID) error
-	List(userID uuid.UUID, page, limit int) ([]dto.ListProjectsResponse, error)
+	Delete(id uuid.UUID, ifMatch *int) error
+	List(userID uuid.UUID, page dto.PageRequest) (*dto.ProjectListResponse, error)
+	Board(id, userID uuid.UUID, perColumn int) (*dto.Board, error)
+	Patch(id uuid.UUID, patch dto.ProjectPatch, ifMatch *int) (*models.Project, error)
 }
 
 type projectService struct {
 	repo     repository.ProjectRepository
 	userRepo repository.UserRepository
+	tasks    repository.TaskRepository
+	views    repository.SavedViewRepository
+	blobs    BlobCollector
+	// События изменения; есть только у копии сервиса внутри inTx
+	events *pendingEvents
 }
 
-func NewProjectService(repo repository.ProjectRepository, userRepo repository.UserRepository) ProjectService {
+func NewProjectService(repo repository.ProjectRepository, userRepo repository.UserRepository, tasks repository.TaskRepository, views repository.SavedViewRepository, blobs BlobCollector) ProjectService {
 	return &projectService{
 		repo:     repo,
 		userRepo: userRepo,
+		tasks:    tasks,
+		views:    views,
+		blobs:    blobs,
 	}
 }
 
+// inTx выполняет изменение в транзакции вместе с записью его событий в outbox, как у задач
+func (s *projectService) inTx(fn func(tx *projectService) error) error {
+	return s.repo.Transaction(func(repo repository.ProjectRepository) error {
+		tx := &projectService{repo: repo, userRepo: s.userRepo, tasks: s.tasks, views: s.views, blobs: deferredBlobCleanup{}, events: &pendingEvents{}}
+		if err := fn(tx); err != nil {
+			return err
+		}
+		return repo.AddEvents(tx.events.events)
+	})
+}
+
 func (s *projectService) Create(req CreateProjectRequest, userID uuid.UUID) (*models.Project, error) {
+	var project *models.Project
+	err := s.inTx(func(tx *projectService) (err error) {
+		project, err = tx.create(req, userID)
+		return err
+	})
+	return project, err
+}
+
+func (s *projectService) create(req CreateProjectRequest, userID uuid.UUID) (*models.Project, error) {
+	if req.EstimateUnit == "" {
+		req.EstimateUnit = models.EstimateHours
+	}
+	if !validEstimateUnit(req.EstimateUnit) {
+		return nil, ErrInvalidEstimateUnit
+	}
+	if !validWIPLimits(req.WIPLimits) {
+		return nil, ErrInvalidWIPLimit
+	}
+
 	project := &models.Project{
-		Name:        req.Name,
-		Description: req.Description,
-		Color:       req.Color,
-		UserID:      userID,
+		ID:           req.ID,
+		Name:         req.Name,
+		Description:  req.Description,
+		Color:        req.Color,
+		UserID:       userID,
+		EstimateUnit: req.EstimateUnit,
+		Key:          strings.ToUpper(strings.TrimSpace(req.Key)),
+		WIPLimits:    req.WIPLimits,
+		WIPStrict:    req.WIPStrict,
+	}
+	if project.Key != "" {
+		if !models.ValidProjectKey(project.Key) {
+			return nil, ErrInvalidProjectKey
+		}
+		if err := s.repo.Create(project); err != nil {
+			return nil, err
+		}
+		s.publishProject(EventProjectCreated, project.ID)
+		return project, nil
 	}
 
-	err := s.repo.Create(project)
-	return project, err
+	// Сгенерированный ключ может успеть занять параллельный запрос — тогда подбираем заново
+	for attempt := 0; ; attempt++ {
+		key, err := s.repo.AvailableKey(models.ProjectKeyBase(project.Name))
+		if err != nil {
+			return nil, err
+		}
+		project.Key = key
+		err = s.repo.Create(project)
+		if errors.Is(err, repository.ErrProjectKeyTaken) && attempt < 2 {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		s.publishProject(EventProjectCreated, project.ID)
+		return project, nil
+	}
 }
 
 func (s *projectService) GetByID(id uuid.UUID) (*models.Project, error) {
@@ -56,10 +158,19 @@ func (s *projectService) GetByID(id uuid.UUID) (*models.Project, error) {
 }
 
 func (s *projectService) Update(id uuid.UUID, req UpdateProjectRequest) error {
+	return s.inTx(func(tx *projectService) error {
+		return tx.update(id, req)
+	})
+}
+
+func (s *projectService) update(id uuid.UUID, req UpdateProjectRequest) error {
 	project, err := s.repo.FindByID(id)
 	if err != nil {
 		return err
 	}
+	if err := checkVersion(project.Version, req.IfMatch); err != nil {
+		return err
+	}
 
 	if req.Name != "" {
 		project.Name = req.Name
@@ -70,15 +181,210 @@ func (s *projectService) Update(id uuid.UUID, req UpdateProjectRequest) error {
 	if req.Color != "" {
 		project.Color = req.Color
 	}
+	if req.EstimateUnit != "" {
+		if !validEstimateUnit(req.EstimateUnit) {
+			return ErrInvalidEstimateUnit
+		}
+		project.EstimateUnit = req.EstimateUnit
+	}
+	if req.WIPLimits != nil {
+		if !validWIPLimits(req.WIPLimits) {
+			return ErrInvalidWIPLimit
+		}
+		project.WIPLimits = req.WIPLimits
+	}
+	if req.WIPStrict != nil {
+		project.WIPStrict = *req.WIPStrict
+	}
 
-	return s.repo.Update(project)
+	if err := s.repo.Update(project); err != nil {
+		return err
+	}
+	s.publishProject(EventProjectUpdated, id)
+	return nil
 }
 
-func (s *projectService) Delete(id uuid.UUID) error {
-	return s.repo.Delete(id)
+// Patch применяет merge-patch к проекту. null возвращает цвет и единицу оценок к значениям
+// по умолчанию, у wip_limits null снимает лимит колонки или, для всего поля, все лимиты
+func (s *projectService) Patch(id uuid.UUID, patch dto.ProjectPatch, ifMatch *int) (*models.Project, error) {
+	var project *models.Project
+	err := s.inTx(func(tx *projectService) (err error) {
+		project, err = tx.patch(id, patch, ifMatch)
+		return err
+	})
+	return project, err
+}
+
+func (s *projectService) patch(id uuid.UUID, patch dto.ProjectPatch, ifMatch *int) (*models.Project, error) {
+	project, err := s.repo.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkVersion(project.Version, ifMatch); err != nil {
+		return nil, err
+	}
+
+	if patch.Name.Set {
+		name, err := requiredField(patch.Name, "name")
+		if err != nil {
+			return nil, err
+		}
+		if strings.TrimSpace(name) == "" {
+			return nil, fmt.Errorf("%w: name must not be empty", ErrInvalidPatch)
+		}
+		project.Name = name
+	}
+	if patch.Description.Set {
+		project.Description = valueOrZero(patch.Description)
+	}
+	if patch.Color.Set {
+		project.Color = defaultProjectColor
+		if patch.Color.Value != nil {
+			if !projectColorPattern.MatchString(*patch.Color.Value) {
+				return nil, fmt.Errorf("%w: color must look like #4f46e5", ErrInvalidPatch)
+			}
+			project.Color = *patch.Color.Value
+		}
+	}
+	if patch.EstimateUnit.Set {
+		project.EstimateUnit = models.EstimateHours
+		if patch.EstimateUnit.Value != nil {
+			if !validEstimateUnit(*patch.EstimateUnit.Value) {
+				return nil, ErrInvalidEstimateUnit
+			}
+			project.EstimateUnit = *patch.EstimateUnit.Value
+		}
+	}
+	if patch.WIPLimits.Set {
+		limits := map[models.TaskStatus]int{}
+		if patch.WIPLimits.Value != nil {
+			maps.Copy(limits, project.WIPLimits)
+			for status, limit := range *patch.WIPLimits.Value {
+				if limit == nil {
+					delete(limits, status)
+					continue
+				}
+				limits[status] = *limit
+			}
+		}
+		if !validWIPLimits(limits) {
+			return nil, ErrInvalidWIPLimit
+		}
+		project.WIPLimits = limits
+	}
+	if patch.WIPStrict.Set {
+		project.WIPStrict = valueOrZero(patch.WIPStrict)
+	}
+
+	if err := s.repo.Update(project); err != nil {
+		return nil, err
+	}
+	s.publishProject(EventProjectUpdated, id)
+	return s.repo.FindByID(id)
+}
+
+// Board собирает доску проекта за один запрос: колонки по статусам с числом видимых задач,
+// лимитами и первыми perColumn задачами в порядке ранга
+func (s *projectService) Board(id, userID uuid.UUID, perColumn int) (*dto.Board, error) {
+	if perColumn <= 0 {
+		perColumn = defaultBoardColumnSize
+	}
+	perColumn = min(perColumn, maxBoardColumnSize)
+
+	visible, err := s.repo.IsVisible(id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !visible {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	project, err := s.repo.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+	project.Tasks = nil // задачи идут по колонкам
+
+	tasks, err := s.tasks.BoardTasks(id, userID, perColumn)
+	if err != nil {
+		return nil, err
+	}
+	counts, err := s.tasks.BoardCounts(id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	board := &dto.Board{Project: project}
+	for _, status := range models.TaskStatuses {
+		column := dto.BoardColumn{Status: status, Count: counts[status], Tasks: []models.Task{}}
+		if limit, ok := project.WIPLimits[status]; ok {
+			column.WIPLimit = &limit
+			column.OverLimit = column.Count > int64(limit)
+		}
+		for _, task := range tasks {
+			if task.Status == status {
+				column.Tasks = append(column.Tasks, task)
+			}
+		}
+		board.Columns = append(board.Columns, column)
+	}
+	return board, nil
+}
+
+func validWIPLimits(limits map[models.TaskStatus]int) bool {
+	for status, limit := range limits {
+		if !slices.Contains(models.TaskStatuses, status) || limit < 1 {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *projectService) Delete(id uuid.UUID, ifMatch *int) error {
+	if err := s.inTx(func(tx *projectService) error {
+		return tx.delete(id, ifMatch)
+	}); err != nil {
+		return err
+	}
+	logCleanupError(s.blobs.DeleteOrphanBlobs(context.Background()))
+	return nil
+}
+
+func (s *projectService) delete(id uuid.UUID, ifMatch *int) error {
+	if ifMatch != nil {
+		project, err := s.repo.FindByID(id)
+		if err != nil {
+			return err
+		}
+		if err := checkVersion(project.Version, ifMatch); err != nil {
+			return err
+		}
+	}
+	// После удаления узнать, кто видел проект, уже не получится
+	audience, err := s.repo.ViewerIDs(id)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.Delete(id, ifMatch); err != nil {
+		return err
+	}
+	s.events.Publish(newEvent(EventProjectDeleted, &id, audience, map[string]any{"id": id}))
+	return nil
+}
+
+// List возвращает страницу проектов вместе с закреплёнными представлениями для боковой панели
+func (s *projectService) List(userID uuid.UUID, page dto.PageRequest) (*dto.ProjectListResponse, error) {
+	projects, err := s.repo.List(userID, page)
+	if err != nil {
+		return nil, err
+	}
+	pinned, err := s.views.ListVisible(userID, nil, true)
+	if err != nil {
+		return nil, err
+	}
+	return &dto.ProjectListResponse{ListResponse: projects, PinnedViews: pinned}, nil
 }
 
-func (s *projectService) List(userID uuid.UUID, page, limit int) ([]dto.ListProjectsResponse, error) {
-	offset := (page - 1) * limit
-	return s.repo.List(userID, limit, offset)
+func validEstimateUnit(unit models.EstimateUnit) bool {
+	return unit == models.EstimateHours || unit == models.EstimatePoints
 }
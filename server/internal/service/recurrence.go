@@ -0,0 +1,118 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"task-tracker/internal/models"
+	"task-tracker/pkg/rrule"
+	"time"
+)
+
+var (
+	ErrInvalidRecurrence      = errors.New("invalid recurrence rule")
+	ErrRecurrenceNeedsDueDate = errors.New("recurring task must have a due date")
+	ErrNotRecurring           = errors.New("task is not recurring")
+	ErrNoNextOccurrence       = errors.New("recurrence has no further occurrences")
+)
+
+// setRecurrence валидирует правило и делает текущий срок задачи началом серии
+func setRecurrence(task *models.Task, rule, timezone string) error {
+	if task.DueDate == nil {
+		return ErrRecurrenceNeedsDueDate
+	}
+	parsed, err := rrule.Parse(rule)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidRecurrence, err)
+	}
+	if timezone == "" {
+		timezone = "UTC"
+	}
+	if _, err := time.LoadLocation(timezone); err != nil {
+		return fmt.Errorf("%w: unknown timezone %q", ErrInvalidRecurrence, timezone)
+	}
+
+	start := *task.DueDate
+	task.Recurrence = parsed.String()
+	task.RecurrenceTimezone = timezone
+	task.RecurrenceStart = &start
+	return nil
+}
+
+func clearRecurrence(task *models.Task) {
+	task.Recurrence = ""
+	task.RecurrenceTimezone = ""
+	task.RecurrenceStart = nil
+}
+
+// nextOccurrence возвращает срок следующего вхождения после текущего срока задачи
+func nextOccurrence(task *models.Task) (*time.Time, error) {
+	if task.Recurrence == "" {
+		return nil, ErrNotRecurring
+	}
+	if task.DueDate == nil {
+		return nil, ErrRecurrenceNeedsDueDate
+	}
+	rule, err := rrule.Parse(task.Recurrence)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidRecurrence, err)
+	}
+	loc := time.UTC
+	if task.RecurrenceTimezone != "" {
+		if loc, err = time.LoadLocation(task.RecurrenceTimezone); err != nil {
+			return nil, fmt.Errorf("%w: unknown timezone %q", ErrInvalidRecurrence, task.RecurrenceTimezone)
+		}
+	}
+
+	start := *task.DueDate
+	if task.RecurrenceStart != nil {
+		start = *task.RecurrenceStart
+	}
+
+	// Срок без времени хранится полночью UTC: правило считаем по этой же дате в поясе правила
+	// и возвращаем снова датой, иначе смещение пояса уводит вхождения на соседний день
+	if isDateOnly(start) && isDateOnly(*task.DueDate) {
+		next, ok := rule.Next(localDate(start, loc), localDate(*task.DueDate, loc))
+		if !ok {
+			return nil, nil
+		}
+		y, m, d := next.Date()
+		due := time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+		return &due, nil
+	}
+
+	next, ok := rule.Next(start.In(loc), task.DueDate.In(loc))
+	if !ok {
+		return nil, nil
+	}
+	return &next, nil
+}
+
+func isDateOnly(t time.Time) bool {
+	t = t.UTC()
+	return t.Hour() == 0 && t.Minute() == 0 && t.Second() == 0 && t.Nanosecond() == 0
+}
+
+// localDate переносит календарную дату из UTC в полночь того же дня в loc
+func localDate(t time.Time, loc *time.Location) time.Time {
+	y, m, d := t.UTC().Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, loc)
+}
+
+// nextTaskInSeries создаёт следующее вхождение, перенося на него правило повторения
+func nextTaskInSeries(task *models.Task, dueDate time.Time) *models.Task {
+	return &models.Task{
+		Title:              task.Title,
+		Description:        task.Description,
+		Priority:           task.Priority,
+		Status:             models.StatusTodo,
+		DueDate:            &dueDate,
+		UserID:             task.UserID,
+		ProjectID:          task.ProjectID,
+		ParentID:           task.ParentID,
+		Estimate:           task.Estimate,
+		RemainingEstimate:  task.Estimate,
+		Recurrence:         task.Recurrence,
+		RecurrenceTimezone: task.RecurrenceTimezone,
+		RecurrenceStart:    task.RecurrenceStart,
+	}
+}
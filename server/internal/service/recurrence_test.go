@@ -0,0 +1,205 @@
+package service
+
+import (
+	"errors"
+	"github.com/google/uuid"
+	"task-tracker/internal/models"
+	"testing"
+	"time"
+)
+
+func date(y int, m time.Month, d int) *time.Time {
+	t := time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+	return &t
+}
+
+func recurringTask(t *testing.T, rule, timezone string, due *time.Time) *models.Task {
+	t.Helper()
+	task := &models.Task{ID: uuid.New(), Title: "recurring", Status: models.StatusTodo, DueDate: due}
+	if err := setRecurrence(task, rule, timezone); err != nil {
+		t.Fatalf("setRecurrence(%q, %q): %v", rule, timezone, err)
+	}
+	return task
+}
+
+func TestNextOccurrenceDateOnly(t *testing.T) {
+	tests := []struct {
+		name     string
+		rule     string
+		timezone string
+		due      *time.Time
+		want     []string
+	}{
+		{
+			name:     "weekly Monday west of UTC across DST",
+			rule:     "FREQ=WEEKLY;BYDAY=MO",
+			timezone: "America/New_York",
+			due:      date(2026, 3, 2),
+			want:     []string{"2026-03-09", "2026-03-16", "2026-03-23"},
+		},
+		{
+			name:     "weekly Monday east of UTC across DST",
+			rule:     "FREQ=WEEKLY;BYDAY=MO",
+			timezone: "Asia/Tokyo",
+			due:      date(2026, 3, 2),
+			want:     []string{"2026-03-09", "2026-03-16"},
+		},
+		{
+			name:     "daily across the European transition",
+			rule:     "FREQ=DAILY",
+			timezone: "Europe/Berlin",
+			due:      date(2026, 3, 28),
+			want:     []string{"2026-03-29", "2026-03-30", "2026-03-31"},
+		},
+		{
+			name:     "month end across fall back",
+			rule:     "FREQ=MONTHLY;BYMONTHDAY=-1",
+			timezone: "America/Los_Angeles",
+			due:      date(2026, 10, 31),
+			want:     []string{"2026-11-30", "2026-12-31", "2027-01-31", "2027-02-28"},
+		},
+		{
+			name:     "BYMONTHDAY=31 from a far-east zone",
+			rule:     "FREQ=MONTHLY;BYMONTHDAY=31",
+			timezone: "Pacific/Kiritimati",
+			due:      date(2026, 1, 31),
+			want:     []string{"2026-03-31", "2026-05-31"},
+		},
+		{
+			name:     "UNTIL date is inclusive",
+			rule:     "FREQ=DAILY;UNTIL=20260310",
+			timezone: "America/New_York",
+			due:      date(2026, 3, 7),
+			want:     []string{"2026-03-08", "2026-03-09", "2026-03-10"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := time.LoadLocation(tt.timezone); err != nil {
+				t.Skipf("timezone %s is unavailable", tt.timezone)
+			}
+			task := recurringTask(t, tt.rule, tt.timezone, tt.due)
+			for i, want := range tt.want {
+				next, err := nextOccurrence(task)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if next == nil {
+					t.Fatalf("occurrence %d: series ended, want %s", i, want)
+				}
+				if got := next.Format(time.RFC3339); got != want+"T00:00:00Z" {
+					t.Fatalf("occurrence %d = %s, want %sT00:00:00Z", i, got, want)
+				}
+				task.DueDate = next
+			}
+		})
+	}
+}
+
+func TestNextOccurrenceEndOfSeries(t *testing.T) {
+	task := recurringTask(t, "FREQ=DAILY;COUNT=2", "Europe/Berlin", date(2026, 3, 28))
+	next, err := nextOccurrence(task)
+	if err != nil || next == nil {
+		t.Fatalf("first next = %v, %v", next, err)
+	}
+	task.DueDate = next
+	if next, err = nextOccurrence(task); err != nil || next != nil {
+		t.Errorf("after COUNT = %v, %v; want nil, nil", next, err)
+	}
+}
+
+func TestNextOccurrenceKeepsTimeOfDay(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip("timezone America/New_York is unavailable")
+	}
+	due := time.Date(2026, 3, 6, 9, 0, 0, 0, ny).UTC()
+	task := recurringTask(t, "FREQ=WEEKLY", "America/New_York", &due)
+
+	next, err := nextOccurrence(task)
+	if err != nil || next == nil {
+		t.Fatalf("nextOccurrence = %v, %v", next, err)
+	}
+	if local := next.In(ny); local.Format("2006-01-02 15:04") != "2026-03-13 09:00" {
+		t.Errorf("next = %s, want 2026-03-13 09:00 local", local)
+	}
+}
+
+func TestNextOccurrenceErrors(t *testing.T) {
+	if _, err := nextOccurrence(&models.Task{DueDate: date(2026, 1, 1)}); !errors.Is(err, ErrNotRecurring) {
+		t.Errorf("non-recurring error = %v", err)
+	}
+	if err := setRecurrence(&models.Task{}, "FREQ=DAILY", ""); !errors.Is(err, ErrRecurrenceNeedsDueDate) {
+		t.Errorf("no due date error = %v", err)
+	}
+	if err := setRecurrence(&models.Task{DueDate: date(2026, 1, 1)}, "FREQ=HOURLY", ""); !errors.Is(err, ErrInvalidRecurrence) {
+		t.Errorf("bad rule error = %v", err)
+	}
+	if err := setRecurrence(&models.Task{DueDate: date(2026, 1, 1)}, "FREQ=DAILY", "Mars/Olympus"); !errors.Is(err, ErrInvalidRecurrence) {
+		t.Errorf("bad timezone error = %v", err)
+	}
+}
+
+func TestSkipOccurrence(t *testing.T) {
+	if _, err := time.LoadLocation("America/New_York"); err != nil {
+		t.Skip("timezone America/New_York is unavailable")
+	}
+	task := recurringTask(t, "FREQ=WEEKLY;BYDAY=MO;COUNT=3", "America/New_York", date(2026, 3, 2))
+	repo := newFakeTaskRepo(task)
+	svc := NewTaskService(repo, nil, nil)
+
+	for _, want := range []string{"2026-03-09", "2026-03-16"} {
+		skipped, err := svc.SkipOccurrence(task.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := skipped.DueDate.Format("2006-01-02"); got != want {
+			t.Errorf("due date = %s, want %s", got, want)
+		}
+		if skipped.Recurrence == "" || !skipped.RecurrenceStart.Equal(*date(2026, 3, 2)) {
+			t.Errorf("recurrence was not kept: %q from %v", skipped.Recurrence, skipped.RecurrenceStart)
+		}
+	}
+	if len(repo.events) != 2 || repo.events[0].Type != EventTaskUpdated {
+		t.Errorf("events = %+v, want two %s", repo.events, EventTaskUpdated)
+	}
+
+	if _, err := svc.SkipOccurrence(task.ID); !errors.Is(err, ErrNoNextOccurrence) {
+		t.Errorf("skip past COUNT error = %v, want ErrNoNextOccurrence", err)
+	}
+	if stored := repo.tasks[task.ID]; stored.DueDate.Format("2006-01-02") != "2026-03-16" {
+		t.Errorf("due date changed by failed skip: %s", stored.DueDate)
+	}
+}
+
+func TestCompletingRecurringTaskCreatesNextOccurrence(t *testing.T) {
+	if _, err := time.LoadLocation("America/New_York"); err != nil {
+		t.Skip("timezone America/New_York is unavailable")
+	}
+	task := recurringTask(t, "FREQ=WEEKLY;BYDAY=MO", "America/New_York", date(2026, 3, 2))
+	repo := newFakeTaskRepo(task)
+	svc := &taskService{repo: repo, events: &pendingEvents{}}
+
+	task.Status = models.StatusDone
+	if err := svc.save(task, false); err != nil {
+		t.Fatal(err)
+	}
+	if task.Recurrence != "" || task.CompletedAt == nil {
+		t.Errorf("completed task keeps recurrence %q, completed at %v", task.Recurrence, task.CompletedAt)
+	}
+	if len(repo.tasks) != 2 {
+		t.Fatalf("tasks = %d, want the next occurrence created", len(repo.tasks))
+	}
+	for id, next := range repo.tasks {
+		if id == task.ID {
+			continue
+		}
+		if next.DueDate.Format(time.RFC3339) != "2026-03-09T00:00:00Z" || next.Status != models.StatusTodo {
+			t.Errorf("next occurrence due %s with status %s", next.DueDate.Format(time.RFC3339), next.Status)
+		}
+		if next.Recurrence != "FREQ=WEEKLY;BYDAY=MO" || next.RecurrenceTimezone != "America/New_York" {
+			t.Errorf("next occurrence recurrence = %q in %q", next.Recurrence, next.RecurrenceTimezone)
+		}
+	}
+}
@@ -0,0 +1,319 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"log"
+	"slices"
+	"strings"
+	"task-tracker/internal/dto"
+	"task-tracker/internal/models"
+	"task-tracker/internal/repository"
+	"task-tracker/pkg/mail"
+	"time"
+)
+
+var ErrInvalidReminderPreferences = errors.New("invalid reminder preferences")
+
+const (
+	// Сколько задач попадает в одно напоминание или дайджест
+	maxReminderTasks = 100
+	// Отдельные напоминания о просрочке уходят только по задачам, просроченным не дольше
+	// этого, — чтобы включившему напоминания не пришла пачка писем о давних задачах
+	overdueReminderDays = 7
+	maxReminderDays     = 30
+
+	// Сколько хранить отметки об отправке: дольше, чем напоминание о задаче может повториться
+	SentNotificationRetention = 60 * 24 * time.Hour
+)
+
+var reminderModes = []models.ReminderMode{models.RemindersOff, models.RemindersIndividual, models.RemindersDigest}
+
+type UpdateReminderPreferencesRequest struct {
+	Mode       models.ReminderMode
+	DaysBefore int
+	RemindAt   string
+	Timezone   string
+	Overdue    bool
+}
+
+type ReminderService interface {
+	// Preferences возвращает настройки; пока пользователь их не менял — выключенные по умолчанию
+	Preferences(userID uuid.UUID) (*models.NotificationPreferences, error)
+	UpdatePreferences(userID uuid.UUID, req UpdateReminderPreferencesRequest) (*models.NotificationPreferences, error)
+	// SendDue рассылает напоминания, время которых наступило к now; возвращает число писем
+	SendDue(ctx context.Context, now time.Time) (int, error)
+	PurgeSent() (int64, error)
+}
+
+type reminderService struct {
+	repo   repository.NotificationRepository
+	tasks  repository.TaskRepository
+	mailer mail.Mailer
+}
+
+func NewReminderService(repo repository.NotificationRepository, tasks repository.TaskRepository, mailer mail.Mailer) ReminderService {
+	return &reminderService{repo: repo, tasks: tasks, mailer: mailer}
+}
+
+func defaultReminderPreferences(userID uuid.UUID) *models.NotificationPreferences {
+	return &models.NotificationPreferences{
+		UserID:     userID,
+		Mode:       models.RemindersOff,
+		DaysBefore: 1,
+		RemindAt:   "09:00",
+		Timezone:   "UTC",
+		Overdue:    true,
+	}
+}
+
+func (s *reminderService) Preferences(userID uuid.UUID) (*models.NotificationPreferences, error) {
+	prefs, err := s.repo.FindPreferences(userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return defaultReminderPreferences(userID), nil
+	}
+	return prefs, err
+}
+
+func (s *reminderService) UpdatePreferences(userID uuid.UUID, req UpdateReminderPreferencesRequest) (*models.NotificationPreferences, error) {
+	if req.Timezone == "" {
+		req.Timezone = "UTC"
+	}
+	switch {
+	case !slices.Contains(reminderModes, req.Mode):
+		return nil, fmt.Errorf("%w: mode must be one of off, individual, digest", ErrInvalidReminderPreferences)
+	case req.DaysBefore < 0 || req.DaysBefore > maxReminderDays:
+		return nil, fmt.Errorf("%w: days_before must be between 0 and %d", ErrInvalidReminderPreferences, maxReminderDays)
+	}
+	if _, err := parseRemindAt(req.RemindAt); err != nil {
+		return nil, fmt.Errorf("%w: remind_at must be HH:MM", ErrInvalidReminderPreferences)
+	}
+	if _, err := time.LoadLocation(req.Timezone); err != nil {
+		return nil, fmt.Errorf("%w: unknown timezone %q", ErrInvalidReminderPreferences, req.Timezone)
+	}
+
+	prefs := &models.NotificationPreferences{
+		UserID:     userID,
+		Mode:       req.Mode,
+		DaysBefore: req.DaysBefore,
+		RemindAt:   req.RemindAt,
+		Timezone:   req.Timezone,
+		Overdue:    req.Overdue,
+	}
+	if err := s.repo.SavePreferences(prefs); err != nil {
+		return nil, err
+	}
+	return s.repo.FindPreferences(userID)
+}
+
+// parseRemindAt переводит "09:00" в минуты от начала суток
+func parseRemindAt(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, err
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// SendDue вызывается по расписанию. Напоминания уходят, когда у пользователя наступило
+// время RemindAt, и не раньше чем за DaysBefore дней до срока. Каждое отмечается до
+// отправки: повторный запуск его не повторит
+func (s *reminderService) SendDue(ctx context.Context, now time.Time) (int, error) {
+	prefs, err := s.repo.ListEnabled()
+	if err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	var errs []error
+	for _, p := range prefs {
+		if ctx.Err() != nil {
+			return sent, ctx.Err()
+		}
+		n, err := s.sendForUser(ctx, p, now)
+		sent += n
+		if err != nil {
+			errs = append(errs, fmt.Errorf("user %s: %w", p.UserID, err))
+		}
+	}
+	return sent, errors.Join(errs...)
+}
+
+func (s *reminderService) sendForUser(ctx context.Context, p models.NotificationPreferences, now time.Time) (int, error) {
+	loc, err := time.LoadLocation(p.Timezone)
+	if err != nil {
+		return 0, err
+	}
+	remindAt, err := parseRemindAt(p.RemindAt)
+	if err != nil {
+		return 0, err
+	}
+	local := now.In(loc)
+	if local.Hour()*60+local.Minute() < remindAt || p.User == nil {
+		return 0, nil
+	}
+
+	today := DueDateToday(now, loc)
+	// Ближайшие и просроченные задачи выбираются отдельно, каждые не больше maxReminderTasks:
+	// иначе пачка давних просрочек вытеснила бы из дайджеста задачи с близким сроком
+	tasks, err := s.dueTasks(p.UserID, &today, today.AddDate(0, 0, p.DaysBefore+1))
+	if err != nil {
+		return 0, err
+	}
+	if p.Overdue {
+		var since *time.Time
+		if p.Mode == models.RemindersIndividual {
+			from := today.AddDate(0, 0, -overdueReminderDays)
+			since = &from
+		}
+		overdue, err := s.dueTasks(p.UserID, since, today)
+		if err != nil {
+			return 0, err
+		}
+		tasks = append(overdue, tasks...)
+	}
+	if len(tasks) == 0 {
+		return 0, nil
+	}
+
+	if p.Mode == models.RemindersDigest {
+		key := "digest:" + today.Format(time.DateOnly)
+		return s.send(ctx, p.User, key, digestMessage(p.User.Email, tasks, today))
+	}
+
+	sent := 0
+	for i := range tasks {
+		task := &tasks[i]
+		due := dueDay(*task.DueDate)
+		kind := "due"
+		if due.Before(today) {
+			kind = "overdue"
+		}
+		// Срок в ключе: после переноса срока задача напомнит о себе снова
+		key := fmt.Sprintf("%s:%s:%s", kind, task.ID, due.Format(time.DateOnly))
+		n, err := s.send(ctx, p.User, key, reminderMessage(p.User.Email, task, today))
+		sent += n
+		if err != nil {
+			return sent, err
+		}
+	}
+	return sent, nil
+}
+
+// dueTasks возвращает открытые задачи пользователя со сроком в [from, to) по порядку сроков;
+// from == nil — без нижней границы
+func (s *reminderService) dueTasks(userID uuid.UUID, from *time.Time, to time.Time) ([]models.Task, error) {
+	tasks, err := s.tasks.List(dto.TaskFilter{
+		UserID:   &userID,
+		Statuses: []models.TaskStatus{models.StatusTodo, models.StatusInProgress},
+		DueFrom:  from,
+		DueTo:    &to,
+		Sort:     []dto.SortField{{Field: "due_date"}, {Field: "priority", Desc: true}},
+	}, dto.PageRequest{Limit: maxReminderTasks})
+	if err != nil {
+		return nil, err
+	}
+	return tasks.Items, nil
+}
+
+// send отмечает напоминание и отправляет его; если письмо не ушло, отметка снимается
+func (s *reminderService) send(ctx context.Context, user *models.User, key string, msg mail.Message) (int, error) {
+	marked, err := s.repo.MarkSent(user.ID, key)
+	if err != nil || !marked {
+		return 0, err
+	}
+	if err := s.mailer.Send(ctx, msg); err != nil {
+		if unmarkErr := s.repo.UnmarkSent(user.ID, key); unmarkErr != nil {
+			log.Printf("reminder %s for user %s stays marked as sent: %v", key, user.ID, unmarkErr)
+		}
+		return 0, err
+	}
+	return 1, nil
+}
+
+func (s *reminderService) PurgeSent() (int64, error) {
+	return s.repo.PurgeSent(time.Now().Add(-SentNotificationRetention))
+}
+
+// dueDay — календарная дата срока (сроки хранятся полуночью UTC)
+func dueDay(due time.Time) time.Time {
+	due = due.UTC()
+	return time.Date(due.Year(), due.Month(), due.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func reminderMessage(to string, task *models.Task, today time.Time) mail.Message {
+	when := dueWhen(dueDay(*task.DueDate), today)
+	subject := fmt.Sprintf("Reminder: %s is due %s", taskLabel(task), when)
+	if dueDay(*task.DueDate).Before(today) {
+		subject = fmt.Sprintf("Overdue: %s was due %s", taskLabel(task), when)
+	}
+
+	var b strings.Builder
+	b.WriteString(subject + "\n\n")
+	writeTaskLine(&b, task, today)
+	return mail.Message{To: to, Subject: subject, Text: b.String()}
+}
+
+func digestMessage(to string, tasks []models.Task, today time.Time) mail.Message {
+	var overdue, upcoming []*models.Task
+	for i := range tasks {
+		if dueDay(*tasks[i].DueDate).Before(today) {
+			overdue = append(overdue, &tasks[i])
+		} else {
+			upcoming = append(upcoming, &tasks[i])
+		}
+	}
+
+	var b strings.Builder
+	if len(overdue) > 0 {
+		fmt.Fprintf(&b, "Overdue (%d):\n", len(overdue))
+		for _, t := range overdue {
+			writeTaskLine(&b, t, today)
+		}
+		b.WriteString("\n")
+	}
+	if len(upcoming) > 0 {
+		fmt.Fprintf(&b, "Due soon (%d):\n", len(upcoming))
+		for _, t := range upcoming {
+			writeTaskLine(&b, t, today)
+		}
+	}
+	return mail.Message{
+		To:      to,
+		Subject: fmt.Sprintf("Your tasks for %s: %d due soon, %d overdue", today.Format("Jan 2"), len(upcoming), len(overdue)),
+		Text:    b.String(),
+	}
+}
+
+func writeTaskLine(b *strings.Builder, task *models.Task, today time.Time) {
+	fmt.Fprintf(b, "- %s — due %s, %s priority", taskLabel(task), dueWhen(dueDay(*task.DueDate), today), task.Priority)
+	if task.Project != nil {
+		fmt.Fprintf(b, ", project %s", task.Project.Name)
+	}
+	b.WriteString("\n")
+}
+
+// taskLabel — ключ и название задачи в одну строку
+func taskLabel(task *models.Task) string {
+	title := strings.Join(strings.Fields(task.Title), " ")
+	if task.Key != "" {
+		return task.Key + " " + title
+	}
+	return title
+}
+
+func dueWhen(due, today time.Time) string {
+	switch days := int(due.Sub(today).Hours() / 24); days {
+	case 0:
+		return "today"
+	case 1:
+		return "tomorrow"
+	case -1:
+		return "yesterday"
+	default:
+		return "on " + due.Format("Mon, Jan 2")
+	}
+}
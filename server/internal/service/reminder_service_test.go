@@ -0,0 +1,235 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"github.com/google/uuid"
+	"mime"
+	netmail "net/mail"
+	"os"
+	"path/filepath"
+	"slices"
+	"task-tracker/internal/models"
+	"task-tracker/internal/repository"
+	"task-tracker/pkg/mail"
+	"testing"
+	"time"
+)
+
+// fakeNotificationRepo помнит отметки об отправке, как таблица sent_notifications
+type fakeNotificationRepo struct {
+	repository.NotificationRepository
+	sent map[string]bool
+}
+
+func (r *fakeNotificationRepo) MarkSent(userID uuid.UUID, key string) (bool, error) {
+	if r.sent[userID.String()+"/"+key] {
+		return false, nil
+	}
+	r.sent[userID.String()+"/"+key] = true
+	return true, nil
+}
+
+func (r *fakeNotificationRepo) UnmarkSent(userID uuid.UUID, key string) error {
+	delete(r.sent, userID.String()+"/"+key)
+	return nil
+}
+
+type reminderFixture struct {
+	svc   *reminderService
+	user  *models.User
+	tasks *fakeTaskRepo
+	dir   string
+	// read — сколько писем из каталога уже проверено
+	read int
+}
+
+func newReminderFixture(t *testing.T) *reminderFixture {
+	dir := t.TempDir()
+	mailer, err := mail.NewFileMailer(dir, "Task Tracker <noreply@example.com>")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tasks := newFakeTaskRepo()
+	user := &models.User{ID: uuid.New(), Email: "ann@example.com"}
+	svc := NewReminderService(&fakeNotificationRepo{sent: map[string]bool{}}, tasks, mailer).(*reminderService)
+	return &reminderFixture{svc: svc, user: user, tasks: tasks, dir: dir}
+}
+
+func (f *reminderFixture) addTask(title, due string, status models.TaskStatus) {
+	f.addTaskFor(f.user.ID, title, due, status)
+}
+
+func (f *reminderFixture) addTaskFor(userID uuid.UUID, title, due string, status models.TaskStatus) {
+	task := &models.Task{ID: uuid.New(), UserID: userID, Title: title, Status: status, Priority: models.PriorityMedium, DueDate: day(due)}
+	f.tasks.tasks[task.ID] = task
+}
+
+func (f *reminderFixture) prefs(mode models.ReminderMode, daysBefore int, overdue bool) models.NotificationPreferences {
+	return models.NotificationPreferences{
+		UserID:     f.user.ID,
+		User:       f.user,
+		Mode:       mode,
+		DaysBefore: daysBefore,
+		RemindAt:   "09:00",
+		Timezone:   "UTC",
+		Overdue:    overdue,
+	}
+}
+
+// newMail возвращает темы писем, записанных после прошлого вызова
+func (f *reminderFixture) newMail(t *testing.T) []string {
+	t.Helper()
+	files, err := filepath.Glob(filepath.Join(f.dir, "*.eml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	slices.Sort(files)
+	var subjects []string
+	for _, name := range files[f.read:] {
+		file, err := os.Open(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		msg, err := netmail.ReadMessage(file)
+		file.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if to := msg.Header.Get("To"); to != f.user.Email {
+			t.Errorf("mail to %q, want %q", to, f.user.Email)
+		}
+		subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		subjects = append(subjects, subject)
+	}
+	f.read = len(files)
+	slices.Sort(subjects)
+	return subjects
+}
+
+func (f *reminderFixture) send(t *testing.T, p models.NotificationPreferences, now time.Time) int {
+	t.Helper()
+	n, err := f.svc.sendForUser(context.Background(), p, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+// 10 марта 2026 года, вторник, 9:00 UTC
+var reminderNow = time.Date(2026, 3, 10, 9, 0, 0, 0, time.UTC)
+
+func TestRemindersIndividual(t *testing.T) {
+	f := newReminderFixture(t)
+	f.addTask("Today", "2026-03-10", models.StatusTodo)
+	f.addTask("In two days", "2026-03-12", models.StatusInProgress)
+	f.addTask("Past the horizon", "2026-03-13", models.StatusTodo)
+	f.addTask("Overdue", "2026-03-05", models.StatusTodo)
+	f.addTask("Long overdue", "2026-02-20", models.StatusTodo)
+	f.addTask("Done", "2026-03-10", models.StatusDone)
+	f.addTaskFor(uuid.New(), "Someone else's", "2026-03-10", models.StatusTodo)
+
+	p := f.prefs(models.RemindersIndividual, 2, true)
+	if n := f.send(t, p, reminderNow.Add(-time.Minute)); n != 0 {
+		t.Fatalf("sent %d before remind_at, want 0", n)
+	}
+
+	if n := f.send(t, p, reminderNow); n != 3 {
+		t.Errorf("sent %d, want 3", n)
+	}
+	// Давняя просрочка не попадает в окно отдельных напоминаний, задача за горизонтом — тоже
+	assertEqual(t, f.newMail(t), []string{
+		"Overdue: Overdue was due on Thu, Mar 5",
+		"Reminder: In two days is due on Thu, Mar 12",
+		"Reminder: Today is due today",
+	})
+
+	// Повторный запуск в тот же день ничего не шлёт
+	if n := f.send(t, p, reminderNow.Add(5*time.Minute)); n != 0 {
+		t.Errorf("second run sent %d, want 0", n)
+	}
+	if mails := f.newMail(t); len(mails) != 0 {
+		t.Errorf("second run wrote %v", mails)
+	}
+
+	// Назавтра приходят только новое: задача, вошедшая в горизонт, и однократное
+	// напоминание о просрочке вчерашней; об остальных уже напоминали
+	if n := f.send(t, p, reminderNow.AddDate(0, 0, 1)); n != 2 {
+		t.Errorf("next day sent %d, want 2", n)
+	}
+	assertEqual(t, f.newMail(t), []string{
+		"Overdue: Today was due yesterday",
+		"Reminder: Past the horizon is due on Fri, Mar 13",
+	})
+}
+
+func TestRemindersWithoutOverdue(t *testing.T) {
+	f := newReminderFixture(t)
+	f.addTask("Today", "2026-03-10", models.StatusTodo)
+	f.addTask("Overdue", "2026-03-09", models.StatusTodo)
+
+	if n := f.send(t, f.prefs(models.RemindersIndividual, 0, false), reminderNow); n != 1 {
+		t.Errorf("sent %d, want 1", n)
+	}
+	assertEqual(t, f.newMail(t), []string{"Reminder: Today is due today"})
+}
+
+func TestRemindersDigest(t *testing.T) {
+	f := newReminderFixture(t)
+	f.addTask("Today", "2026-03-10", models.StatusTodo)
+	f.addTask("Tomorrow", "2026-03-11", models.StatusTodo)
+	f.addTask("Past the horizon", "2026-03-12", models.StatusTodo)
+	f.addTask("Overdue", "2026-03-05", models.StatusTodo)
+	f.addTask("Long overdue", "2026-02-20", models.StatusTodo)
+
+	p := f.prefs(models.RemindersDigest, 1, true)
+	if n := f.send(t, p, reminderNow); n != 1 {
+		t.Fatalf("sent %d, want one digest", n)
+	}
+	// В дайджест попадают все просроченные задачи, без окна
+	assertEqual(t, f.newMail(t), []string{"Your tasks for Mar 10: 2 due soon, 2 overdue"})
+
+	if n := f.send(t, p, reminderNow.Add(time.Hour)); n != 0 {
+		t.Errorf("second digest the same day: sent %d, want 0", n)
+	}
+	if n := f.send(t, p, reminderNow.AddDate(0, 0, 1)); n != 1 {
+		t.Errorf("next day sent %d, want a new digest", n)
+	}
+	assertEqual(t, f.newMail(t), []string{"Your tasks for Mar 11: 2 due soon, 3 overdue"})
+}
+
+func TestRemindersDigestKeepsUpcomingBehindManyOverdue(t *testing.T) {
+	f := newReminderFixture(t)
+	for i := range maxReminderTasks + 20 {
+		f.addTask(fmt.Sprintf("Stale %d", i), reminderNow.AddDate(-1, 0, -i).Format(time.DateOnly), models.StatusTodo)
+	}
+	f.addTask("Today", "2026-03-10", models.StatusTodo)
+
+	if n := f.send(t, f.prefs(models.RemindersDigest, 0, true), reminderNow); n != 1 {
+		t.Fatalf("sent %d, want one digest", n)
+	}
+	assertEqual(t, f.newMail(t), []string{fmt.Sprintf("Your tasks for Mar 10: 1 due soon, %d overdue", maxReminderTasks)})
+}
+
+func TestRemindersUseUserCalendar(t *testing.T) {
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Skip("timezone Asia/Tokyo is unavailable")
+	}
+	f := newReminderFixture(t)
+	f.addTask("Tokyo today", "2026-03-11", models.StatusTodo)
+	p := f.prefs(models.RemindersIndividual, 0, true)
+	p.Timezone = tokyo.String()
+
+	// 23:59 UTC 10 марта — в Токио уже 11-е, но ещё 8:59
+	if n := f.send(t, p, time.Date(2026, 3, 10, 23, 59, 0, 0, time.UTC)); n != 0 {
+		t.Fatalf("sent %d before remind_at in the user's zone", n)
+	}
+	if n := f.send(t, p, time.Date(2026, 3, 11, 0, 0, 0, 0, time.UTC)); n != 1 {
+		t.Fatalf("sent %d at 9:00 in the user's zone, want 1", n)
+	}
+	assertEqual(t, f.newMail(t), []string{"Reminder: Tokyo today is due today"})
+}
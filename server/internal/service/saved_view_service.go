@@ -0,0 +1,320 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"slices"
+	"strings"
+	"task-tracker/internal/dto"
+	"task-tracker/internal/models"
+	"task-tracker/internal/repository"
+	"task-tracker/pkg/taskql"
+	"time"
+)
+
+var (
+	ErrSavedViewNotFound = errors.New("saved view not found")
+	ErrInvalidSavedView  = errors.New("invalid saved view")
+)
+
+var viewGroupings = []models.ViewGrouping{
+	models.GroupNone, models.GroupStatus, models.GroupPriority, models.GroupProject, models.GroupDueDate,
+}
+
+// Группировка требует, чтобы задачи одной группы шли подряд: ключ группы ставится первым в сортировке
+var groupSort = map[models.ViewGrouping]dto.SortField{
+	models.GroupStatus:   {Field: "status"},
+	models.GroupPriority: {Field: "priority", Desc: true},
+	models.GroupProject:  {Field: "project_id"},
+	models.GroupDueDate:  {Field: "due_date"},
+}
+
+type CreateSavedViewRequest struct {
+	Name      string
+	ProjectID *uuid.UUID
+	Filters   models.ViewFilters
+	Sort      string
+	GroupBy   models.ViewGrouping
+	Pinned    bool
+}
+
+type UpdateSavedViewRequest struct {
+	Name    string
+	Filters *models.ViewFilters
+	Sort    *string
+	GroupBy *models.ViewGrouping
+	Pinned  *bool
+}
+
+type SavedViewService interface {
+	Create(userID uuid.UUID, req CreateSavedViewRequest) (*models.SavedView, error)
+	Get(id, userID uuid.UUID) (*models.SavedView, error)
+	Update(id, userID uuid.UUID, req UpdateSavedViewRequest) (*models.SavedView, error)
+	Delete(id, userID uuid.UUID) error
+	SetPinned(id, userID uuid.UUID, pinned bool) (*models.SavedView, error)
+	List(userID uuid.UUID, projectID *uuid.UUID) ([]models.SavedView, error)
+	Execute(id, userID uuid.UUID, loc *time.Location, page dto.PageRequest) (*dto.SavedViewResult, error)
+}
+
+type savedViewService struct {
+	repo     repository.SavedViewRepository
+	projects repository.ProjectRepository
+	tasks    TaskService
+}
+
+func NewSavedViewService(repo repository.SavedViewRepository, projects repository.ProjectRepository, tasks TaskService) SavedViewService {
+	return &savedViewService{
+		repo:     repo,
+		projects: projects,
+		tasks:    tasks,
+	}
+}
+
+func (s *savedViewService) Create(userID uuid.UUID, req CreateSavedViewRequest) (*models.SavedView, error) {
+	view := &models.SavedView{
+		Name:      strings.TrimSpace(req.Name),
+		UserID:    userID,
+		ProjectID: req.ProjectID,
+		Filters:   req.Filters,
+		Sort:      req.Sort,
+		GroupBy:   req.GroupBy,
+	}
+	if err := validateView(view); err != nil {
+		return nil, err
+	}
+
+	if view.ProjectID != nil {
+		visible, err := s.projects.IsVisible(*view.ProjectID, userID)
+		if err != nil {
+			return nil, err
+		}
+		if !visible {
+			return nil, gorm.ErrRecordNotFound
+		}
+	}
+
+	if err := s.repo.Create(view); err != nil {
+		return nil, err
+	}
+	if req.Pinned {
+		if err := s.repo.SetPinned(view.ID, userID, true); err != nil {
+			return nil, err
+		}
+		view.Pinned = true
+	}
+	return view, nil
+}
+
+func (s *savedViewService) Get(id, userID uuid.UUID) (*models.SavedView, error) {
+	view, err := s.repo.FindByID(id, userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrSavedViewNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if view.UserID == userID {
+		return view, nil
+	}
+
+	// Чужое личное представление и представление невидимого проекта не выдаём вовсе
+	if view.ProjectID == nil {
+		return nil, ErrSavedViewNotFound
+	}
+	visible, err := s.projects.IsVisible(*view.ProjectID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !visible {
+		return nil, ErrSavedViewNotFound
+	}
+	return view, nil
+}
+
+// Update и Delete доступны только автору представления
+func (s *savedViewService) Update(id, userID uuid.UUID, req UpdateSavedViewRequest) (*models.SavedView, error) {
+	view, err := s.Get(id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if view.UserID != userID {
+		return nil, ErrForbidden
+	}
+
+	if req.Name != "" {
+		view.Name = strings.TrimSpace(req.Name)
+	}
+	if req.Filters != nil {
+		view.Filters = *req.Filters
+	}
+	if req.Sort != nil {
+		view.Sort = *req.Sort
+	}
+	if req.GroupBy != nil {
+		view.GroupBy = *req.GroupBy
+	}
+	if err := validateView(view); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.Update(view); err != nil {
+		return nil, err
+	}
+	if req.Pinned != nil {
+		if err := s.repo.SetPinned(id, userID, *req.Pinned); err != nil {
+			return nil, err
+		}
+		view.Pinned = *req.Pinned
+	}
+	return view, nil
+}
+
+// SetPinned закрепляет представление у пользователя или снимает закрепление.
+// В отличие от Update, это доступно каждому, кто видит представление
+func (s *savedViewService) SetPinned(id, userID uuid.UUID, pinned bool) (*models.SavedView, error) {
+	view, err := s.Get(id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.SetPinned(id, userID, pinned); err != nil {
+		return nil, err
+	}
+	view.Pinned = pinned
+	return view, nil
+}
+
+func (s *savedViewService) Delete(id, userID uuid.UUID) error {
+	view, err := s.Get(id, userID)
+	if err != nil {
+		return err
+	}
+	if view.UserID != userID {
+		return ErrForbidden
+	}
+	return s.repo.Delete(id)
+}
+
+func (s *savedViewService) List(userID uuid.UUID, projectID *uuid.UUID) ([]models.SavedView, error) {
+	return s.repo.ListVisible(userID, projectID, false)
+}
+
+// Execute выбирает задачи по представлению. Общее представление проекта показывает
+// все видимые пользователю задачи этого проекта, а не только его собственные
+func (s *savedViewService) Execute(id, userID uuid.UUID, loc *time.Location, page dto.PageRequest) (*dto.SavedViewResult, error) {
+	view, err := s.Get(id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	filter, err := viewTaskFilter(view, userID, loc)
+	if err != nil {
+		return nil, err
+	}
+
+	tasks, err := s.tasks.List(filter, page)
+	if err != nil {
+		return nil, err
+	}
+	return &dto.SavedViewResult{View: view, ListResponse: tasks}, nil
+}
+
+func viewTaskFilter(view *models.SavedView, userID uuid.UUID, loc *time.Location) (dto.TaskFilter, error) {
+	filter, err := taskFilterFromView(view.Filters, userID, loc)
+	if err != nil {
+		return filter, err
+	}
+	if view.ProjectID != nil {
+		filter.ProjectID = view.ProjectID
+	}
+	filter.Sort = dto.ParseSort(view.Sort)
+
+	if key, ok := groupSort[view.GroupBy]; ok {
+		if len(filter.Sort) == 0 || filter.Sort[0].Field != key.Field {
+			filter.Sort = append([]dto.SortField{key}, filter.Sort...)
+		}
+	}
+	return filter, nil
+}
+
+// taskFilterFromView переводит сохраняемый фильтр в фильтр списка задач, видимых userID
+func taskFilterFromView(f models.ViewFilters, userID uuid.UUID, loc *time.Location) (dto.TaskFilter, error) {
+	filter := dto.TaskFilter{
+		ViewerID:    &userID,
+		ProjectID:   f.ProjectID,
+		NoProject:   f.NoProject,
+		Statuses:    f.Statuses,
+		Priorities:  f.Priorities,
+		Search:      f.Search,
+		EstimateMin: f.EstimateMin,
+		EstimateMax: f.EstimateMax,
+		HasEstimate: f.HasEstimate,
+		HasDueDate:  f.HasDueDate,
+		Overdue:     f.Overdue,
+		Today:       DueDateToday(time.Now(), loc),
+		Query:       f.Query,
+		Location:    loc,
+	}
+
+	var err error
+	filter.DueFrom, filter.DueTo, err = viewDueRange(f)
+	return filter, err
+}
+
+// viewDueRange переводит даты представления (включительно) в полуинтервал сроков
+func viewDueRange(f models.ViewFilters) (*time.Time, *time.Time, error) {
+	var from, to *time.Time
+	if f.DueFrom != "" {
+		t, err := time.Parse("2006-01-02", f.DueFrom)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w: due_from must be YYYY-MM-DD", ErrInvalidSavedView)
+		}
+		from = &t
+	}
+	if f.DueTo != "" {
+		t, err := time.Parse("2006-01-02", f.DueTo)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w: due_to must be YYYY-MM-DD", ErrInvalidSavedView)
+		}
+		t = t.AddDate(0, 0, 1)
+		to = &t
+	}
+	if from != nil && to != nil && !to.After(*from) {
+		return nil, nil, fmt.Errorf("%w: due_from must not be after due_to", ErrInvalidSavedView)
+	}
+	return from, to, nil
+}
+
+func validateView(view *models.SavedView) error {
+	if view.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidSavedView)
+	}
+	if !slices.Contains(viewGroupings, view.GroupBy) {
+		return fmt.Errorf("%w: group_by must be one of status, priority, project, due_date", ErrInvalidSavedView)
+	}
+
+	f := view.Filters
+	for _, status := range f.Statuses {
+		if !slices.Contains(models.TaskStatuses, status) {
+			return fmt.Errorf("%w: unknown status %q", ErrInvalidSavedView, status)
+		}
+	}
+	for _, priority := range f.Priorities {
+		if !slices.Contains(models.TaskPriorities, priority) {
+			return fmt.Errorf("%w: unknown priority %q", ErrInvalidSavedView, priority)
+		}
+	}
+	if f.ProjectID != nil && f.NoProject {
+		return fmt.Errorf("%w: project_id and no_project exclude each other", ErrInvalidSavedView)
+	}
+	if _, _, err := viewDueRange(f); err != nil {
+		return err
+	}
+	if f.Query != "" {
+		if _, err := taskql.Parse(f.Query, taskQueryFields...); err != nil {
+			return err
+		}
+	}
+	return repository.ValidateTaskSort(dto.ParseSort(view.Sort))
+}
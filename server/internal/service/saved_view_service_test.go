@@ -0,0 +1,141 @@
+package service
+
+import (
+	"errors"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"task-tracker/internal/models"
+	"testing"
+)
+
+// fakeSavedViewRepo хранит представления и личные закрепления в памяти
+type fakeSavedViewRepo struct {
+	views map[uuid.UUID]models.SavedView
+	pins  map[[2]uuid.UUID]bool
+}
+
+func newFakeSavedViewRepo() *fakeSavedViewRepo {
+	return &fakeSavedViewRepo{views: map[uuid.UUID]models.SavedView{}, pins: map[[2]uuid.UUID]bool{}}
+}
+
+func (r *fakeSavedViewRepo) Create(view *models.SavedView) error {
+	view.ID = uuid.New()
+	stored := *view
+	stored.Pinned = false
+	r.views[view.ID] = stored
+	return nil
+}
+
+func (r *fakeSavedViewRepo) FindByID(id, userID uuid.UUID) (*models.SavedView, error) {
+	view, ok := r.views[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	view.Pinned = r.pins[[2]uuid.UUID{id, userID}]
+	return &view, nil
+}
+
+func (r *fakeSavedViewRepo) Update(view *models.SavedView) error {
+	stored := *view
+	stored.Pinned = false
+	r.views[view.ID] = stored
+	return nil
+}
+
+func (r *fakeSavedViewRepo) Delete(id uuid.UUID) error {
+	delete(r.views, id)
+	return nil
+}
+
+func (r *fakeSavedViewRepo) ListVisible(userID uuid.UUID, projectID *uuid.UUID, pinnedOnly bool) ([]models.SavedView, error) {
+	var result []models.SavedView
+	for id := range r.views {
+		view, _ := r.FindByID(id, userID)
+		if pinnedOnly && !view.Pinned {
+			continue
+		}
+		result = append(result, *view)
+	}
+	return result, nil
+}
+
+func (r *fakeSavedViewRepo) SetPinned(id, userID uuid.UUID, pinned bool) error {
+	if pinned {
+		r.pins[[2]uuid.UUID{id, userID}] = true
+	} else {
+		delete(r.pins, [2]uuid.UUID{id, userID})
+	}
+	return nil
+}
+
+func TestSavedViewPinsArePerUser(t *testing.T) {
+	project := &models.Project{ID: uuid.New(), Name: "Backend"}
+	repo := newFakeSavedViewRepo()
+	svc := NewSavedViewService(repo, newFakeProjectRepo(project), nil)
+	author, member := uuid.New(), uuid.New()
+
+	view, err := svc.Create(author, CreateSavedViewRequest{Name: "Open", ProjectID: &project.ID, Pinned: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !view.Pinned {
+		t.Error("created view is not pinned for its author")
+	}
+
+	// Закрепление автора не попадает в боковую панель участника
+	if pinned, _ := repo.ListVisible(member, nil, true); len(pinned) != 0 {
+		t.Errorf("member sees %d pinned views, want 0", len(pinned))
+	}
+	got, err := svc.Get(view.ID, member)
+	if err != nil || got.Pinned {
+		t.Fatalf("member Get = %+v, %v; want unpinned", got, err)
+	}
+
+	// Участник не может менять представление, но может закрепить его у себя
+	pinned := false
+	if _, err := svc.Update(view.ID, member, UpdateSavedViewRequest{Pinned: &pinned}); !errors.Is(err, ErrForbidden) {
+		t.Errorf("member Update error = %v, want ErrForbidden", err)
+	}
+	if got, err = svc.SetPinned(view.ID, member, true); err != nil || !got.Pinned {
+		t.Fatalf("member SetPinned = %+v, %v", got, err)
+	}
+	if got, err = svc.SetPinned(view.ID, author, false); err != nil || got.Pinned {
+		t.Fatalf("author unpin = %+v, %v", got, err)
+	}
+
+	if got, _ := svc.Get(view.ID, member); !got.Pinned {
+		t.Error("author's unpin removed the member's pin")
+	}
+	if got, _ := svc.Get(view.ID, author); got.Pinned {
+		t.Error("author's view is still pinned")
+	}
+}
+
+func TestSavedViewPinRequiresVisibility(t *testing.T) {
+	project := &models.Project{ID: uuid.New(), Name: "Backend"}
+	projects := newFakeProjectRepo(project)
+	repo := newFakeSavedViewRepo()
+	svc := NewSavedViewService(repo, projects, nil)
+	author := uuid.New()
+
+	shared, err := svc.Create(author, CreateSavedViewRequest{Name: "Shared", ProjectID: &project.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	personal, err := svc.Create(author, CreateSavedViewRequest{Name: "Mine"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	outsider := uuid.New()
+	if _, err := svc.SetPinned(personal.ID, outsider, true); !errors.Is(err, ErrSavedViewNotFound) {
+		t.Errorf("pin of someone's personal view error = %v, want ErrSavedViewNotFound", err)
+	}
+	projects.visible[project.ID] = false
+	if _, err := svc.SetPinned(shared.ID, outsider, true); !errors.Is(err, ErrSavedViewNotFound) {
+		t.Errorf("pin of an invisible project view error = %v, want ErrSavedViewNotFound", err)
+	}
+	if len(repo.pins) != 0 {
+		t.Errorf("pins = %v, want none", repo.pins)
+	}
+}
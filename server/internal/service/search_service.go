@@ -0,0 +1,55 @@
+package service
+
+import (
+	"errors"
+	"github.com/google/uuid"
+	"slices"
+	"strings"
+	"task-tracker/internal/dto"
+	"task-tracker/internal/repository"
+	"unicode/utf8"
+)
+
+const (
+	maxSearchQuery     = 200
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+)
+
+var ErrInvalidSearch = errors.New("invalid search request")
+
+// Комментариев к задачам в трекере пока нет, поэтому ищем по задачам и проектам
+var searchTypes = []string{repository.SearchTasks, repository.SearchProjects}
+
+type SearchService interface {
+	Search(userID uuid.UUID, query string, types []string, limit int) ([]dto.SearchHit, error)
+}
+
+type searchService struct {
+	repo repository.SearchRepository
+}
+
+func NewSearchService(repo repository.SearchRepository) SearchService {
+	return &searchService{repo: repo}
+}
+
+func (s *searchService) Search(userID uuid.UUID, query string, types []string, limit int) ([]dto.SearchHit, error) {
+	query = strings.TrimSpace(query)
+	if query == "" || utf8.RuneCountInString(query) > maxSearchQuery {
+		return nil, ErrInvalidSearch
+	}
+
+	if len(types) == 0 {
+		types = searchTypes
+	}
+	for _, t := range types {
+		if !slices.Contains(searchTypes, t) {
+			return nil, ErrInvalidSearch
+		}
+	}
+
+	if limit <= 0 {
+		limit = defaultSearchLimit
+	}
+	return s.repo.Search(userID, query, types, min(limit, maxSearchLimit))
+}
@@ -0,0 +1,46 @@
+package service
+
+import (
+	"github.com/google/uuid"
+	"task-tracker/internal/dto"
+	"task-tracker/internal/repository"
+	"time"
+)
+
+type StatsService interface {
+	Overview(userID uuid.UUID, loc *time.Location) (*dto.StatsOverview, error)
+}
+
+type statsService struct {
+	repo repository.StatsRepository
+	now  func() time.Time
+}
+
+func NewStatsService(repo repository.StatsRepository) StatsService {
+	return &statsService{repo: repo, now: time.Now}
+}
+
+func (s *statsService) Overview(userID uuid.UUID, loc *time.Location) (*dto.StatsOverview, error) {
+	return s.repo.Overview(userID, statsBounds(s.now(), loc))
+}
+
+// DueDateToday возвращает сегодняшнюю дату в поясе loc в том виде, в котором хранятся
+// сроки задач, — полночь UTC этой даты
+func DueDateToday(now time.Time, loc *time.Location) time.Time {
+	local := now.In(loc)
+	return time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// statsBounds считает границы по календарю пользователя; неделя заканчивается воскресеньем включительно
+func statsBounds(now time.Time, loc *time.Location) dto.StatsBounds {
+	today := DueDateToday(now, loc)
+	daysLeft := (7 - int(today.Weekday())) % 7
+
+	return dto.StatsBounds{
+		Today:            today,
+		Tomorrow:         today.AddDate(0, 0, 1),
+		WeekEnd:          today.AddDate(0, 0, daysLeft+1),
+		CompletedSince7:  now.AddDate(0, 0, -7),
+		CompletedSince30: now.AddDate(0, 0, -30),
+	}
+}
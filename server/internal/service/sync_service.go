@@ -0,0 +1,230 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"strconv"
+	"strings"
+	"task-tracker/internal/dto"
+	"task-tracker/internal/models"
+	"task-tracker/internal/repository"
+	"time"
+)
+
+var (
+	ErrInvalidSyncCursor = errors.New("invalid sync cursor")
+	ErrSyncCursorExpired = errors.New("sync cursor expired, start a full sync without a cursor")
+	ErrInvalidSync       = errors.New("invalid sync mutation")
+)
+
+const (
+	defaultSyncPageSize = 500
+	maxSyncPageSize     = 1000
+	maxSyncMutations    = 100
+)
+
+// Сколько хранятся надгробия удалённых объектов. Клиент, не синхронизировавшийся дольше,
+// получает ErrSyncCursorExpired и выгружает всё заново
+const TombstoneRetention = 30 * 24 * time.Hour
+
+type SyncService interface {
+	Changes(userID uuid.UUID, cursor string, limit int) (*dto.SyncChanges, error)
+	Push(userID uuid.UUID, mutations []dto.SyncMutation) (*dto.SyncPushResult, error)
+	PurgeTombstones() (int64, error)
+}
+
+type syncService struct {
+	repo     repository.SyncRepository
+	tasks    repository.TaskRepository
+	projects repository.ProjectRepository
+	batch    *batchService
+}
+
+func NewSyncService(repo repository.SyncRepository, tx repository.Transactor, tasks repository.TaskRepository, projects repository.ProjectRepository, users repository.UserRepository, views repository.SavedViewRepository, blobs BlobCollector) SyncService {
+	return &syncService{
+		repo:     repo,
+		tasks:    tasks,
+		projects: projects,
+		batch:    &batchService{tx: tx, users: users, views: views, blobs: blobs},
+	}
+}
+
+// Changes возвращает задачи, проекты и удаления после курсора; пустой курсор — полная выгрузка
+func (s *syncService) Changes(userID uuid.UUID, cursor string, limit int) (*dto.SyncChanges, error) {
+	position, err := decodeSyncCursor(cursor)
+	if err != nil {
+		return nil, err
+	}
+	if cursor == "" {
+		// Первая выгрузка: удаления до её начала клиенту не нужны
+		if position.TombstonesFrom, err = s.repo.Watermark(); err != nil {
+			return nil, err
+		}
+	}
+	if limit <= 0 {
+		limit = defaultSyncPageSize
+	}
+	limit = min(limit, maxSyncPageSize)
+
+	changes, err := s.repo.Changes(userID, position, limit+1)
+	if errors.Is(err, repository.ErrSyncPositionExpired) {
+		return nil, ErrSyncCursorExpired
+	}
+	if err != nil {
+		return nil, err
+	}
+	result := &dto.SyncChanges{
+		Tasks:    []models.Task{},
+		Projects: []models.Project{},
+		Deleted:  []dto.SyncDeletion{},
+		HasMore:  len(changes) > limit,
+	}
+	if result.HasMore {
+		changes = changes[:limit]
+	}
+
+	var taskIDs, projectIDs []uuid.UUID
+	for _, change := range changes {
+		switch {
+		case change.Deleted:
+			result.Deleted = append(result.Deleted, dto.SyncDeletion{Resource: dto.BatchResource(change.Resource), ID: change.ID})
+		case change.Resource == string(dto.BatchTask):
+			taskIDs = append(taskIDs, change.ID)
+		default:
+			projectIDs = append(projectIDs, change.ID)
+		}
+	}
+	// Удалённые после чтения журнала строки просто не найдутся: их надгробия придут следующей страницей
+	if result.Tasks, err = s.repo.FindTasks(taskIDs); err != nil {
+		return nil, err
+	}
+	if result.Projects, err = s.repo.FindProjects(projectIDs); err != nil {
+		return nil, err
+	}
+
+	if len(changes) > 0 {
+		last := changes[len(changes)-1].Position
+		position.XID, position.Seq = last.XID, last.Seq
+	}
+	result.Cursor = encodeSyncCursor(position)
+	return result, nil
+}
+
+// Push применяет мутации клиента по одной, каждую в своей транзакции. Версия из base_version
+// сверяется с текущей: при расхождении мутация не применяется, а клиент получает текущее
+// состояние объекта для слияния. Повторная отправка уже применённых create и delete безопасна
+func (s *syncService) Push(userID uuid.UUID, mutations []dto.SyncMutation) (*dto.SyncPushResult, error) {
+	if len(mutations) == 0 {
+		return nil, fmt.Errorf("%w: no mutations", ErrInvalidSync)
+	}
+	if len(mutations) > maxSyncMutations {
+		return nil, fmt.Errorf("%w: at most %d mutations per request", ErrInvalidSync, maxSyncMutations)
+	}
+
+	result := &dto.SyncPushResult{Results: make([]dto.SyncMutationResult, len(mutations))}
+	deleted := false
+	for i, m := range mutations {
+		item := &result.Results[i]
+		item.Index, item.ID = i, m.ID
+		item.Result, item.Data, item.Err = s.apply(userID, m)
+		if item.Err != nil {
+			item.Error = item.Err.Error()
+		}
+		deleted = deleted || (m.Method == dto.BatchDelete && item.Result == dto.SyncApplied)
+	}
+
+	if deleted {
+		logCleanupError(s.batch.blobs.DeleteOrphanBlobs(context.Background()))
+	}
+	return result, nil
+}
+
+func (s *syncService) apply(userID uuid.UUID, m dto.SyncMutation) (dto.SyncMutationStatus, any, error) {
+	if m.ID == uuid.Nil {
+		return dto.SyncFailed, nil, fmt.Errorf("%w: id is required", ErrInvalidSync)
+	}
+	if m.Method != dto.BatchCreate && m.BaseVersion == nil {
+		return dto.SyncFailed, nil, fmt.Errorf("%w: base_version is required for update and delete", ErrInvalidSync)
+	}
+
+	op := dto.BatchOperation{
+		Method:   m.Method,
+		Resource: m.Resource,
+		ID:       m.ID.String(),
+		IfMatch:  m.BaseVersion,
+		Body:     m.Data,
+	}
+	var data any
+	err := s.batch.tx.Transaction(func(tasks repository.TaskRepository, projects repository.ProjectRepository) error {
+		var err error
+		_, data, err = s.batch.newRun(userID, tasks, projects).execute(op)
+		return err
+	})
+
+	switch {
+	case err == nil:
+		return dto.SyncApplied, data, nil
+	// Ответ на прошлую отправку не дошёл до клиента: объект уже создан им же
+	case m.Method == dto.BatchCreate && errors.Is(err, ErrIDTaken):
+		if current := s.current(userID, m); current != nil {
+			return dto.SyncApplied, current, nil
+		}
+		return dto.SyncFailed, nil, err
+	// Удалять уже нечего
+	case m.Method == dto.BatchDelete && errors.Is(err, gorm.ErrRecordNotFound):
+		return dto.SyncApplied, nil, nil
+	case errors.Is(err, repository.ErrVersionConflict):
+		return dto.SyncConflict, s.current(userID, m), err
+	default:
+		return dto.SyncFailed, nil, err
+	}
+}
+
+// current возвращает текущее состояние объекта мутации или nil, если пользователь не может его менять
+func (s *syncService) current(userID uuid.UUID, m dto.SyncMutation) any {
+	run := s.batch.newRun(userID, s.tasks, s.projects)
+	if m.Resource == dto.BatchProject {
+		if project, err := run.findProject(m.ID.String()); err == nil {
+			return project
+		}
+		return nil
+	}
+	if task, err := run.findTask(m.ID.String()); err == nil {
+		return task
+	}
+	return nil
+}
+
+func (s *syncService) PurgeTombstones() (int64, error) {
+	return s.repo.PurgeTombstones(time.Now().Add(-TombstoneRetention))
+}
+
+// Курсор непрозрачен для клиента: позиция в журнале изменений в base64
+func encodeSyncCursor(p repository.SyncPosition) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf("%d.%d.%d", p.XID, p.Seq, p.TombstonesFrom)))
+}
+
+func decodeSyncCursor(cursor string) (repository.SyncPosition, error) {
+	var p repository.SyncPosition
+	if cursor == "" {
+		return p, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return p, ErrInvalidSyncCursor
+	}
+	parts := strings.Split(string(raw), ".")
+	if len(parts) != 3 {
+		return p, ErrInvalidSyncCursor
+	}
+	for i, target := range []*int64{&p.XID, &p.Seq, &p.TombstonesFrom} {
+		if *target, err = strconv.ParseInt(parts[i], 10, 64); err != nil || *target < 0 {
+			return p, ErrInvalidSyncCursor
+		}
+	}
+	return p, nil
+}
@@ -0,0 +1,227 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"slices"
+	"task-tracker/internal/dto"
+	"task-tracker/internal/models"
+	"task-tracker/internal/repository"
+	"time"
+)
+
+var ErrInvalidBulk = errors.New("invalid bulk request")
+
+// Сколько задач можно изменить одним запросом
+const maxBulkTasks = 500
+
+// BulkTaskRequest — набор изменений для задач из IDs или найденных по Filter.
+// Пустые поля не меняются; Delete исключает остальные изменения
+type BulkTaskRequest struct {
+	IDs      []uuid.UUID
+	Filter   *models.ViewFilters
+	Location *time.Location
+
+	Delete       bool
+	Status       models.TaskStatus
+	Priority     models.TaskPriority
+	ProjectID    *uuid.UUID
+	NoProject    bool
+	DueDate      *time.Time
+	ClearDueDate bool
+
+	// AllOrNothing откатывает все изменения, если хотя бы одна задача не прошла
+	AllOrNothing bool
+}
+
+func (s *taskService) Bulk(userID uuid.UUID, req BulkTaskRequest) (*dto.BulkResult, error) {
+	if err := s.validateBulk(userID, req); err != nil {
+		return nil, err
+	}
+
+	var ids []uuid.UUID
+	for _, id := range req.IDs {
+		if !slices.Contains(ids, id) {
+			ids = append(ids, id)
+		}
+	}
+	if req.Filter != nil {
+		loc := req.Location
+		if loc == nil {
+			loc = time.UTC
+		}
+		filter, err := taskFilterFromView(*req.Filter, userID, loc)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidBulk, err)
+		}
+		if filter.Query != "" {
+			if err := s.applyQuery(&filter); err != nil {
+				return nil, err
+			}
+		}
+		// Лишняя задача показывает, что под фильтр попало больше допустимого
+		if ids, err = s.repo.ListIDs(filter, maxBulkTasks+1); err != nil {
+			return nil, err
+		}
+		if len(ids) > maxBulkTasks {
+			return nil, fmt.Errorf("%w: filter matches more than %d tasks", ErrInvalidBulk, maxBulkTasks)
+		}
+	}
+
+	result := &dto.BulkResult{Matched: len(ids), Items: make([]dto.BulkItemResult, 0, len(ids))}
+	errRollback := errors.New("bulk rolled back")
+
+	// Каждая задача меняется в своей точке сохранения: ошибка откатывает только её,
+	// вместе с её событиями в outbox
+	err := s.repo.Transaction(func(repo repository.TaskRepository) error {
+		bulk := &taskService{repo: repo, projects: s.projects, blobs: deferredBlobCleanup{}}
+		for _, id := range ids {
+			item := dto.BulkItemResult{ID: id, Result: dto.BulkOK}
+			err := bulk.inTx(func(tx *taskService) error {
+				warning, err := tx.bulkItem(id, userID, req)
+				item.Warning = warning
+				return err
+			})
+
+			switch {
+			case err == nil:
+				result.Succeeded++
+			case errors.Is(err, gorm.ErrRecordNotFound):
+				item.Result, item.Error = dto.BulkNotFound, "task not found"
+				result.Failed++
+			default:
+				item.Result, item.Error = dto.BulkFailed, err.Error()
+				result.Failed++
+			}
+			result.Items = append(result.Items, item)
+		}
+
+		if req.AllOrNothing && result.Failed > 0 {
+			return errRollback
+		}
+		return nil
+	})
+	switch {
+	case errors.Is(err, errRollback):
+		result.RolledBack = true
+		result.Succeeded = 0
+	case err != nil:
+		return nil, err
+	}
+
+	if req.Delete && result.Succeeded > 0 {
+		logCleanupError(s.blobs.DeleteOrphanBlobs(context.Background()))
+	}
+	return result, nil
+}
+
+// bulkItem применяет изменения к одной задаче. Чужие задачи, которые пользователь
+// не может менять, — «не найдены»
+func (s *taskService) bulkItem(id, userID uuid.UUID, req BulkTaskRequest) (*dto.WIPWarning, error) {
+	task, err := s.repo.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if !canModifyTask(task, userID) {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	if req.Delete {
+		if err := s.repo.Delete(id, nil); err != nil {
+			return nil, err
+		}
+		s.publishTaskDeleted(task)
+		return nil, nil
+	}
+	audience := taskAudience(task)
+
+	oldKey := task.Key
+	switch {
+	case req.NoProject:
+		moveToProject(task, nil)
+	case req.ProjectID != nil:
+		moveToProject(task, req.ProjectID)
+	}
+	if req.Priority != "" {
+		task.Priority = req.Priority
+	}
+	switch {
+	case req.ClearDueDate:
+		if task.Recurrence != "" {
+			return nil, ErrRecurrenceNeedsDueDate
+		}
+		task.DueDate = nil
+	case req.DueDate != nil:
+		task.DueDate = req.DueDate
+	}
+
+	previousStatus := task.Status
+	wasDone := task.Status == models.StatusDone
+	if req.Status != "" && req.Status != task.Status {
+		task.Status = req.Status
+		task.Rank = 0 // в конец новой колонки
+	}
+
+	var warning *dto.WIPWarning
+	if task.Rank == 0 {
+		if warning, err = s.checkWIP(task); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.save(task, wasDone); err != nil {
+		return nil, err
+	}
+	if oldKey != "" && task.Key != oldKey {
+		if err := s.repo.AddKeyAlias(task.ID, oldKey); err != nil {
+			return nil, err
+		}
+	}
+	s.publishTaskUpdated(id, audience, previousStatus)
+	return warning, nil
+}
+
+// canModifyTask — менять задачу может её владелец или владелец её проекта
+func canModifyTask(task *models.Task, userID uuid.UUID) bool {
+	return task.UserID == userID || (task.Project != nil && task.Project.UserID == userID)
+}
+
+// validateBulk проверяет запрос целиком, до изменения задач
+func (s *taskService) validateBulk(userID uuid.UUID, req BulkTaskRequest) error {
+	if (len(req.IDs) > 0) == (req.Filter != nil) {
+		return fmt.Errorf("%w: specify either ids or filter", ErrInvalidBulk)
+	}
+	if len(req.IDs) > maxBulkTasks {
+		return fmt.Errorf("%w: at most %d tasks per request", ErrInvalidBulk, maxBulkTasks)
+	}
+
+	changes := req.Status != "" || req.Priority != "" || req.ProjectID != nil || req.NoProject ||
+		req.DueDate != nil || req.ClearDueDate
+	switch {
+	case req.Delete && changes:
+		return fmt.Errorf("%w: delete cannot be combined with other changes", ErrInvalidBulk)
+	case !req.Delete && !changes:
+		return fmt.Errorf("%w: nothing to change", ErrInvalidBulk)
+	case req.ProjectID != nil && req.NoProject:
+		return fmt.Errorf("%w: project_id and no_project exclude each other", ErrInvalidBulk)
+	case req.DueDate != nil && req.ClearDueDate:
+		return fmt.Errorf("%w: due_date and clear_due_date exclude each other", ErrInvalidBulk)
+	case req.Status != "" && !slices.Contains(models.TaskStatuses, req.Status):
+		return fmt.Errorf("%w: unknown status %q", ErrInvalidBulk, req.Status)
+	case req.Priority != "" && !slices.Contains(models.TaskPriorities, req.Priority):
+		return fmt.Errorf("%w: unknown priority %q", ErrInvalidBulk, req.Priority)
+	}
+
+	if req.ProjectID != nil {
+		visible, err := s.projects.IsVisible(*req.ProjectID, userID)
+		if err != nil {
+			return err
+		}
+		if !visible {
+			return fmt.Errorf("%w: project not found", ErrInvalidBulk)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,405 @@
+package service
+
+import (
+	"github.com/google/uuid"
+	"slices"
+	"strconv"
+	"strings"
+	"task-tracker/internal/dto"
+	"task-tracker/internal/models"
+	"task-tracker/internal/repository"
+	"task-tracker/pkg/taskql"
+	"time"
+)
+
+// taskQueryFields — поля, которые понимает queryCompiler; прочие слово:значение ищутся как текст
+var taskQueryFields = []string{
+	"project", "status", "priority", "due", "created", "updated", "estimate",
+	"assignee", "owner", "is", "has", "no",
+}
+
+// queryCompiler переводит условия taskql в поля dto.TaskFilter. Значения никогда не попадают
+// в SQL напрямую — только через типизированные поля фильтра
+type queryCompiler struct {
+	filter   *dto.TaskFilter
+	projects repository.ProjectRepository
+	viewer   uuid.UUID
+	today    time.Time
+	loc      *time.Location
+}
+
+// applyQuery разбирает filter.Query и сужает фильтр его условиями
+func (s *taskService) applyQuery(filter *dto.TaskFilter) error {
+	query, err := taskql.Parse(filter.Query, taskQueryFields...)
+	if err != nil {
+		return err
+	}
+
+	c := &queryCompiler{filter: filter, projects: s.projects, today: filter.Today, loc: filter.Location}
+	switch {
+	case filter.ViewerID != nil:
+		c.viewer = *filter.ViewerID
+	case filter.UserID != nil:
+		c.viewer = *filter.UserID
+	}
+	if c.loc == nil {
+		c.loc = time.UTC
+	}
+	if c.today.IsZero() {
+		c.today = DueDateToday(time.Now(), c.loc)
+	}
+
+	for _, term := range query.Terms {
+		if err := c.term(term); err != nil {
+			return err
+		}
+	}
+	if text := query.SearchText(); text != "" {
+		filter.Search = strings.TrimSpace(filter.Search + " " + text)
+	}
+	filter.Query = ""
+	return nil
+}
+
+func (c *queryCompiler) term(t taskql.Term) error {
+	switch t.Field {
+	case "project":
+		return c.project(t)
+	case "status":
+		return c.status(t)
+	case "priority":
+		return c.priority(t)
+	case "due":
+		return c.due(t)
+	case "created":
+		return c.dateRange(t, &c.filter.CreatedFrom, &c.filter.CreatedTo, c.loc)
+	case "updated":
+		return c.dateRange(t, &c.filter.UpdatedFrom, &c.filter.UpdatedTo, c.loc)
+	case "estimate":
+		return c.estimate(t)
+	case "assignee", "owner":
+		return c.assignee(t)
+	case "is":
+		return c.is(t)
+	case "has", "no":
+		return c.has(t)
+	default:
+		return taskql.Errorf(t.Pos, "unknown field %q", t.Field)
+	}
+}
+
+func (c *queryCompiler) project(t taskql.Term) error {
+	if t.Op != taskql.OpEq {
+		return unsupportedOp(t)
+	}
+	if len(t.Values) == 1 && strings.EqualFold(t.Values[0], "none") {
+		c.filter.NoProject = true
+		return nil
+	}
+
+	var ids []uuid.UUID
+	for _, value := range t.Values {
+		if id, err := uuid.Parse(value); err == nil {
+			ids = append(ids, id)
+			continue
+		}
+		projects, err := c.projects.FindByName(c.viewer, value)
+		if err != nil {
+			return err
+		}
+		if len(projects) == 0 {
+			return taskql.Errorf(t.ValuePos, "unknown project %q", value)
+		}
+		for _, p := range projects {
+			ids = append(ids, p.ID)
+		}
+	}
+
+	if c.filter.ProjectIDs != nil {
+		ids = intersect(c.filter.ProjectIDs, ids)
+		if len(ids) == 0 {
+			return taskql.Errorf(t.Pos, "project conditions exclude each other")
+		}
+	}
+	c.filter.ProjectIDs = ids
+	return nil
+}
+
+func (c *queryCompiler) status(t taskql.Term) error {
+	var matched []models.TaskStatus
+	switch t.Op {
+	case taskql.OpEq, taskql.OpNe:
+		values, err := enumValues(t, models.TaskStatuses)
+		if err != nil {
+			return err
+		}
+		for _, status := range models.TaskStatuses {
+			if slices.Contains(values, status) == (t.Op == taskql.OpEq) {
+				matched = append(matched, status)
+			}
+		}
+	default:
+		return unsupportedOp(t)
+	}
+	return c.setStatuses(t, matched)
+}
+
+func (c *queryCompiler) setStatuses(t taskql.Term, statuses []models.TaskStatus) error {
+	if len(c.filter.Statuses) > 0 {
+		statuses = intersect(c.filter.Statuses, statuses)
+	}
+	if len(statuses) == 0 {
+		return taskql.Errorf(t.Pos, "status conditions exclude each other")
+	}
+	c.filter.Statuses = statuses
+	return nil
+}
+
+// priority поддерживает сравнения: priority>=medium — это medium и high
+func (c *queryCompiler) priority(t taskql.Term) error {
+	values, err := enumValues(t, models.TaskPriorities)
+	if err != nil {
+		return err
+	}
+	if t.Op != taskql.OpEq && t.Op != taskql.OpNe && len(values) > 1 {
+		return taskql.Errorf(t.ValuePos, "comparison needs a single priority")
+	}
+
+	bound := slices.Index(models.TaskPriorities, values[0])
+	var matched []models.TaskPriority
+	for i, priority := range models.TaskPriorities {
+		ok := false
+		switch t.Op {
+		case taskql.OpEq:
+			ok = slices.Contains(values, priority)
+		case taskql.OpNe:
+			ok = !slices.Contains(values, priority)
+		case taskql.OpLt:
+			ok = i < bound
+		case taskql.OpLe:
+			ok = i <= bound
+		case taskql.OpGt:
+			ok = i > bound
+		case taskql.OpGe:
+			ok = i >= bound
+		}
+		if ok {
+			matched = append(matched, priority)
+		}
+	}
+
+	if len(c.filter.Priorities) > 0 {
+		matched = intersect(c.filter.Priorities, matched)
+	}
+	if len(matched) == 0 {
+		return taskql.Errorf(t.Pos, "priority conditions exclude each other")
+	}
+	c.filter.Priorities = matched
+	return nil
+}
+
+func (c *queryCompiler) due(t taskql.Term) error {
+	if len(t.Values) == 1 && (t.Op == taskql.OpEq || t.Op == taskql.OpNe) {
+		switch strings.ToLower(t.Values[0]) {
+		case "none":
+			setBool(&c.filter.HasDueDate, t.Op == taskql.OpNe)
+			return nil
+		case "any":
+			setBool(&c.filter.HasDueDate, t.Op == taskql.OpEq)
+			return nil
+		}
+	}
+	// Сроки хранятся как полночь UTC
+	return c.dateRange(t, &c.filter.DueFrom, &c.filter.DueTo, time.UTC)
+}
+
+// dateRange сужает полуинтервал [from, to) по условию с датой:
+// YYYY-MM-DD, today, tomorrow, yesterday или смещение от сегодня (+7d, -2w, +1m)
+func (c *queryCompiler) dateRange(t taskql.Term, from, to **time.Time, loc *time.Location) error {
+	if len(t.Values) != 1 {
+		return taskql.Errorf(t.ValuePos, "%s expects a single date", t.Field)
+	}
+	day, err := c.parseDate(t.Values[0], loc)
+	if err != nil {
+		return taskql.Errorf(t.ValuePos, "invalid date %q: use YYYY-MM-DD, today, tomorrow, yesterday or an offset like +7d", t.Values[0])
+	}
+	next := day.AddDate(0, 0, 1)
+
+	switch t.Op {
+	case taskql.OpEq:
+		raise(from, day)
+		lower(to, next)
+	case taskql.OpLt:
+		lower(to, day)
+	case taskql.OpLe:
+		lower(to, next)
+	case taskql.OpGt:
+		raise(from, next)
+	case taskql.OpGe:
+		raise(from, day)
+	default:
+		return unsupportedOp(t)
+	}
+
+	if *from != nil && *to != nil && !(*to).After(**from) {
+		return taskql.Errorf(t.Pos, "%s conditions exclude each other", t.Field)
+	}
+	return nil
+}
+
+func (c *queryCompiler) parseDate(value string, loc *time.Location) (time.Time, error) {
+	today := time.Date(c.today.Year(), c.today.Month(), c.today.Day(), 0, 0, 0, 0, loc)
+	switch strings.ToLower(value) {
+	case "today":
+		return today, nil
+	case "tomorrow":
+		return today.AddDate(0, 0, 1), nil
+	case "yesterday":
+		return today.AddDate(0, 0, -1), nil
+	}
+
+	if len(value) > 2 && (value[0] == '+' || value[0] == '-') {
+		n, err := strconv.Atoi(value[1 : len(value)-1])
+		if err != nil {
+			return time.Time{}, err
+		}
+		if value[0] == '-' {
+			n = -n
+		}
+		switch value[len(value)-1] {
+		case 'd':
+			return today.AddDate(0, 0, n), nil
+		case 'w':
+			return today.AddDate(0, 0, 7*n), nil
+		case 'm':
+			return today.AddDate(0, n, 0), nil
+		}
+	}
+
+	return time.ParseInLocation("2006-01-02", value, loc)
+}
+
+// estimate допускает только нестрогие сравнения, как и параметры estimate_min/estimate_max
+func (c *queryCompiler) estimate(t taskql.Term) error {
+	if len(t.Values) != 1 {
+		return taskql.Errorf(t.ValuePos, "estimate expects a single number")
+	}
+	if strings.EqualFold(t.Values[0], "none") && (t.Op == taskql.OpEq || t.Op == taskql.OpNe) {
+		setBool(&c.filter.HasEstimate, t.Op == taskql.OpNe)
+		return nil
+	}
+
+	value, err := strconv.ParseFloat(t.Values[0], 64)
+	if err != nil {
+		return taskql.Errorf(t.ValuePos, "invalid number %q", t.Values[0])
+	}
+	switch t.Op {
+	case taskql.OpEq:
+		c.filter.EstimateMin, c.filter.EstimateMax = &value, &value
+	case taskql.OpGe:
+		c.filter.EstimateMin = &value
+	case taskql.OpLe:
+		c.filter.EstimateMax = &value
+	default:
+		return taskql.Errorf(t.Pos, "estimate supports only :, >= and <=")
+	}
+	return nil
+}
+
+// У задачи нет отдельного исполнителя: отвечает за неё владелец, поэтому понимаем только "me"
+func (c *queryCompiler) assignee(t taskql.Term) error {
+	if t.Op != taskql.OpEq {
+		return unsupportedOp(t)
+	}
+	if len(t.Values) != 1 || !strings.EqualFold(t.Values[0], "me") {
+		return taskql.Errorf(t.ValuePos, "only %s:me is supported", t.Field)
+	}
+	c.filter.UserID = &c.viewer
+	return nil
+}
+
+func (c *queryCompiler) is(t taskql.Term) error {
+	if (t.Op != taskql.OpEq && t.Op != taskql.OpNe) || len(t.Values) != 1 {
+		return unsupportedOp(t)
+	}
+	positive := t.Op == taskql.OpEq
+
+	switch strings.ToLower(t.Values[0]) {
+	case "overdue":
+		setBool(&c.filter.Overdue, positive)
+		return nil
+	case "open", "done":
+		done := strings.EqualFold(t.Values[0], "done") == positive
+		if done {
+			return c.setStatuses(t, []models.TaskStatus{models.StatusDone})
+		}
+		return c.setStatuses(t, []models.TaskStatus{models.StatusTodo, models.StatusInProgress})
+	default:
+		return taskql.Errorf(t.ValuePos, "unknown value %q: expected overdue, open or done", t.Values[0])
+	}
+}
+
+// has:due и no:due, has:estimate и no:estimate, no:project
+func (c *queryCompiler) has(t taskql.Term) error {
+	if t.Op != taskql.OpEq || len(t.Values) != 1 {
+		return unsupportedOp(t)
+	}
+	positive := t.Field == "has"
+
+	switch strings.ToLower(t.Values[0]) {
+	case "due":
+		setBool(&c.filter.HasDueDate, positive)
+	case "estimate":
+		setBool(&c.filter.HasEstimate, positive)
+	case "project":
+		if positive {
+			return taskql.Errorf(t.ValuePos, "has:project is not supported, use project:<name>")
+		}
+		c.filter.NoProject = true
+	default:
+		return taskql.Errorf(t.ValuePos, "unknown value %q: expected due, estimate or project", t.Values[0])
+	}
+	return nil
+}
+
+func setBool(dst **bool, value bool) {
+	*dst = &value
+}
+
+func enumValues[T ~string](t taskql.Term, allowed []T) ([]T, error) {
+	values := make([]T, 0, len(t.Values))
+	for _, v := range t.Values {
+		value := T(strings.ToLower(v))
+		if !slices.Contains(allowed, value) {
+			return nil, taskql.Errorf(t.ValuePos, "unknown %s %q: expected one of %v", t.Field, v, allowed)
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
+
+func unsupportedOp(t taskql.Term) error {
+	return taskql.Errorf(t.Pos, "operator %q is not supported for %s", t.Op, t.Field)
+}
+
+func intersect[T comparable](a, b []T) []T {
+	var out []T
+	for _, v := range a {
+		if slices.Contains(b, v) {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+func raise(dst **time.Time, t time.Time) {
+	if *dst == nil || t.After(**dst) {
+		*dst = &t
+	}
+}
+
+func lower(dst **time.Time, t time.Time) {
+	if *dst == nil || t.Before(**dst) {
+		*dst = &t
+	}
+}
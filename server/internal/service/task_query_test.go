@@ -0,0 +1,194 @@
+package service
+
+import (
+	"errors"
+	"github.com/google/uuid"
+	"reflect"
+	"task-tracker/internal/dto"
+	"task-tracker/internal/models"
+	"task-tracker/pkg/taskql"
+	"testing"
+	"time"
+)
+
+func compileQuery(t *testing.T, projects *fakeProjectRepo, query string) (dto.TaskFilter, error) {
+	t.Helper()
+	viewer := uuid.New()
+	filter := dto.TaskFilter{
+		ViewerID: &viewer,
+		Query:    query,
+		Today:    time.Date(2026, 3, 10, 0, 0, 0, 0, time.UTC),
+		Location: time.UTC,
+	}
+	svc := &taskService{projects: projects}
+	err := svc.applyQuery(&filter)
+	return filter, err
+}
+
+func day(s string) *time.Time {
+	t, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		panic(err)
+	}
+	return &t
+}
+
+func TestApplyQuery(t *testing.T) {
+	backend := &models.Project{ID: uuid.New(), Name: "Backend"}
+	mobile := &models.Project{ID: uuid.New(), Name: "Mobile app"}
+	projects := newFakeProjectRepo(backend, mobile)
+	yes, no := true, false
+	two := 2.0
+
+	tests := []struct {
+		query string
+		check func(t *testing.T, f dto.TaskFilter)
+	}{
+		{"status:todo,in_progress", func(t *testing.T, f dto.TaskFilter) {
+			assertEqual(t, f.Statuses, []models.TaskStatus{models.StatusTodo, models.StatusInProgress})
+		}},
+		{"status:!done", func(t *testing.T, f dto.TaskFilter) {
+			assertEqual(t, f.Statuses, []models.TaskStatus{models.StatusTodo, models.StatusInProgress})
+		}},
+		{"STATUS:Done is:done", func(t *testing.T, f dto.TaskFilter) {
+			assertEqual(t, f.Statuses, []models.TaskStatus{models.StatusDone})
+		}},
+		{"is:open", func(t *testing.T, f dto.TaskFilter) {
+			assertEqual(t, f.Statuses, []models.TaskStatus{models.StatusTodo, models.StatusInProgress})
+		}},
+		{"priority>=medium", func(t *testing.T, f dto.TaskFilter) {
+			assertEqual(t, f.Priorities, []models.TaskPriority{models.PriorityMedium, models.PriorityHigh})
+		}},
+		{"priority<high priority!=low", func(t *testing.T, f dto.TaskFilter) {
+			assertEqual(t, f.Priorities, []models.TaskPriority{models.PriorityMedium})
+		}},
+		{"project:backend", func(t *testing.T, f dto.TaskFilter) {
+			assertEqual(t, f.ProjectIDs, []uuid.UUID{backend.ID})
+		}},
+		{`project:"Mobile app" project:` + mobile.ID.String() + "," + backend.ID.String(), func(t *testing.T, f dto.TaskFilter) {
+			assertEqual(t, f.ProjectIDs, []uuid.UUID{mobile.ID})
+		}},
+		{"project:none", func(t *testing.T, f dto.TaskFilter) {
+			assertEqual(t, f.NoProject, true)
+		}},
+		{"due<+7d", func(t *testing.T, f dto.TaskFilter) {
+			assertEqual(t, f.DueTo, day("2026-03-17"))
+			assertEqual(t, f.DueFrom, (*time.Time)(nil))
+		}},
+		{"due:today", func(t *testing.T, f dto.TaskFilter) {
+			assertEqual(t, f.DueFrom, day("2026-03-10"))
+			assertEqual(t, f.DueTo, day("2026-03-11"))
+		}},
+		{"due>=2026-03-01 due<=-1w", func(t *testing.T, f dto.TaskFilter) {
+			assertEqual(t, f.DueFrom, day("2026-03-01"))
+			assertEqual(t, f.DueTo, day("2026-03-04"))
+		}},
+		{"due>+1m", func(t *testing.T, f dto.TaskFilter) {
+			assertEqual(t, f.DueFrom, day("2026-04-11"))
+		}},
+		{"due:none", func(t *testing.T, f dto.TaskFilter) {
+			assertEqual(t, f.HasDueDate, &no)
+		}},
+		{"has:due no:estimate", func(t *testing.T, f dto.TaskFilter) {
+			assertEqual(t, f.HasDueDate, &yes)
+			assertEqual(t, f.HasEstimate, &no)
+		}},
+		{"estimate>=2", func(t *testing.T, f dto.TaskFilter) {
+			assertEqual(t, f.EstimateMin, &two)
+		}},
+		{"is:overdue", func(t *testing.T, f dto.TaskFilter) {
+			assertEqual(t, f.Overdue, &yes)
+		}},
+		{"assignee:me", func(t *testing.T, f dto.TaskFilter) {
+			assertEqual(t, f.UserID, f.ViewerID)
+		}},
+		{`status:todo "login bug" crash`, func(t *testing.T, f dto.TaskFilter) {
+			assertEqual(t, f.Search, `"login bug" crash`)
+			assertEqual(t, f.Query, "")
+		}},
+		{"see http://example.com/a:b", func(t *testing.T, f dto.TaskFilter) {
+			assertEqual(t, f.Search, "see http://example.com/a:b")
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			f, err := compileQuery(t, projects, tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.check(t, f)
+		})
+	}
+}
+
+func TestApplyQueryCreatedUsesLocation(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip("timezone Europe/Berlin is unavailable")
+	}
+	filter := dto.TaskFilter{Query: "created:yesterday", Today: time.Date(2026, 3, 10, 0, 0, 0, 0, time.UTC), Location: berlin}
+	if err := (&taskService{}).applyQuery(&filter); err != nil {
+		t.Fatal(err)
+	}
+	// Полночь 9 марта в Берлине — 23:00 UTC 8 марта
+	if got := filter.CreatedFrom.UTC().Format(time.RFC3339); got != "2026-03-08T23:00:00Z" {
+		t.Errorf("CreatedFrom = %s", got)
+	}
+	if got := filter.CreatedTo.UTC().Format(time.RFC3339); got != "2026-03-09T23:00:00Z" {
+		t.Errorf("CreatedTo = %s", got)
+	}
+}
+
+func TestApplyQueryErrors(t *testing.T) {
+	projects := newFakeProjectRepo(&models.Project{ID: uuid.New(), Name: "Backend"})
+
+	tests := []struct {
+		query string
+		pos   int
+	}{
+		{"status:closed", 8},
+		{"bug status:done status:todo", 17},
+		{"priority>low,high", 10},
+		{"priority:urgent", 10},
+		{"project:Nowhere", 9},
+		{"project>Backend", 1},
+		{"due:someday", 5},
+		{"due<+7x", 5},
+		{"due>today due<today", 11},
+		{"estimate>1", 1},
+		{"estimate:many", 10},
+		{"assignee:bob", 10},
+		{"is:blocked", 4},
+		{"has:project", 5},
+		{`status:"done' OR '1'='1"`, 8},
+		{"status:", 8},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			_, err := compileQuery(t, projects, tt.query)
+			var qerr *taskql.Error
+			if !errors.As(err, &qerr) {
+				t.Fatalf("error = %v, want *taskql.Error", err)
+			}
+			if qerr.Pos != tt.pos {
+				t.Errorf("error position = %d (%s), want %d", qerr.Pos, qerr.Msg, tt.pos)
+			}
+		})
+	}
+}
+
+func assertEqual[T any](t *testing.T, got, want T) {
+	t.Helper()
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", describe(got), describe(want))
+	}
+}
+
+func describe(v any) any {
+	if p, ok := v.(*time.Time); ok && p != nil {
+		return p.Format(time.RFC3339)
+	}
+	return v
+}
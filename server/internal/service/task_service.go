@@ -1,49 +1,121 @@
 package service
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"github.com/google/uuid"
+	"slices"
+	"strconv"
+	"strings"
 	"task-tracker/internal/dto"
 	"task-tracker/internal/models"
 	"task-tracker/internal/repository"
 	"time"
 )
 
+var (
+	ErrInvalidEstimate = errors.New("estimate must not be negative")
+	ErrProjectNotFound = errors.New("project not found")
+	ErrInvalidTaskKey  = errors.New("task key must look like API-42")
+	ErrInvalidMove     = errors.New("specify at most one neighbor other than the task itself")
+	ErrWIPLimitReached = errors.New("column WIP limit reached")
+)
+
 type CreateTaskRequest struct {
-	Title       string
-	Description string
-	Priority    models.TaskPriority
-	DueDate     *time.Time
-	ProjectID   *uuid.UUID
+	ID                 uuid.UUID // задан клиентом (офлайн-создание); пустой — сгенерировать
+	Title              string
+	Description        string
+	Priority           models.TaskPriority
+	DueDate            *time.Time
+	ProjectID          *uuid.UUID
+	Recurrence         string
+	RecurrenceTimezone string
+	Estimate           *float64
+	RemainingEstimate  *float64
 }
 
 type UpdateTaskRequest struct {
-	Title       string
-	Description string
-	Priority    models.TaskPriority
-	Status      models.TaskStatus
-	DueDate     *time.Time
-	ProjectID   *uuid.UUID
+	Title              string
+	Description        string
+	Priority           models.TaskPriority
+	Status             models.TaskStatus
+	DueDate            *time.Time
+	ProjectID          *uuid.UUID // nil — не менять; очистить поля можно через Patch
+	Recurrence         string
+	RecurrenceTimezone string
+	Estimate           *float64
+	RemainingEstimate  *float64
+	IfMatch            *int // ожидаемая версия задачи; nil — без проверки
+}
+
+// MoveTaskRequest ставит задачу в колонку Status сразу после AfterID или перед BeforeID;
+// без соседа — в конец колонки
+type MoveTaskRequest struct {
+	Status   models.TaskStatus
+	AfterID  *uuid.UUID
+	BeforeID *uuid.UUID
 }
 
 type TaskService interface {
 	Create(req CreateTaskRequest, userID uuid.UUID) (*models.Task, error)
 	GetByID(id uuid.UUID) (*models.Task, error)
-	Update(id uuid.UUID, req UpdateTaskRequest) error
-	Delete(id uuid.UUID) error
-	List(filter dto.TaskFilter, page, limit int) ([]models.Task, error)
-	UpdateStatus(id uuid.UUID, status models.TaskStatus) error
+	Update(id uuid.UUID, userID uuid.UUID, req UpdateTaskRequest) error
+	Delete(id uuid.UUID, ifMatch *int) error
+	List(filter dto.TaskFilter, page dto.PageRequest) (*dto.ListResponse[models.Task], error)
+	UpdateStatus(id uuid.UUID, status models.TaskStatus, ifMatch *int) (*dto.WIPWarning, error)
+	SkipOccurrence(id uuid.UUID) (*models.Task, error)
+	StopRecurrence(id uuid.UUID) (*models.Task, error)
+	ResolveKey(key string) (uuid.UUID, error)
+	Move(id uuid.UUID, req MoveTaskRequest) (*models.Task, *dto.WIPWarning, error)
+	Bulk(userID uuid.UUID, req BulkTaskRequest) (*dto.BulkResult, error)
+	Patch(id uuid.UUID, userID uuid.UUID, patch dto.TaskPatch, ifMatch *int) (*models.Task, error)
 }
 
 type taskService struct {
-	repo repository.TaskRepository
+	repo     repository.TaskRepository
+	projects repository.ProjectRepository
+	blobs    BlobCollector
+	// События изменения; есть только у копии сервиса внутри inTx
+	events *pendingEvents
 }
 
-func NewTaskService(repo repository.TaskRepository) TaskService {
-	return &taskService{repo: repo}
+func NewTaskService(repo repository.TaskRepository, projects repository.ProjectRepository, blobs BlobCollector) TaskService {
+	return &taskService{
+		repo:     repo,
+		projects: projects,
+		blobs:    blobs,
+	}
+}
+
+// inTx выполняет изменение в транзакции и той же транзакцией пишет его события в outbox:
+// подписчики узнают только о зафиксированном, и ни одно событие не теряется. Внутри
+// внешней транзакции (пакет, массовое изменение) это точка сохранения
+func (s *taskService) inTx(fn func(tx *taskService) error) error {
+	return s.repo.Transaction(func(repo repository.TaskRepository) error {
+		tx := &taskService{repo: repo, projects: s.projects, blobs: deferredBlobCleanup{}, events: &pendingEvents{}}
+		if err := fn(tx); err != nil {
+			return err
+		}
+		return repo.AddEvents(tx.events.events)
+	})
 }
 
 func (s *taskService) Create(req CreateTaskRequest, userID uuid.UUID) (*models.Task, error) {
+	var task *models.Task
+	err := s.inTx(func(tx *taskService) (err error) {
+		task, err = tx.create(req, userID)
+		return err
+	})
+	return task, err
+}
+
+func (s *taskService) create(req CreateTaskRequest, userID uuid.UUID) (*models.Task, error) {
+	if err := s.checkProjectVisible(req.ProjectID, userID); err != nil {
+		return nil, err
+	}
 	task := &models.Task{
+		ID:          req.ID,
 		Title:       req.Title,
 		Description: req.Description,
 		Priority:    req.Priority,
@@ -52,18 +124,44 @@ func (s *taskService) Create(req CreateTaskRequest, userID uuid.UUID) (*models.T
 		ProjectID:   req.ProjectID,
 		Status:      models.StatusTodo,
 	}
-	return task, s.repo.Create(task)
+	if err := setEstimates(task, req.Estimate, req.RemainingEstimate); err != nil {
+		return nil, err
+	}
+	if task.Estimate != nil && task.RemainingEstimate == nil {
+		remaining := *task.Estimate
+		task.RemainingEstimate = &remaining
+	}
+	if req.Recurrence != "" {
+		if err := setRecurrence(task, req.Recurrence, req.RecurrenceTimezone); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.repo.Create(task); err != nil {
+		return nil, err
+	}
+	s.publishTask(EventTaskCreated, task.ID, nil)
+	return task, nil
 }
 
 func (s *taskService) GetByID(id uuid.UUID) (*models.Task, error) {
 	return s.repo.FindByID(id)
 }
 
-func (s *taskService) Update(id uuid.UUID, req UpdateTaskRequest) error {
+func (s *taskService) Update(id uuid.UUID, userID uuid.UUID, req UpdateTaskRequest) error {
+	return s.inTx(func(tx *taskService) error {
+		return tx.update(id, userID, req)
+	})
+}
+
+func (s *taskService) update(id uuid.UUID, userID uuid.UUID, req UpdateTaskRequest) error {
 	task, err := s.repo.FindByID(id)
 	if err != nil {
 		return err
 	}
+	if err := checkVersion(task.Version, req.IfMatch); err != nil {
+		return err
+	}
+	audience := taskAudience(task)
 
 	if req.Title != "" {
 		task.Title = req.Title
@@ -74,33 +172,505 @@ func (s *taskService) Update(id uuid.UUID, req UpdateTaskRequest) error {
 	if req.Priority != "" {
 		task.Priority = req.Priority
 	}
-	if req.Status != "" {
-		task.Status = req.Status
-	}
 	if req.DueDate != nil {
 		task.DueDate = req.DueDate
 	}
+	oldKey := task.Key
 	if req.ProjectID != nil {
-		task.ProjectID = req.ProjectID // nil → отвязать
+		if err := s.checkProjectVisible(req.ProjectID, userID); err != nil {
+			return err
+		}
+		moveToProject(task, req.ProjectID)
+	}
+	if err := setEstimates(task, req.Estimate, req.RemainingEstimate); err != nil {
+		return err
+	}
+	if req.Recurrence != "" {
+		timezone := req.RecurrenceTimezone
+		if timezone == "" {
+			timezone = task.RecurrenceTimezone
+		}
+		if err := setRecurrence(task, req.Recurrence, timezone); err != nil {
+			return err
+		}
+	}
+
+	previousStatus := task.Status
+	wasDone := task.Status == models.StatusDone
+	if req.Status != "" && req.Status != task.Status {
+		task.Status = req.Status
+		task.Rank = 0 // в конец новой колонки
+	}
+	// Нулевой ранг — задача переходит в другую колонку; предупреждения здесь не возвращаются,
+	// действует только строгий лимит
+	if task.Rank == 0 {
+		if _, err := s.checkWIP(task); err != nil {
+			return err
+		}
 	}
 
-	return s.repo.Update(task)
+	if err := s.save(task, wasDone); err != nil {
+		return err
+	}
+	if oldKey != "" && task.Key != oldKey {
+		if err := s.repo.AddKeyAlias(task.ID, oldKey); err != nil {
+			return err
+		}
+	}
+	s.publishTaskUpdated(id, audience, previousStatus)
+	return nil
 }
 
-func (s *taskService) Delete(id uuid.UUID) error {
-	return s.repo.Delete(id)
+// Patch применяет merge-patch: поля, которых нет в запросе, не меняются, null очищает поле
+func (s *taskService) Patch(id uuid.UUID, userID uuid.UUID, patch dto.TaskPatch, ifMatch *int) (*models.Task, error) {
+	var task *models.Task
+	err := s.inTx(func(tx *taskService) (err error) {
+		task, err = tx.patch(id, userID, patch, ifMatch)
+		return err
+	})
+	return task, err
 }
 
-func (s *taskService) List(filter dto.TaskFilter, page, limit int) ([]models.Task, error) {
-	offset := (page - 1) * limit
-	return s.repo.List(filter, limit, offset)
+func (s *taskService) patch(id uuid.UUID, userID uuid.UUID, patch dto.TaskPatch, ifMatch *int) (*models.Task, error) {
+	task, err := s.repo.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkVersion(task.Version, ifMatch); err != nil {
+		return nil, err
+	}
+	audience := taskAudience(task)
+
+	if patch.Title.Set {
+		title, err := requiredField(patch.Title, "title")
+		if err != nil {
+			return nil, err
+		}
+		if strings.TrimSpace(title) == "" {
+			return nil, ErrEmptyTitle
+		}
+		task.Title = title
+	}
+	if patch.Description.Set {
+		task.Description = valueOrZero(patch.Description)
+	}
+	if patch.Priority.Set {
+		priority, err := requiredField(patch.Priority, "priority")
+		if err != nil {
+			return nil, err
+		}
+		if !slices.Contains(models.TaskPriorities, priority) {
+			return nil, fmt.Errorf("%w: unknown priority %q", ErrInvalidPatch, priority)
+		}
+		task.Priority = priority
+	}
+	if patch.DueDate.Set {
+		task.DueDate = nil
+		if patch.DueDate.Value != nil {
+			due, err := dto.ParseDueDate(*patch.DueDate.Value)
+			if err != nil {
+				return nil, fmt.Errorf("%w: due_date must be YYYY-MM-DD or RFC 3339", ErrInvalidPatch)
+			}
+			task.DueDate = &due
+		}
+	}
+
+	oldKey := task.Key
+	if patch.ProjectID.Set {
+		if err := s.checkProjectVisible(patch.ProjectID.Value, userID); err != nil {
+			return nil, err
+		}
+		moveToProject(task, patch.ProjectID.Value)
+	}
+
+	for _, f := range []struct {
+		field  dto.Field[float64]
+		target **float64
+	}{
+		{patch.Estimate, &task.Estimate},
+		{patch.RemainingEstimate, &task.RemainingEstimate},
+	} {
+		if !f.field.Set {
+			continue
+		}
+		if f.field.Value != nil && *f.field.Value < 0 {
+			return nil, ErrInvalidEstimate
+		}
+		*f.target = f.field.Value
+	}
+
+	if err := patchRecurrence(task, patch); err != nil {
+		return nil, err
+	}
+	if task.Recurrence != "" && task.DueDate == nil {
+		return nil, ErrRecurrenceNeedsDueDate
+	}
+
+	previousStatus := task.Status
+	wasDone := task.Status == models.StatusDone
+	if patch.Status.Set {
+		status, err := requiredField(patch.Status, "status")
+		if err != nil {
+			return nil, err
+		}
+		if !slices.Contains(models.TaskStatuses, status) {
+			return nil, fmt.Errorf("%w: unknown status %q", ErrInvalidPatch, status)
+		}
+		if status != task.Status {
+			task.Status = status
+			task.Rank = 0 // в конец новой колонки
+		}
+	}
+	if task.Rank == 0 {
+		if _, err := s.checkWIP(task); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.save(task, wasDone); err != nil {
+		return nil, err
+	}
+	if oldKey != "" && task.Key != oldKey {
+		if err := s.repo.AddKeyAlias(task.ID, oldKey); err != nil {
+			return nil, err
+		}
+	}
+	s.publishTaskUpdated(id, audience, previousStatus)
+	return s.repo.FindByID(id)
+}
+
+// patchRecurrence: null или пустое правило снимает повторение; часовой пояс без правила
+// пересчитывает текущее правило
+func patchRecurrence(task *models.Task, patch dto.TaskPatch) error {
+	timezone := task.RecurrenceTimezone
+	if patch.RecurrenceTimezone.Set {
+		timezone = valueOrZero(patch.RecurrenceTimezone)
+	}
+
+	switch {
+	case patch.Recurrence.Set && valueOrZero(patch.Recurrence) == "":
+		clearRecurrence(task)
+		return nil
+	case patch.Recurrence.Set:
+		return setRecurrence(task, *patch.Recurrence.Value, timezone)
+	case patch.RecurrenceTimezone.Set:
+		if task.Recurrence == "" {
+			return ErrNotRecurring
+		}
+		return setRecurrence(task, task.Recurrence, timezone)
+	}
+	return nil
+}
+
+func (s *taskService) Delete(id uuid.UUID, ifMatch *int) error {
+	if err := s.inTx(func(tx *taskService) error {
+		return tx.delete(id, ifMatch)
+	}); err != nil {
+		return err
+	}
+	// Вложения удаляются каскадно, осиротевшее содержимое чистим после коммита
+	logCleanupError(s.blobs.DeleteOrphanBlobs(context.Background()))
+	return nil
 }
 
-func (s *taskService) UpdateStatus(id uuid.UUID, status models.TaskStatus) error {
+func (s *taskService) delete(id uuid.UUID, ifMatch *int) error {
 	task, err := s.repo.FindByID(id)
 	if err != nil {
 		return err
 	}
+	if err := checkVersion(task.Version, ifMatch); err != nil {
+		return err
+	}
+	if err := s.repo.Delete(id, ifMatch); err != nil {
+		return err
+	}
+	s.publishTaskDeleted(task)
+	return nil
+}
+
+func (s *taskService) List(filter dto.TaskFilter, page dto.PageRequest) (*dto.ListResponse[models.Task], error) {
+	if filter.Query != "" {
+		if err := s.applyQuery(&filter); err != nil {
+			return nil, err
+		}
+	}
+	return s.repo.List(filter, page)
+}
+
+// UpdateStatus переводит задачу в другую колонку; при превышении нестрогого WIP-лимита
+// перевод выполняется и возвращается предупреждение
+func (s *taskService) UpdateStatus(id uuid.UUID, status models.TaskStatus, ifMatch *int) (*dto.WIPWarning, error) {
+	var warning *dto.WIPWarning
+	err := s.inTx(func(tx *taskService) (err error) {
+		warning, err = tx.updateStatus(id, status, ifMatch)
+		return err
+	})
+	return warning, err
+}
+
+func (s *taskService) updateStatus(id uuid.UUID, status models.TaskStatus, ifMatch *int) (*dto.WIPWarning, error) {
+	task, err := s.repo.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkVersion(task.Version, ifMatch); err != nil {
+		return nil, err
+	}
+	if task.Status == status {
+		return nil, nil
+	}
+
+	previousStatus := task.Status
+	wasDone := task.Status == models.StatusDone
 	task.Status = status
-	return s.repo.Update(task)
+	task.Rank = 0 // в конец новой колонки
+	warning, err := s.checkWIP(task)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.save(task, wasDone); err != nil {
+		return nil, err
+	}
+	s.publishTaskUpdated(id, nil, previousStatus)
+	return warning, nil
+}
+
+// Move переставляет задачу на доске: меняет статус и ставит между соседями,
+// меняя ранг только у самой задачи
+func (s *taskService) Move(id uuid.UUID, req MoveTaskRequest) (*models.Task, *dto.WIPWarning, error) {
+	var task *models.Task
+	var warning *dto.WIPWarning
+	err := s.inTx(func(tx *taskService) (err error) {
+		task, warning, err = tx.move(id, req)
+		return err
+	})
+	return task, warning, err
+}
+
+func (s *taskService) move(id uuid.UUID, req MoveTaskRequest) (*models.Task, *dto.WIPWarning, error) {
+	if req.AfterID != nil && req.BeforeID != nil {
+		return nil, nil, ErrInvalidMove
+	}
+	for _, neighbor := range []*uuid.UUID{req.AfterID, req.BeforeID} {
+		if neighbor != nil && *neighbor == id {
+			return nil, nil, ErrInvalidMove
+		}
+	}
+
+	task, err := s.repo.FindByID(id)
+	if err != nil {
+		return nil, nil, err
+	}
+	previousStatus := task.Status
+	wasDone := task.Status == models.StatusDone
+	var warning *dto.WIPWarning
+	if req.Status != "" && req.Status != task.Status {
+		task.Status = req.Status
+		if warning, err = s.checkWIP(task); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if err := s.repo.PlaceRank(task, req.AfterID, req.BeforeID); err != nil {
+		return nil, nil, err
+	}
+	if err := s.save(task, wasDone); err != nil {
+		return nil, nil, err
+	}
+	s.publishTaskUpdated(id, nil, previousStatus)
+	task, err = s.repo.FindByID(id)
+	return task, warning, err
+}
+
+// checkProjectVisible проверяет, что проект задачи (nil — без проекта) виден автору изменения.
+// Владелец задачи видит весь её проект, поэтому задача в чужом проекте открыла бы к нему доступ
+func (s *taskService) checkProjectVisible(projectID *uuid.UUID, userID uuid.UUID) error {
+	if projectID == nil {
+		return nil
+	}
+	visible, err := s.projects.IsVisible(*projectID, userID)
+	if err != nil {
+		return err
+	}
+	if !visible {
+		return ErrProjectNotFound
+	}
+	return nil
+}
+
+// moveToProject переносит задачу в другой проект (nil — без проекта). В новом проекте задача
+// получает следующий номер и встаёт в конец колонки; старый ключ сохраняется псевдонимом после save
+func moveToProject(task *models.Task, projectID *uuid.UUID) {
+	if projectID == task.ProjectID || projectID != nil && task.ProjectID != nil && *projectID == *task.ProjectID {
+		return
+	}
+	task.ProjectID = projectID
+	task.Number = nil
+	task.Project = nil
+	task.Rank = 0
+}
+
+// checkWIP проверяет лимит колонки, в которую переходит задача. Строгий лимит
+// запрещает переход, нестрогий — возвращает предупреждение. Вызывается в транзакции
+// изменения: строка проекта блокируется до коммита, иначе два параллельных перехода
+// посчитают колонку одновременно и оба пройдут
+func (s *taskService) checkWIP(task *models.Task) (*dto.WIPWarning, error) {
+	if task.ProjectID == nil {
+		return nil, nil
+	}
+	project := task.Project
+	if project == nil || project.ID != *task.ProjectID {
+		var err error
+		if project, err = s.projects.FindByID(*task.ProjectID); err != nil {
+			return nil, err
+		}
+	}
+	if _, ok := project.WIPLimits[task.Status]; !ok {
+		return nil, nil
+	}
+
+	// Лимиты перечитываются из заблокированной строки: их могли изменить до блокировки
+	project, err := s.repo.LockProject(*task.ProjectID)
+	if err != nil {
+		return nil, err
+	}
+	limit, ok := project.WIPLimits[task.Status]
+	if !ok {
+		return nil, nil
+	}
+
+	count, err := s.repo.CountInColumn(task.ColumnID(), task.Status, task.ID)
+	if err != nil {
+		return nil, err
+	}
+	if count < int64(limit) {
+		return nil, nil
+	}
+	if project.WIPStrict {
+		return nil, fmt.Errorf("%w: %s allows %d tasks", ErrWIPLimitReached, task.Status, limit)
+	}
+	return &dto.WIPWarning{Status: task.Status, Limit: limit, Count: count + 1}, nil
+}
+
+// SkipOccurrence переносит срок на следующее вхождение, не создавая новую задачу
+func (s *taskService) SkipOccurrence(id uuid.UUID) (*models.Task, error) {
+	var task *models.Task
+	err := s.inTx(func(tx *taskService) (err error) {
+		task, err = tx.skipOccurrence(id)
+		return err
+	})
+	return task, err
+}
+
+func (s *taskService) skipOccurrence(id uuid.UUID) (*models.Task, error) {
+	task, err := s.repo.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+	next, err := nextOccurrence(task)
+	if err != nil {
+		return nil, err
+	}
+	if next == nil {
+		return nil, ErrNoNextOccurrence
+	}
+	task.DueDate = next
+	if err := s.repo.Update(task); err != nil {
+		return nil, err
+	}
+	s.publishTask(EventTaskUpdated, id, nil)
+	return task, nil
+}
+
+func (s *taskService) StopRecurrence(id uuid.UUID) (*models.Task, error) {
+	var task *models.Task
+	err := s.inTx(func(tx *taskService) (err error) {
+		task, err = tx.stopRecurrence(id)
+		return err
+	})
+	return task, err
+}
+
+func (s *taskService) stopRecurrence(id uuid.UUID) (*models.Task, error) {
+	task, err := s.repo.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if task.Recurrence == "" {
+		return nil, ErrNotRecurring
+	}
+	clearRecurrence(task)
+	if err := s.repo.Update(task); err != nil {
+		return nil, err
+	}
+	s.publishTask(EventTaskUpdated, id, nil)
+	return task, nil
+}
+
+// ResolveKey находит задачу по ключу вида API-42 (регистр не важен), в том числе по ключу,
+// который был у задачи до переноса в другой проект
+func (s *taskService) ResolveKey(key string) (uuid.UUID, error) {
+	i := strings.LastIndexByte(key, '-')
+	if i < 0 {
+		return uuid.Nil, ErrInvalidTaskKey
+	}
+	projectKey := strings.ToUpper(key[:i])
+	number, err := strconv.Atoi(key[i+1:])
+	if err != nil || number <= 0 || !models.ValidProjectKey(projectKey) {
+		return uuid.Nil, ErrInvalidTaskKey
+	}
+	return s.repo.FindIDByKey(projectKey, number)
+}
+
+// save сохраняет задачу; при переводе повторяющейся задачи в done
+// правило переезжает на новое вхождение со сдвинутым сроком
+func (s *taskService) save(task *models.Task, wasDone bool) error {
+	switch {
+	case !wasDone && task.Status == models.StatusDone:
+		now := time.Now()
+		task.CompletedAt = &now
+		// У выполненной задачи не остаётся работы
+		if task.Estimate != nil {
+			zero := 0.0
+			task.RemainingEstimate = &zero
+		}
+	case task.Status != models.StatusDone:
+		task.CompletedAt = nil
+	}
+
+	if wasDone || task.Status != models.StatusDone || task.Recurrence == "" {
+		return s.repo.Update(task)
+	}
+
+	due, err := nextOccurrence(task)
+	if err != nil {
+		return err
+	}
+	var next *models.Task
+	if due != nil {
+		next = nextTaskInSeries(task, *due)
+	}
+	clearRecurrence(task)
+
+	if next == nil {
+		return s.repo.Update(task)
+	}
+	if err := s.repo.CompleteRecurring(task, next); err != nil {
+		return err
+	}
+	s.publishTask(EventTaskCreated, next.ID, nil)
+	return nil
+}
+
+func setEstimates(task *models.Task, estimate, remaining *float64) error {
+	if (estimate != nil && *estimate < 0) || (remaining != nil && *remaining < 0) {
+		return ErrInvalidEstimate
+	}
+	if estimate != nil {
+		task.Estimate = estimate
+	}
+	if remaining != nil {
+		task.RemainingEstimate = remaining
+	}
+	return nil
 }
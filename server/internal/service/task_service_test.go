@@ -0,0 +1,155 @@
+package service
+
+import (
+	"errors"
+	"github.com/google/uuid"
+	"task-tracker/internal/dto"
+	"task-tracker/internal/models"
+	"task-tracker/internal/repository"
+	"testing"
+)
+
+func TestCheckWIPLocksProjectAndUsesLockedLimits(t *testing.T) {
+	project := &models.Project{ID: uuid.New(), Name: "Board", WIPLimits: map[models.TaskStatus]int{models.StatusInProgress: 1}}
+	projects := newFakeProjectRepo(project)
+	busy := &models.Task{ID: uuid.New(), ProjectID: &project.ID, Status: models.StatusInProgress}
+	task := &models.Task{ID: uuid.New(), ProjectID: &project.ID, Status: models.StatusTodo}
+	repo := newFakeTaskRepo(busy, task)
+	repo.projects = projects
+	svc := &taskService{repo: repo, projects: projects}
+
+	task.Status = models.StatusInProgress
+	warning, err := svc.checkWIP(task)
+	if err != nil || warning == nil || warning.Count != 2 {
+		t.Fatalf("soft limit = %+v, %v; want a warning with count 2", warning, err)
+	}
+	if len(repo.locks) != 1 || repo.locks[0] != project.ID {
+		t.Errorf("locks = %v, want the project row locked", repo.locks)
+	}
+
+	// Строгость включили, пока задача читалась: решает заблокированная строка
+	task.Project = &models.Project{ID: project.ID, WIPLimits: project.WIPLimits}
+	project.WIPStrict = true
+	if _, err := svc.checkWIP(task); !errors.Is(err, ErrWIPLimitReached) {
+		t.Errorf("strict limit error = %v, want ErrWIPLimitReached", err)
+	}
+
+	repo.locks = nil
+	task.Project = nil
+	task.Status = models.StatusDone
+	if warning, err := svc.checkWIP(task); err != nil || warning != nil {
+		t.Errorf("column without limit = %+v, %v", warning, err)
+	}
+	if len(repo.locks) != 0 {
+		t.Errorf("column without limit locked the project")
+	}
+}
+
+func TestPatchRejectsProjectInvisibleToCaller(t *testing.T) {
+	user := uuid.New()
+	own := &models.Project{ID: uuid.New(), Name: "Mine"}
+	foreign := &models.Project{ID: uuid.New(), Name: "Foreign"}
+	projects := newFakeProjectRepo(own, foreign)
+	projects.visible[foreign.ID] = false
+	task := &models.Task{ID: uuid.New(), Title: "Task", ProjectID: &own.ID, Status: models.StatusTodo, Version: 1}
+	repo := newFakeTaskRepo(task)
+	repo.projects = projects
+	svc := &taskService{repo: repo, projects: projects}
+
+	var patch dto.TaskPatch
+	patch.ProjectID = dto.Field[uuid.UUID]{Set: true, Value: &foreign.ID}
+	if _, err := svc.Patch(task.ID, user, patch, nil); !errors.Is(err, ErrProjectNotFound) {
+		t.Fatalf("error = %v, want ErrProjectNotFound", err)
+	}
+	if *repo.tasks[task.ID].ProjectID != own.ID {
+		t.Errorf("task moved into a project the caller cannot see")
+	}
+	if len(repo.events) != 0 {
+		t.Errorf("events = %v, want none", repo.events)
+	}
+}
+
+func TestCreateRejectsProjectInvisibleToCaller(t *testing.T) {
+	user := uuid.New()
+	own := &models.Project{ID: uuid.New(), Name: "Mine"}
+	foreign := &models.Project{ID: uuid.New(), Name: "Foreign"}
+	projects := newFakeProjectRepo(own, foreign)
+	projects.visible[foreign.ID] = false
+	repo := newFakeTaskRepo()
+	svc := &taskService{repo: repo, projects: projects}
+
+	req := CreateTaskRequest{Title: "Task", Priority: models.PriorityMedium, ProjectID: &foreign.ID}
+	if _, err := svc.Create(req, user); !errors.Is(err, ErrProjectNotFound) {
+		t.Fatalf("error = %v, want ErrProjectNotFound", err)
+	}
+	if len(repo.tasks) != 0 || len(repo.events) != 0 {
+		t.Fatalf("tasks = %d, events = %v; want nothing created", len(repo.tasks), repo.events)
+	}
+
+	req.ProjectID = &own.ID
+	task, err := svc.Create(req, user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *repo.tasks[task.ID].ProjectID != own.ID {
+		t.Errorf("task was not created in the caller's project")
+	}
+}
+
+func TestUpdateRejectsProjectInvisibleToCaller(t *testing.T) {
+	user := uuid.New()
+	own := &models.Project{ID: uuid.New(), Name: "Mine"}
+	foreign := &models.Project{ID: uuid.New(), Name: "Foreign"}
+	projects := newFakeProjectRepo(own, foreign)
+	projects.visible[foreign.ID] = false
+	task := &models.Task{ID: uuid.New(), Title: "Task", ProjectID: &own.ID, Status: models.StatusTodo, Version: 1}
+	repo := newFakeTaskRepo(task)
+	repo.projects = projects
+	svc := &taskService{repo: repo, projects: projects}
+
+	if err := svc.Update(task.ID, user, UpdateTaskRequest{Title: "Renamed", ProjectID: &foreign.ID}); !errors.Is(err, ErrProjectNotFound) {
+		t.Fatalf("error = %v, want ErrProjectNotFound", err)
+	}
+	if got := repo.tasks[task.ID]; *got.ProjectID != own.ID || got.Title != "Task" {
+		t.Errorf("task = %+v, want it unchanged", got)
+	}
+	if len(repo.events) != 0 {
+		t.Errorf("events = %v, want none", repo.events)
+	}
+}
+
+// racingTaskRepo изменяет задачу сразу после того, как её прочитали
+type racingTaskRepo struct {
+	*fakeTaskRepo
+}
+
+func (r racingTaskRepo) FindByID(id uuid.UUID) (*models.Task, error) {
+	task, err := r.fakeTaskRepo.FindByID(id)
+	if err == nil {
+		r.tasks[id].Version++
+	}
+	return task, err
+}
+
+func TestDeleteIsConditionalOnVersion(t *testing.T) {
+	task := &models.Task{ID: uuid.New(), Title: "Task", Version: 3}
+	repo := newFakeTaskRepo(task)
+	svc := &taskService{repo: racingTaskRepo{repo}}
+
+	version := 3
+	if err := svc.delete(task.ID, &version); !errors.Is(err, repository.ErrVersionConflict) {
+		t.Fatalf("error = %v, want ErrVersionConflict", err)
+	}
+	if _, ok := repo.tasks[task.ID]; !ok {
+		t.Fatal("task changed after the If-Match check was deleted")
+	}
+
+	svc = &taskService{repo: repo, events: &pendingEvents{}}
+	version = 4
+	if err := svc.delete(task.ID, &version); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := repo.tasks[task.ID]; ok {
+		t.Error("task was not deleted")
+	}
+}
@@ -0,0 +1,200 @@
+package service
+
+import (
+	"errors"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"task-tracker/internal/dto"
+	"task-tracker/internal/models"
+	"task-tracker/internal/repository"
+	"time"
+)
+
+// Ручная запись не может быть длиннее суток
+const maxManualEntry = 24 * time.Hour
+
+// Отчёт строится не более чем за год
+const maxReportRange = 366 * 24 * time.Hour
+
+var (
+	ErrForbidden         = errors.New("access denied")
+	ErrNoRunningTimer    = errors.New("no running timer")
+	ErrInvalidTimeRange  = errors.New("invalid time range")
+	ErrTimeEntryNotFound = errors.New("time entry not found")
+)
+
+type ManualTimeEntryRequest struct {
+	StartedAt time.Time
+	EndedAt   time.Time
+	Note      string
+}
+
+type UpdateTimeEntryRequest struct {
+	StartedAt *time.Time
+	EndedAt   *time.Time
+	Note      *string
+}
+
+type TimeTrackingService interface {
+	StartTimer(taskID, userID uuid.UUID, note string) (*models.TimeEntry, error)
+	StopTimer(userID uuid.UUID) (*models.TimeEntry, error)
+	CurrentTimer(userID uuid.UUID) (*models.TimeEntry, error)
+	AddEntry(taskID, userID uuid.UUID, req ManualTimeEntryRequest) (*models.TimeEntry, error)
+	ListEntries(taskID uuid.UUID) ([]models.TimeEntry, error)
+	UpdateEntry(id, userID uuid.UUID, req UpdateTimeEntryRequest) (*models.TimeEntry, error)
+	DeleteEntry(id, userID uuid.UUID) error
+	Report(filter dto.TimeReportFilter) ([]dto.TimeReportRow, error)
+}
+
+type timeTrackingService struct {
+	repo     repository.TimeEntryRepository
+	taskRepo repository.TaskRepository
+	now      func() time.Time
+}
+
+func NewTimeTrackingService(repo repository.TimeEntryRepository, taskRepo repository.TaskRepository) TimeTrackingService {
+	return &timeTrackingService{
+		repo:     repo,
+		taskRepo: taskRepo,
+		now:      time.Now,
+	}
+}
+
+func (s *timeTrackingService) StartTimer(taskID, userID uuid.UUID, note string) (*models.TimeEntry, error) {
+	if _, err := s.taskRepo.FindByID(taskID); err != nil {
+		return nil, err
+	}
+
+	entry := &models.TimeEntry{
+		TaskID:    taskID,
+		UserID:    userID,
+		StartedAt: s.now().UTC(),
+		Note:      note,
+	}
+	if _, err := s.repo.StartTimer(entry); err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+func (s *timeTrackingService) StopTimer(userID uuid.UUID) (*models.TimeEntry, error) {
+	entry, err := s.repo.FindRunning(userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNoRunningTimer
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.now().UTC()
+	entry.EndedAt = &now
+	return entry, s.repo.Update(entry)
+}
+
+func (s *timeTrackingService) CurrentTimer(userID uuid.UUID) (*models.TimeEntry, error) {
+	entry, err := s.repo.FindRunning(userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return entry, err
+}
+
+func (s *timeTrackingService) AddEntry(taskID, userID uuid.UUID, req ManualTimeEntryRequest) (*models.TimeEntry, error) {
+	if err := s.validateRange(req.StartedAt, req.EndedAt); err != nil {
+		return nil, err
+	}
+	if _, err := s.taskRepo.FindByID(taskID); err != nil {
+		return nil, err
+	}
+
+	ended := req.EndedAt.UTC()
+	entry := &models.TimeEntry{
+		TaskID:    taskID,
+		UserID:    userID,
+		StartedAt: req.StartedAt.UTC(),
+		EndedAt:   &ended,
+		Note:      req.Note,
+		Manual:    true,
+	}
+	return entry, s.repo.Create(entry)
+}
+
+func (s *timeTrackingService) ListEntries(taskID uuid.UUID) ([]models.TimeEntry, error) {
+	if _, err := s.taskRepo.FindByID(taskID); err != nil {
+		return nil, err
+	}
+	return s.repo.ListByTask(taskID)
+}
+
+func (s *timeTrackingService) UpdateEntry(id, userID uuid.UUID, req UpdateTimeEntryRequest) (*models.TimeEntry, error) {
+	entry, err := s.findOwn(id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.StartedAt != nil {
+		entry.StartedAt = req.StartedAt.UTC()
+	}
+	if req.EndedAt != nil {
+		if entry.EndedAt == nil {
+			// Запущенный таймер останавливается только через StopTimer
+			return nil, ErrInvalidTimeRange
+		}
+		ended := req.EndedAt.UTC()
+		entry.EndedAt = &ended
+	}
+	if req.Note != nil {
+		entry.Note = *req.Note
+	}
+
+	if entry.EndedAt != nil {
+		if err := s.validateRange(entry.StartedAt, *entry.EndedAt); err != nil {
+			return nil, err
+		}
+	} else if entry.StartedAt.After(s.now()) {
+		return nil, ErrInvalidTimeRange
+	}
+
+	return entry, s.repo.Update(entry)
+}
+
+func (s *timeTrackingService) DeleteEntry(id, userID uuid.UUID) error {
+	if _, err := s.findOwn(id, userID); err != nil {
+		return err
+	}
+	return s.repo.Delete(id)
+}
+
+func (s *timeTrackingService) Report(filter dto.TimeReportFilter) ([]dto.TimeReportRow, error) {
+	if !filter.To.After(filter.From) || filter.To.Sub(filter.From) > maxReportRange {
+		return nil, ErrInvalidTimeRange
+	}
+	if !filter.GroupByProject && !filter.GroupByUser && !filter.GroupByDay {
+		filter.GroupByProject, filter.GroupByUser, filter.GroupByDay = true, true, true
+	}
+	if filter.Timezone == "" {
+		filter.Timezone = "UTC"
+	}
+	return s.repo.Report(filter)
+}
+
+func (s *timeTrackingService) findOwn(id, userID uuid.UUID) (*models.TimeEntry, error) {
+	entry, err := s.repo.FindByID(id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrTimeEntryNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if entry.UserID != userID {
+		return nil, ErrForbidden
+	}
+	return entry, nil
+}
+
+func (s *timeTrackingService) validateRange(start, end time.Time) error {
+	if !end.After(start) || end.Sub(start) > maxManualEntry || end.After(s.now().Add(time.Minute)) {
+		return ErrInvalidTimeRange
+	}
+	return nil
+}
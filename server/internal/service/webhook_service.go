@@ -0,0 +1,490 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"task-tracker/internal/dto"
+	"task-tracker/internal/models"
+	"task-tracker/internal/repository"
+	"task-tracker/pkg/events"
+	"time"
+)
+
+var (
+	ErrWebhookNotFound  = errors.New("webhook not found")
+	ErrDeliveryNotFound = errors.New("webhook delivery not found")
+	ErrInvalidWebhook   = errors.New("invalid webhook")
+	// ErrWebhookAddress — адрес получателя во внутренней сети; попытка доставки с ней неудачна
+	ErrWebhookAddress = errors.New("webhook address is not allowed")
+)
+
+// Заголовки запроса вебхука. Подпись — HMAC-SHA256 ключом вебхука от строки "<timestamp>.<тело>";
+// получатель проверяет её и отвергает запросы со старой меткой времени
+const (
+	WebhookEventHeader     = "X-Webhook-Event"
+	WebhookDeliveryHeader  = "X-Webhook-Delivery"
+	WebhookTimestampHeader = "X-Webhook-Timestamp"
+	WebhookSignatureHeader = "X-Webhook-Signature"
+)
+
+const (
+	webhookTimeout = 10 * time.Second
+	// Попытки одной доставки; паузы между ними растут вдвое, начиная с минуты
+	maxDeliveryAttempts = 8
+	deliveryRetryBase   = time.Minute
+	// Неудачные попытки подряд, после которых вебхук отключается
+	webhookFailureThreshold = 20
+	deliveryBatchSize       = 20
+	// Пока идёт попытка, доставку не заберёт другой обработчик; должно быть больше webhookTimeout
+	deliveryLease         = 2 * time.Minute
+	maxLoggedResponseBody = 1024
+	maxDeliveryListLimit  = 100
+)
+
+// Сколько хранится журнал завершённых доставок
+const WebhookDeliveryRetention = 30 * 24 * time.Hour
+
+// Типы событий, на которые можно подписать вебхук, кроме масок "task.*", "project.*" и "*"
+var webhookEventTypes = []string{
+	EventTaskCreated, EventTaskUpdated, EventTaskStatusChanged, EventTaskDeleted,
+	EventProjectCreated, EventProjectUpdated, EventProjectDeleted,
+}
+
+type WebhookConfig struct {
+	// AllowPrivateNetworks разрешает доставку на loopback, частные и link-local адреса.
+	// По умолчанию запрещено: иначе владелец проекта мог бы слать запросы от имени сервера
+	// во внутреннюю сеть (SSRF). Нужно тестам с локальным получателем и закрытым установкам
+	AllowPrivateNetworks bool
+}
+
+// Диапазоны, не отмеченные как частные в net/netip, но тоже не ведущие в интернет
+var reservedWebhookPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+}
+
+type CreateWebhookRequest struct {
+	URL    string
+	Events []string
+	Active *bool
+}
+
+type UpdateWebhookRequest struct {
+	URL    string
+	Events []string
+	Active *bool
+}
+
+// WebhookService управляет вебхуками проекта (только владелец проекта) и доставляет на них
+// события: Enqueue ставит доставки в очередь, DeliverDue отправляет те, которым пора
+type WebhookService interface {
+	Enqueue(e events.Event) error
+	Create(projectID, userID uuid.UUID, req CreateWebhookRequest) (*dto.CreatedWebhook, error)
+	List(projectID, userID uuid.UUID) ([]models.Webhook, error)
+	Get(projectID, id, userID uuid.UUID) (*models.Webhook, error)
+	Update(projectID, id, userID uuid.UUID, req UpdateWebhookRequest) (*models.Webhook, error)
+	Delete(projectID, id, userID uuid.UUID) error
+	Deliveries(projectID, id, userID uuid.UUID, status models.DeliveryStatus, limit int) ([]models.WebhookDelivery, error)
+	Redeliver(projectID, id, deliveryID, userID uuid.UUID) (*models.WebhookDelivery, error)
+	DeliverDue(ctx context.Context) (int, error)
+	PurgeDeliveries() (int64, error)
+}
+
+type webhookService struct {
+	repo     repository.WebhookRepository
+	projects repository.ProjectRepository
+	client   *http.Client
+}
+
+func NewWebhookService(repo repository.WebhookRepository, projects repository.ProjectRepository, config WebhookConfig) WebhookService {
+	dialer := &net.Dialer{Timeout: webhookTimeout}
+	if !config.AllowPrivateNetworks {
+		dialer.Control = checkWebhookAddress
+	}
+	return &webhookService{
+		repo:     repo,
+		projects: projects,
+		client: &http.Client{
+			Timeout: webhookTimeout,
+			// Адрес проверяется при соединении, уже после разрешения имени: так не пройдут ни
+			// имена, указывающие на внутренние адреса, ни подмена DNS после создания вебхука.
+			// Прокси из окружения не используется, иначе проверялся бы адрес прокси
+			Transport: &http.Transport{
+				DialContext:         dialer.DialContext,
+				TLSHandshakeTimeout: webhookTimeout,
+				MaxIdleConnsPerHost: 2,
+				IdleConnTimeout:     time.Minute,
+			},
+			// Перенаправление — ошибка настройки получателя, а не повод слать подписанное тело дальше
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+}
+
+func (s *webhookService) Create(projectID, userID uuid.UUID, req CreateWebhookRequest) (*dto.CreatedWebhook, error) {
+	if err := s.checkOwner(projectID, userID); err != nil {
+		return nil, err
+	}
+	secret, err := newWebhookSecret()
+	if err != nil {
+		return nil, err
+	}
+	webhook := &models.Webhook{
+		ProjectID: projectID,
+		URL:       strings.TrimSpace(req.URL),
+		Events:    req.Events,
+		Secret:    secret,
+		Active:    req.Active == nil || *req.Active,
+	}
+	if err := validateWebhook(webhook); err != nil {
+		return nil, err
+	}
+	if err := s.repo.Create(webhook); err != nil {
+		return nil, err
+	}
+	return &dto.CreatedWebhook{Webhook: *webhook, Secret: secret}, nil
+}
+
+func (s *webhookService) List(projectID, userID uuid.UUID) ([]models.Webhook, error) {
+	if err := s.checkOwner(projectID, userID); err != nil {
+		return nil, err
+	}
+	return s.repo.ListByProject(projectID)
+}
+
+func (s *webhookService) Get(projectID, id, userID uuid.UUID) (*models.Webhook, error) {
+	if err := s.checkOwner(projectID, userID); err != nil {
+		return nil, err
+	}
+	webhook, err := s.repo.FindByID(id)
+	if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && webhook.ProjectID != projectID) {
+		return nil, ErrWebhookNotFound
+	}
+	return webhook, err
+}
+
+// Update меняет адрес, подписки и активность. Включение сбрасывает счётчик неудач:
+// отложенные доставки пойдут заново
+func (s *webhookService) Update(projectID, id, userID uuid.UUID, req UpdateWebhookRequest) (*models.Webhook, error) {
+	webhook, err := s.Get(projectID, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if req.URL != "" {
+		webhook.URL = strings.TrimSpace(req.URL)
+	}
+	if req.Events != nil {
+		webhook.Events = req.Events
+	}
+	if req.Active != nil && *req.Active != webhook.Active {
+		webhook.Active = *req.Active
+		webhook.FailureCount = 0
+		webhook.DisabledReason = ""
+		webhook.DisabledAt = nil
+		if !webhook.Active {
+			now := time.Now()
+			webhook.DisabledReason = "disabled by owner"
+			webhook.DisabledAt = &now
+		}
+	}
+	if err := validateWebhook(webhook); err != nil {
+		return nil, err
+	}
+	return webhook, s.repo.Update(webhook)
+}
+
+func (s *webhookService) Delete(projectID, id, userID uuid.UUID) error {
+	if _, err := s.Get(projectID, id, userID); err != nil {
+		return err
+	}
+	return s.repo.Delete(id)
+}
+
+func (s *webhookService) Deliveries(projectID, id, userID uuid.UUID, status models.DeliveryStatus, limit int) ([]models.WebhookDelivery, error) {
+	if _, err := s.Get(projectID, id, userID); err != nil {
+		return nil, err
+	}
+	switch status {
+	case "", models.DeliveryPending, models.DeliverySucceeded, models.DeliveryFailed:
+	default:
+		return nil, fmt.Errorf("%w: unknown delivery status %q", ErrInvalidWebhook, status)
+	}
+	if limit <= 0 || limit > maxDeliveryListLimit {
+		limit = maxDeliveryListLimit
+	}
+	return s.repo.ListDeliveries(id, status, limit)
+}
+
+// Redeliver ставит в очередь копию доставки с тем же событием. Отправлена она будет,
+// только пока вебхук включён
+func (s *webhookService) Redeliver(projectID, id, deliveryID, userID uuid.UUID) (*models.WebhookDelivery, error) {
+	if _, err := s.Get(projectID, id, userID); err != nil {
+		return nil, err
+	}
+	original, err := s.repo.FindDelivery(deliveryID)
+	if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && original.WebhookID != id) {
+		return nil, ErrDeliveryNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	// ID задаём сами: CreateDeliveries сохраняет копию из среза, и сгенерированный
+	// при вставке ID до этой переменной не дошёл бы
+	delivery := models.WebhookDelivery{
+		ID:            uuid.New(),
+		WebhookID:     id,
+		EventID:       original.EventID,
+		EventType:     original.EventType,
+		Payload:       original.Payload,
+		RedeliveryOf:  &original.ID,
+		Status:        models.DeliveryPending,
+		NextAttemptAt: &now,
+	}
+	if err := s.repo.CreateDeliveries([]models.WebhookDelivery{delivery}); err != nil {
+		return nil, err
+	}
+	return s.repo.FindDelivery(delivery.ID)
+}
+
+// Enqueue ставит событие из outbox в очередь доставки всем включённым вебхукам его проекта,
+// подписанным на этот тип. События задач без проекта вебхукам не отправляются
+func (s *webhookService) Enqueue(e events.Event) error {
+	if e.ProjectID == uuid.Nil {
+		return nil
+	}
+	webhooks, err := s.repo.ListActive(e.ProjectID)
+	if err != nil {
+		return err
+	}
+	webhooks = slices.DeleteFunc(webhooks, func(w models.Webhook) bool {
+		return !webhookSubscribed(w.Events, e.Type)
+	})
+	if len(webhooks) == 0 {
+		return nil
+	}
+
+	payload, err := json.Marshal(dto.WebhookPayload{
+		ID:        e.ID,
+		Type:      e.Type,
+		ProjectID: e.ProjectID,
+		CreatedAt: e.Time,
+		Data:      e.Data,
+	})
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	deliveries := make([]models.WebhookDelivery, len(webhooks))
+	for i, webhook := range webhooks {
+		deliveries[i] = models.WebhookDelivery{
+			WebhookID:     webhook.ID,
+			EventID:       e.ID,
+			EventType:     e.Type,
+			Payload:       payload,
+			Status:        models.DeliveryPending,
+			NextAttemptAt: &now,
+		}
+	}
+	return s.repo.CreateDeliveries(deliveries)
+}
+
+// DeliverDue отправляет доставки, которым пора уйти, и возвращает их число
+func (s *webhookService) DeliverDue(ctx context.Context) (int, error) {
+	deliveries, err := s.repo.ClaimDue(deliveryBatchSize, deliveryLease)
+	if err != nil {
+		return 0, err
+	}
+	var wg sync.WaitGroup
+	for i := range deliveries {
+		wg.Add(1)
+		go func(delivery *models.WebhookDelivery) {
+			defer wg.Done()
+			s.attempt(ctx, delivery)
+		}(&deliveries[i])
+	}
+	wg.Wait()
+	return len(deliveries), nil
+}
+
+func (s *webhookService) attempt(ctx context.Context, delivery *models.WebhookDelivery) {
+	if delivery.Webhook == nil {
+		return // вебхук удалён, доставка ушла вместе с ним
+	}
+	started := time.Now()
+	status, body, sendErr := s.send(ctx, delivery.Webhook, delivery, started)
+
+	delivery.Attempts++
+	delivery.LastAttemptAt = &started
+	delivery.DurationMs = time.Since(started).Milliseconds()
+	delivery.ResponseStatus, delivery.ResponseBody, delivery.Error = status, body, ""
+	switch {
+	case sendErr == nil:
+		delivery.Status, delivery.NextAttemptAt = models.DeliverySucceeded, nil
+	case delivery.Attempts >= maxDeliveryAttempts:
+		delivery.Status, delivery.NextAttemptAt, delivery.Error = models.DeliveryFailed, nil, sendErr.Error()
+	default:
+		next := time.Now().Add(deliveryRetryBase << (delivery.Attempts - 1))
+		delivery.NextAttemptAt, delivery.Error = &next, sendErr.Error()
+	}
+	if err := s.repo.SaveDelivery(delivery); err != nil {
+		log.Printf("Failed to save webhook delivery %s: %v", delivery.ID, err)
+	}
+
+	if sendErr == nil {
+		if err := s.repo.RecordSuccess(delivery.WebhookID); err != nil {
+			log.Printf("Failed to reset failures of webhook %s: %v", delivery.WebhookID, err)
+		}
+		return
+	}
+	reason := fmt.Sprintf("%d consecutive failed deliveries, last: %v", webhookFailureThreshold, sendErr)
+	failures, err := s.repo.RecordFailure(delivery.WebhookID, webhookFailureThreshold, reason)
+	if err != nil {
+		log.Printf("Failed to count failures of webhook %s: %v", delivery.WebhookID, err)
+	} else if failures == webhookFailureThreshold {
+		log.Printf("Webhook %s disabled after %d consecutive failures", delivery.WebhookID, failures)
+	}
+}
+
+// send отправляет подписанное тело; успех — только ответ 2xx
+func (s *webhookService) send(ctx context.Context, webhook *models.Webhook, delivery *models.WebhookDelivery, now time.Time) (int, string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, "", err
+	}
+	timestamp := now.Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "TaskTracker-Webhooks/1.0")
+	req.Header.Set(WebhookEventHeader, delivery.EventType)
+	req.Header.Set(WebhookDeliveryHeader, delivery.ID.String())
+	req.Header.Set(WebhookTimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(WebhookSignatureHeader, SignWebhook(webhook.Secret, timestamp, delivery.Payload))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxLoggedResponseBody))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, string(body), fmt.Errorf("unexpected response status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, string(body), nil
+}
+
+func (s *webhookService) PurgeDeliveries() (int64, error) {
+	return s.repo.PurgeDeliveries(time.Now().Add(-WebhookDeliveryRetention))
+}
+
+// SignWebhook вычисляет значение заголовка подписи: "sha256=" и hex HMAC-SHA256
+// от "<timestamp>.<body>"
+func SignWebhook(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// checkOwner пускает к вебхукам только владельца проекта; тем, кто проект не видит, он не найден
+func (s *webhookService) checkOwner(projectID, userID uuid.UUID) error {
+	project, err := s.projects.FindByID(projectID)
+	if err != nil {
+		return err
+	}
+	if project.UserID == userID {
+		return nil
+	}
+	visible, err := s.projects.IsVisible(projectID, userID)
+	if err != nil {
+		return err
+	}
+	if visible {
+		return ErrForbidden
+	}
+	return gorm.ErrRecordNotFound
+}
+
+// checkWebhookAddress — Control для net.Dialer: пропускает только адреса публичного интернета
+func checkWebhookAddress(network, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrWebhookAddress, address)
+	}
+	addr := addrPort.Addr().Unmap()
+	if !addr.IsGlobalUnicast() || addr.IsPrivate() {
+		return fmt.Errorf("%w: %s", ErrWebhookAddress, addr)
+	}
+	for _, prefix := range reservedWebhookPrefixes {
+		if prefix.Contains(addr) {
+			return fmt.Errorf("%w: %s", ErrWebhookAddress, addr)
+		}
+	}
+	return nil
+}
+
+func newWebhookSecret() (string, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(key), nil
+}
+
+// webhookSubscribed проверяет тип события по подпискам: точное имя, "task.*", "project.*" или "*"
+func webhookSubscribed(subscriptions []string, kind string) bool {
+	for _, sub := range subscriptions {
+		if sub == "*" || sub == kind {
+			return true
+		}
+		if prefix, ok := strings.CutSuffix(sub, "*"); ok && strings.HasPrefix(kind, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func validateWebhook(webhook *models.Webhook) error {
+	u, err := url.Parse(webhook.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%w: url must be an absolute http or https URL", ErrInvalidWebhook)
+	}
+	if len(webhook.Events) == 0 {
+		return fmt.Errorf("%w: subscribe to at least one event type", ErrInvalidWebhook)
+	}
+	for _, kind := range webhook.Events {
+		if kind != "*" && kind != "task.*" && kind != "project.*" && !slices.Contains(webhookEventTypes, kind) {
+			return fmt.Errorf("%w: unknown event type %q", ErrInvalidWebhook, kind)
+		}
+	}
+	slices.Sort(webhook.Events)
+	webhook.Events = slices.Compact(webhook.Events)
+	return nil
+}
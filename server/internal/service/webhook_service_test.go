@@ -0,0 +1,393 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"task-tracker/internal/models"
+	"task-tracker/internal/repository"
+	"task-tracker/pkg/events"
+	"testing"
+	"time"
+)
+
+// fakeWebhookRepo повторяет семантику webhookRepo в памяти, включая то, что gorm
+// присваивает ID при вставке элементам среза, а не исходным переменным
+type fakeWebhookRepo struct {
+	repository.WebhookRepository
+	mu         sync.Mutex
+	webhooks   map[uuid.UUID]*models.Webhook
+	deliveries map[uuid.UUID]*models.WebhookDelivery
+}
+
+func newFakeWebhookRepo(webhooks ...*models.Webhook) *fakeWebhookRepo {
+	r := &fakeWebhookRepo{webhooks: map[uuid.UUID]*models.Webhook{}, deliveries: map[uuid.UUID]*models.WebhookDelivery{}}
+	for _, webhook := range webhooks {
+		r.webhooks[webhook.ID] = webhook
+	}
+	return r
+}
+
+func (r *fakeWebhookRepo) FindByID(id uuid.UUID) (*models.Webhook, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	webhook, ok := r.webhooks[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	copied := *webhook
+	return &copied, nil
+}
+
+func (r *fakeWebhookRepo) ListActive(projectID uuid.UUID) ([]models.Webhook, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var result []models.Webhook
+	for _, webhook := range r.webhooks {
+		if webhook.ProjectID == projectID && webhook.Active {
+			result = append(result, *webhook)
+		}
+	}
+	return result, nil
+}
+
+func (r *fakeWebhookRepo) CreateDeliveries(deliveries []models.WebhookDelivery) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range deliveries {
+		if deliveries[i].ID == uuid.Nil {
+			deliveries[i].ID = uuid.New()
+		}
+		copied := deliveries[i]
+		r.deliveries[copied.ID] = &copied
+	}
+	return nil
+}
+
+func (r *fakeWebhookRepo) FindDelivery(id uuid.UUID) (*models.WebhookDelivery, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delivery, ok := r.deliveries[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	copied := *delivery
+	return &copied, nil
+}
+
+func (r *fakeWebhookRepo) ClaimDue(limit int, lease time.Duration) ([]models.WebhookDelivery, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now()
+	var claimed []models.WebhookDelivery
+	for _, delivery := range r.deliveries {
+		webhook := r.webhooks[delivery.WebhookID]
+		if len(claimed) == limit || delivery.Status != models.DeliveryPending ||
+			delivery.NextAttemptAt == nil || delivery.NextAttemptAt.After(now) || webhook == nil || !webhook.Active {
+			continue
+		}
+		next := now.Add(lease)
+		delivery.NextAttemptAt = &next
+		copied, hook := *delivery, *webhook
+		copied.Webhook = &hook
+		claimed = append(claimed, copied)
+	}
+	return claimed, nil
+}
+
+func (r *fakeWebhookRepo) SaveDelivery(delivery *models.WebhookDelivery) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	copied := *delivery
+	copied.Webhook = nil
+	r.deliveries[delivery.ID] = &copied
+	return nil
+}
+
+func (r *fakeWebhookRepo) RecordSuccess(webhookID uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.webhooks[webhookID].FailureCount = 0
+	return nil
+}
+
+func (r *fakeWebhookRepo) RecordFailure(webhookID uuid.UUID, threshold int, reason string) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	webhook := r.webhooks[webhookID]
+	webhook.FailureCount++
+	if webhook.Active && webhook.FailureCount >= threshold {
+		now := time.Now()
+		webhook.Active, webhook.DisabledAt, webhook.DisabledReason = false, &now, reason
+	}
+	return webhook.FailureCount, nil
+}
+
+// webhookReceiver — локальный получатель: отвечает status и запоминает запросы
+type webhookReceiver struct {
+	*httptest.Server
+	mu       sync.Mutex
+	status   int
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func newWebhookReceiver(t *testing.T) *webhookReceiver {
+	rcv := &webhookReceiver{status: http.StatusOK}
+	rcv.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		rcv.mu.Lock()
+		rcv.requests = append(rcv.requests, r)
+		rcv.bodies = append(rcv.bodies, body)
+		status := rcv.status
+		rcv.mu.Unlock()
+		w.WriteHeader(status)
+		io.WriteString(w, "received")
+	}))
+	t.Cleanup(rcv.Close)
+	return rcv
+}
+
+func (r *webhookReceiver) calls() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.requests)
+}
+
+type webhookFixture struct {
+	svc     *webhookService
+	repo    *fakeWebhookRepo
+	webhook *models.Webhook
+	owner   uuid.UUID
+	rcv     *webhookReceiver
+}
+
+func newWebhookFixture(t *testing.T, config WebhookConfig) *webhookFixture {
+	rcv := newWebhookReceiver(t)
+	owner := uuid.New()
+	project := &models.Project{ID: uuid.New(), Name: "Hooks", UserID: owner}
+	webhook := &models.Webhook{
+		ID:        uuid.New(),
+		ProjectID: project.ID,
+		URL:       rcv.URL + "/hook",
+		Events:    []string{"task.*"},
+		Secret:    "whsec_test",
+		Active:    true,
+	}
+	repo := newFakeWebhookRepo(webhook)
+	svc := NewWebhookService(repo, newFakeProjectRepo(project), config).(*webhookService)
+	return &webhookFixture{svc: svc, repo: repo, webhook: webhook, owner: owner, rcv: rcv}
+}
+
+func (f *webhookFixture) enqueue(t *testing.T) *models.WebhookDelivery {
+	t.Helper()
+	err := f.svc.Enqueue(events.Event{
+		ID:        uuid.NewString(),
+		Type:      EventTaskCreated,
+		ProjectID: f.webhook.ProjectID,
+		Data:      []byte(`{"title":"Task"}`),
+		Time:      time.Now(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, delivery := range f.repo.deliveries {
+		return delivery
+	}
+	t.Fatal("no delivery queued")
+	return nil
+}
+
+func TestWebhookDeliverySigned(t *testing.T) {
+	f := newWebhookFixture(t, WebhookConfig{AllowPrivateNetworks: true})
+	queued := f.enqueue(t)
+
+	n, err := f.svc.DeliverDue(context.Background())
+	if err != nil || n != 1 {
+		t.Fatalf("DeliverDue = %d, %v; want 1 delivery", n, err)
+	}
+	if f.rcv.calls() != 1 {
+		t.Fatalf("receiver got %d requests, want 1", f.rcv.calls())
+	}
+
+	req, body := f.rcv.requests[0], f.rcv.bodies[0]
+	if req.URL.Path != "/hook" || req.Header.Get(WebhookEventHeader) != EventTaskCreated || req.Header.Get(WebhookDeliveryHeader) != queued.ID.String() {
+		t.Errorf("request = %s %v", req.URL.Path, req.Header)
+	}
+	// Получатель проверяет подпись сам, без SignWebhook
+	mac := hmac.New(sha256.New, []byte(f.webhook.Secret))
+	io.WriteString(mac, req.Header.Get(WebhookTimestampHeader)+".")
+	mac.Write(body)
+	if want := "sha256=" + hex.EncodeToString(mac.Sum(nil)); req.Header.Get(WebhookSignatureHeader) != want {
+		t.Errorf("signature = %s, want %s", req.Header.Get(WebhookSignatureHeader), want)
+	}
+	if ts, err := strconv.ParseInt(req.Header.Get(WebhookTimestampHeader), 10, 64); err != nil || time.Since(time.Unix(ts, 0)) > time.Minute {
+		t.Errorf("timestamp = %q", req.Header.Get(WebhookTimestampHeader))
+	}
+
+	delivery := f.repo.deliveries[queued.ID]
+	if delivery.Status != models.DeliverySucceeded || delivery.Attempts != 1 || delivery.ResponseStatus != http.StatusOK ||
+		delivery.ResponseBody != "received" || delivery.NextAttemptAt != nil {
+		t.Errorf("delivery = %+v, want succeeded after one attempt", delivery)
+	}
+}
+
+func TestSignWebhook(t *testing.T) {
+	got := SignWebhook("secret", 1700000000, []byte(`{"a":1}`))
+	// printf '%s' '1700000000.{"a":1}' | openssl dgst -sha256 -hmac secret
+	want := "sha256=49f24e537407743fa4a0242bb63b94b9a47ee99cbbe071ccd8a22550ae411686"
+	if got != want {
+		t.Errorf("SignWebhook = %s, want %s", got, want)
+	}
+	if SignWebhook("secret", 1700000001, []byte(`{"a":1}`)) == got {
+		t.Error("signature does not depend on the timestamp")
+	}
+}
+
+func TestWebhookDeliveryBackoff(t *testing.T) {
+	f := newWebhookFixture(t, WebhookConfig{AllowPrivateNetworks: true})
+	f.rcv.status = http.StatusServiceUnavailable
+	queued := f.enqueue(t)
+
+	for attempt := 1; attempt <= maxDeliveryAttempts; attempt++ {
+		delivery, _ := f.repo.FindDelivery(queued.ID)
+		delivery.Webhook = f.webhook
+		before := time.Now()
+		f.svc.attempt(context.Background(), delivery)
+
+		saved := f.repo.deliveries[queued.ID]
+		if saved.Attempts != attempt || saved.ResponseStatus != http.StatusServiceUnavailable || saved.Error == "" {
+			t.Fatalf("attempt %d: delivery = %+v", attempt, saved)
+		}
+		if attempt == maxDeliveryAttempts {
+			if saved.Status != models.DeliveryFailed || saved.NextAttemptAt != nil {
+				t.Errorf("after the last attempt delivery = %s, next %v; want failed", saved.Status, saved.NextAttemptAt)
+			}
+			break
+		}
+		wait := deliveryRetryBase << (attempt - 1)
+		if saved.Status != models.DeliveryPending || saved.NextAttemptAt.Before(before.Add(wait)) || saved.NextAttemptAt.After(time.Now().Add(wait)) {
+			t.Errorf("attempt %d: next attempt in %v, want %v", attempt, saved.NextAttemptAt.Sub(before), wait)
+		}
+	}
+	if f.rcv.calls() != maxDeliveryAttempts {
+		t.Errorf("receiver got %d requests, want %d", f.rcv.calls(), maxDeliveryAttempts)
+	}
+}
+
+func TestWebhookDisabledAfterConsecutiveFailures(t *testing.T) {
+	f := newWebhookFixture(t, WebhookConfig{AllowPrivateNetworks: true})
+
+	// Успех обнуляет счётчик: порог считает только неудачи подряд
+	f.webhook.FailureCount = webhookFailureThreshold - 1
+	f.enqueue(t)
+	if _, err := f.svc.DeliverDue(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if f.webhook.FailureCount != 0 {
+		t.Fatalf("failure count after success = %d, want 0", f.webhook.FailureCount)
+	}
+
+	f.rcv.status = http.StatusInternalServerError
+	for i := 0; i < webhookFailureThreshold; i++ {
+		if !f.webhook.Active {
+			t.Fatalf("webhook disabled after %d failures, want %d", i, webhookFailureThreshold)
+		}
+		delivery := &models.WebhookDelivery{ID: uuid.New(), WebhookID: f.webhook.ID, Webhook: f.webhook, Status: models.DeliveryPending}
+		f.svc.attempt(context.Background(), delivery)
+	}
+	if f.webhook.Active || f.webhook.DisabledAt == nil || f.webhook.DisabledReason == "" {
+		t.Fatalf("webhook = %+v, want disabled with a reason", f.webhook)
+	}
+
+	calls := f.rcv.calls()
+	f.enqueue(t)
+	if n, _ := f.svc.DeliverDue(context.Background()); n != 0 || f.rcv.calls() != calls {
+		t.Errorf("disabled webhook still receives deliveries")
+	}
+}
+
+func TestWebhookRedeliver(t *testing.T) {
+	f := newWebhookFixture(t, WebhookConfig{AllowPrivateNetworks: true})
+	original := f.enqueue(t)
+	if _, err := f.svc.DeliverDue(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	copied, err := f.svc.Redeliver(f.webhook.ProjectID, f.webhook.ID, original.ID, f.owner)
+	if err != nil {
+		t.Fatalf("Redeliver: %v", err)
+	}
+	if copied.ID == original.ID || copied.RedeliveryOf == nil || *copied.RedeliveryOf != original.ID ||
+		copied.Status != models.DeliveryPending || copied.EventID != original.EventID {
+		t.Fatalf("redelivery = %+v", copied)
+	}
+
+	if n, err := f.svc.DeliverDue(context.Background()); err != nil || n != 1 {
+		t.Fatalf("DeliverDue = %d, %v; want the copy sent", n, err)
+	}
+	if f.rcv.calls() != 2 || string(f.rcv.bodies[1]) != string(f.rcv.bodies[0]) {
+		t.Errorf("receiver got %d requests, want the same payload twice", f.rcv.calls())
+	}
+	if got := f.rcv.requests[1].Header.Get(WebhookDeliveryHeader); got != copied.ID.String() {
+		t.Errorf("delivery header = %s, want %s", got, copied.ID)
+	}
+
+	if _, err := f.svc.Redeliver(f.webhook.ProjectID, f.webhook.ID, uuid.New(), f.owner); !errors.Is(err, ErrDeliveryNotFound) {
+		t.Errorf("unknown delivery: %v, want ErrDeliveryNotFound", err)
+	}
+	if _, err := f.svc.Redeliver(f.webhook.ProjectID, f.webhook.ID, original.ID, uuid.New()); err == nil {
+		t.Error("a stranger redelivered a webhook delivery")
+	}
+}
+
+func TestWebhookRefusesPrivateAddresses(t *testing.T) {
+	f := newWebhookFixture(t, WebhookConfig{})
+	queued := f.enqueue(t)
+	if _, err := f.svc.DeliverDue(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if f.rcv.calls() != 0 {
+		t.Fatal("delivery reached a loopback receiver")
+	}
+	if delivery := f.repo.deliveries[queued.ID]; delivery.Status != models.DeliveryPending || delivery.Error == "" {
+		t.Errorf("delivery = %+v, want a failed attempt", delivery)
+	}
+
+	tests := []struct {
+		address string
+		allowed bool
+	}{
+		{"93.184.216.34:443", true},
+		{"[2606:4700::1111]:443", true},
+		{"127.0.0.1:80", false},
+		{"10.1.2.3:80", false},
+		{"172.16.0.1:80", false},
+		{"192.168.1.1:80", false},
+		{"169.254.169.254:80", false},
+		{"100.64.0.1:80", false},
+		{"0.0.0.0:80", false},
+		{"[::1]:80", false},
+		{"[::ffff:127.0.0.1]:80", false},
+		{"[fd00::1]:80", false},
+		{"[fe80::1]:80", false},
+	}
+	for _, tt := range tests {
+		err := checkWebhookAddress("tcp", tt.address, nil)
+		if tt.allowed && err != nil {
+			t.Errorf("%s: %v, want allowed", tt.address, err)
+		}
+		if !tt.allowed && !errors.Is(err, ErrWebhookAddress) {
+			t.Errorf("%s: %v, want ErrWebhookAddress", tt.address, err)
+		}
+	}
+}
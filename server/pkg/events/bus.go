@@ -0,0 +1,168 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/google/uuid"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Event — изменение, о котором сообщают подписчикам. Audience — пользователи, которым оно видно,
+// ProjectID — проект, к которому относится изменение (uuid.Nil для задач без проекта)
+type Event struct {
+	ID        string          `json:"id"`
+	Type      string          `json:"type"`
+	Audience  []uuid.UUID     `json:"-"`
+	ProjectID uuid.UUID       `json:"-"`
+	Data      json.RawMessage `json:"data"`
+	Time      time.Time       `json:"time"`
+}
+
+// VisibleTo проверяет, входит ли пользователь в аудиторию события
+func (e Event) VisibleTo(userID uuid.UUID) bool {
+	for _, id := range e.Audience {
+		if id == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// Сколько событий подписчик может не успеть прочитать, прежде чем его отключат
+const subscriberBuffer = 64
+
+// Bus — шина событий внутри процесса. Последние события хранятся в истории, чтобы
+// переподключившийся клиент получил пропущенное по Last-Event-ID
+type Bus struct {
+	mu sync.Mutex
+	// epoch отличает запуски процесса: номера событий прошлого запуска ничего не значат
+	epoch   string
+	seq     uint64
+	history []Event
+	limit   int
+	subs    map[*Subscription]struct{}
+	closed  bool
+}
+
+type Subscription struct {
+	ch     chan Event
+	filter func(Event) bool
+}
+
+// C закрывается, если подписчик отстал и был отключён, или после Unsubscribe
+func (s *Subscription) C() <-chan Event {
+	return s.ch
+}
+
+func NewBus(historySize int) *Bus {
+	return &Bus{
+		epoch:   strconv.FormatInt(time.Now().UnixNano(), 36),
+		history: make([]Event, 0, historySize),
+		limit:   historySize,
+		subs:    make(map[*Subscription]struct{}),
+	}
+}
+
+// Publish присваивает событию номер и рассылает его подписчикам. Не блокируется:
+// подписчик с переполненным буфером отключается и может переподключиться с Last-Event-ID
+func (b *Bus) Publish(e Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.seq++
+	e.ID = fmt.Sprintf("%s-%d", b.epoch, b.seq)
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	if b.limit > 0 {
+		if len(b.history) == b.limit {
+			copy(b.history, b.history[1:])
+			b.history = b.history[:b.limit-1]
+		}
+		b.history = append(b.history, e)
+	}
+
+	for sub := range b.subs {
+		if !sub.filter(e) {
+			continue
+		}
+		select {
+		case sub.ch <- e:
+		default:
+			delete(b.subs, sub)
+			close(sub.ch)
+		}
+	}
+}
+
+// Subscribe подписывает на события, прошедшие filter. lastID — последнее событие, полученное
+// клиентом до переподключения: пропущенные после него возвращаются в missed. resumed=false
+// значит, что часть пропущенного восстановить нельзя (другой запуск или событие вытеснено
+// из истории) и клиенту стоит перечитать данные целиком
+func (b *Bus) Subscribe(filter func(Event) bool, lastID string) (sub *Subscription, missed []Event, resumed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	sub = &Subscription{ch: make(chan Event, subscriberBuffer), filter: filter}
+	if b.closed {
+		close(sub.ch)
+		return sub, nil, true
+	}
+	b.subs[sub] = struct{}{}
+	if lastID == "" {
+		return sub, nil, true
+	}
+
+	epoch, seqStr, ok := strings.Cut(lastID, "-")
+	seq, err := strconv.ParseUint(seqStr, 10, 64)
+	if !ok || err != nil || epoch != b.epoch || seq > b.seq {
+		return sub, nil, false
+	}
+	resumed = true
+	if len(b.history) > 0 {
+		oldest := b.seq - uint64(len(b.history)) + 1
+		resumed = seq+1 >= oldest
+		for i, e := range b.history {
+			if oldest+uint64(i) > seq && filter(e) {
+				missed = append(missed, e)
+			}
+		}
+	} else if seq < b.seq {
+		resumed = false
+	}
+	return sub, missed, resumed
+}
+
+// LastID — номер последнего опубликованного события; пустой, если событий ещё не было
+func (b *Bus) LastID() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.seq == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%s-%d", b.epoch, b.seq)
+}
+
+func (b *Bus) Unsubscribe(sub *Subscription) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.subs[sub]; ok {
+		delete(b.subs, sub)
+		close(sub.ch)
+	}
+}
+
+// Close отключает всех подписчиков и не принимает новых — при остановке сервера,
+// чтобы открытые потоки событий не держали его
+func (b *Bus) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	for sub := range b.subs {
+		delete(b.subs, sub)
+		close(sub.ch)
+	}
+}
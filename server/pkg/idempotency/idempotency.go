@@ -0,0 +1,51 @@
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"time"
+)
+
+// ErrReservationLost — ключ уже не занят этим запросом: резерв истёк и ключ перезанял повтор
+var ErrReservationLost = errors.New("idempotency key reservation lost")
+
+// Record — запрос, выполненный под ключом идемпотентности, и его ответ
+type Record struct {
+	Fingerprint string
+	Status      int // 0 — запрос ещё выполняется
+	Header      map[string]string
+	Body        []byte
+}
+
+// Store хранит ключи идемпотентности. Ключи уникальны в пределах scope (пользователя)
+type Store interface {
+	// Reserve занимает ключ под запрос с отпечатком fingerprint на время lock и возвращает
+	// токен резерва. Если ключ уже занят и не истёк, возвращает существующую запись и пустой
+	// токен. Короткий lock не даёт ключу зависнуть на сутки, если процесс упал посреди запроса
+	Reserve(ctx context.Context, scope, key, fingerprint string, lock time.Duration) (existing *Record, token string, err error)
+	// Complete сохраняет ответ для повторов на время ttl. Если резерв token истёк и ключ
+	// перезанят, ответ не сохраняется и возвращается ErrReservationLost
+	Complete(ctx context.Context, scope, key, token string, status int, header map[string]string, body []byte, ttl time.Duration) error
+	// Release освобождает ключ, если запрос не удался и его можно повторить. Чужой,
+	// более новый резерв не трогает
+	Release(ctx context.Context, scope, key, token string) error
+	DeleteExpired(ctx context.Context) (int64, error)
+}
+
+type Config struct {
+	Driver string // postgres | memory
+	DB     *gorm.DB
+}
+
+func New(config Config) (Store, error) {
+	switch config.Driver {
+	case "", "postgres":
+		return NewPostgresStore(config.DB)
+	case "memory":
+		return NewMemoryStore(), nil
+	default:
+		return nil, fmt.Errorf("unknown idempotency store %q", config.Driver)
+	}
+}
@@ -0,0 +1,97 @@
+package idempotency
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"maps"
+	"sync"
+	"time"
+)
+
+// memoryStore держит ключи в памяти процесса: подходит для одного экземпляра и разработки
+type memoryStore struct {
+	mu      sync.Mutex
+	records map[memoryKey]*memoryRecord
+}
+
+type memoryKey struct {
+	scope, key string
+}
+
+type memoryRecord struct {
+	Record
+	token     string
+	expiresAt time.Time
+}
+
+func NewMemoryStore() Store {
+	return &memoryStore{records: make(map[memoryKey]*memoryRecord)}
+}
+
+func (s *memoryStore) Reserve(ctx context.Context, scope, key, fingerprint string, lock time.Duration) (*Record, string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if rec, ok := s.records[memoryKey{scope, key}]; ok && now.Before(rec.expiresAt) {
+		existing := rec.Record
+		existing.Header = maps.Clone(rec.Header)
+		return &existing, "", nil
+	}
+	token := uuid.NewString()
+	s.records[memoryKey{scope, key}] = &memoryRecord{
+		Record:    Record{Fingerprint: fingerprint},
+		token:     token,
+		expiresAt: now.Add(lock),
+	}
+	return nil, token, nil
+}
+
+// reserved возвращает запись, если ключ всё ещё занят резервом token и ответа в нём нет
+func (s *memoryStore) reserved(scope, key, token string) (*memoryRecord, bool) {
+	rec, ok := s.records[memoryKey{scope, key}]
+	if !ok || rec.token != token || rec.Status != 0 {
+		return nil, false
+	}
+	return rec, true
+}
+
+func (s *memoryStore) Complete(ctx context.Context, scope, key, token string, status int, header map[string]string, body []byte, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rec, ok := s.reserved(scope, key, token)
+	if !ok {
+		return ErrReservationLost
+	}
+	rec.Status = status
+	rec.Header = maps.Clone(header)
+	rec.Body = append([]byte(nil), body...)
+	rec.expiresAt = time.Now().Add(ttl)
+	return nil
+}
+
+func (s *memoryStore) Release(ctx context.Context, scope, key, token string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.reserved(scope, key, token); ok {
+		delete(s.records, memoryKey{scope, key})
+	}
+	return nil
+}
+
+func (s *memoryStore) DeleteExpired(ctx context.Context) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var n int64
+	now := time.Now()
+	for k, rec := range s.records {
+		if !now.Before(rec.expiresAt) {
+			delete(s.records, k)
+			n++
+		}
+	}
+	return n, nil
+}
@@ -0,0 +1,46 @@
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestLateRequestDoesNotTouchNewerReservation(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+
+	_, slow, err := store.Reserve(ctx, "user", "key", "fp", 10*time.Millisecond)
+	if err != nil || slow == "" {
+		t.Fatalf("Reserve = %q, %v", slow, err)
+	}
+	// Медленный запрос пережил lock, и ключ занял повтор
+	time.Sleep(20 * time.Millisecond)
+	_, retry, err := store.Reserve(ctx, "user", "key", "fp", time.Minute)
+	if err != nil || retry == "" || retry == slow {
+		t.Fatalf("retry Reserve = %q, %v; want a new reservation", retry, err)
+	}
+
+	if err := store.Release(ctx, "user", "key", slow); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Complete(ctx, "user", "key", slow, 200, nil, []byte("slow"), time.Hour); !errors.Is(err, ErrReservationLost) {
+		t.Fatalf("late Complete error = %v, want ErrReservationLost", err)
+	}
+	if err := store.Complete(ctx, "user", "key", retry, 201, nil, []byte("retry"), time.Hour); err != nil {
+		t.Fatalf("retry Complete: %v", err)
+	}
+	// Завершённый ответ не перезаписывается и не удаляется даже своим резервом
+	if err := store.Complete(ctx, "user", "key", retry, 500, nil, nil, time.Hour); !errors.Is(err, ErrReservationLost) {
+		t.Errorf("second Complete error = %v, want ErrReservationLost", err)
+	}
+	if err := store.Release(ctx, "user", "key", retry); err != nil {
+		t.Fatal(err)
+	}
+
+	existing, token, err := store.Reserve(ctx, "user", "key", "fp", time.Minute)
+	if err != nil || token != "" || existing == nil || existing.Status != 201 || string(existing.Body) != "retry" {
+		t.Fatalf("stored = %+v, %q, %v; want the retry's response", existing, token, err)
+	}
+}
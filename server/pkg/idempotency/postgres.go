@@ -0,0 +1,96 @@
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+type postgresStore struct {
+	db *gorm.DB
+}
+
+// keyRow — строка таблицы idempotency_keys. Token — резерв запроса, занявшего ключ:
+// сохранить ответ и освободить ключ может только он
+type keyRow struct {
+	Scope       string            `gorm:"type:varchar(64);primaryKey"`
+	Key         string            `gorm:"type:varchar(255);primaryKey"`
+	Fingerprint string            `gorm:"type:varchar(64);not null"`
+	Token       string            `gorm:"type:varchar(36);not null;default:''"`
+	Status      int               `gorm:"not null;default:0"`
+	Header      map[string]string `gorm:"type:jsonb;serializer:json"`
+	Body        []byte            `gorm:"type:bytea"`
+	CreatedAt   time.Time
+	ExpiresAt   time.Time `gorm:"not null;index"`
+}
+
+func (keyRow) TableName() string {
+	return "idempotency_keys"
+}
+
+func NewPostgresStore(db *gorm.DB) (Store, error) {
+	if db == nil {
+		return nil, errors.New("idempotency: postgres store needs a database")
+	}
+	if err := db.AutoMigrate(&keyRow{}); err != nil {
+		return nil, err
+	}
+	return &postgresStore{db: db}, nil
+}
+
+// Reserve вставляет ключ или перезанимает истёкший (в том числе брошенный упавшим процессом)
+// одним запросом, поэтому из параллельных запросов с одним ключом выполняется только один
+func (s *postgresStore) Reserve(ctx context.Context, scope, key, fingerprint string, lock time.Duration) (*Record, string, error) {
+	now := time.Now()
+	row := keyRow{Scope: scope, Key: key, Fingerprint: fingerprint, Token: uuid.NewString(), CreatedAt: now, ExpiresAt: now.Add(lock)}
+	result := s.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "scope"}, {Name: "key"}},
+		DoUpdates: clause.AssignmentColumns([]string{"fingerprint", "token", "status", "header", "body", "created_at", "expires_at"}),
+		Where: clause.Where{Exprs: []clause.Expression{
+			clause.Expr{SQL: "idempotency_keys.expires_at <= ?", Vars: []any{now}},
+		}},
+	}).Create(&row)
+	if result.Error != nil {
+		return nil, "", result.Error
+	}
+	if result.RowsAffected > 0 {
+		return nil, row.Token, nil
+	}
+
+	var existing keyRow
+	if err := s.db.WithContext(ctx).First(&existing, "scope = ? AND key = ?", scope, key).Error; err != nil {
+		return nil, "", err
+	}
+	return &Record{
+		Fingerprint: existing.Fingerprint,
+		Status:      existing.Status,
+		Header:      existing.Header,
+		Body:        existing.Body,
+	}, "", nil
+}
+
+// Complete и Release меняют ключ, только пока он занят резервом token и ответа в нём нет
+func (s *postgresStore) Complete(ctx context.Context, scope, key, token string, status int, header map[string]string, body []byte, ttl time.Duration) error {
+	result := s.db.WithContext(ctx).Model(&keyRow{}).
+		Where("scope = ? AND key = ? AND token = ? AND status = 0", scope, key, token).
+		Updates(&keyRow{Status: status, Header: header, Body: body, ExpiresAt: time.Now().Add(ttl)})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrReservationLost
+	}
+	return nil
+}
+
+func (s *postgresStore) Release(ctx context.Context, scope, key, token string) error {
+	return s.db.WithContext(ctx).Delete(&keyRow{}, "scope = ? AND key = ? AND token = ? AND status = 0", scope, key, token).Error
+}
+
+func (s *postgresStore) DeleteExpired(ctx context.Context) (int64, error) {
+	result := s.db.WithContext(ctx).Delete(&keyRow{}, "expires_at <= ?", time.Now())
+	return result.RowsAffected, result.Error
+}
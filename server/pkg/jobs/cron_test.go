@@ -0,0 +1,135 @@
+package jobs
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParseCronErrors(t *testing.T) {
+	for _, spec := range []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"*/x * * * *",
+		"5-1 * * * *",
+		"a * * * *",
+		"1,,2 * * * *",
+		"@every 5m",
+	} {
+		if _, err := ParseCron(spec); !errors.Is(err, ErrInvalidCron) {
+			t.Errorf("ParseCron(%q) error = %v, want ErrInvalidCron", spec, err)
+		}
+	}
+}
+
+func TestCronNext(t *testing.T) {
+	// 10 марта 2026 года — вторник
+	at := func(s string) time.Time {
+		t, err := time.Parse("2006-01-02 15:04", s)
+		if err != nil {
+			panic(err)
+		}
+		return t
+	}
+
+	tests := []struct {
+		spec string
+		from string
+		want string
+	}{
+		{"* * * * *", "2026-03-10 10:07", "2026-03-10 10:08"},
+		{"*/15 * * * *", "2026-03-10 10:07", "2026-03-10 10:15"},
+		{"*/15 * * * *", "2026-03-10 10:45", "2026-03-10 11:00"},
+		// Следующий момент строго после from
+		{"30 10 * * *", "2026-03-10 10:30", "2026-03-11 10:30"},
+		// Шаг у диапазона и у начального значения
+		{"1-10/4 * * * *", "2026-03-10 10:05", "2026-03-10 10:09"},
+		{"1-10/4 * * * *", "2026-03-10 10:09", "2026-03-10 11:01"},
+		{"5/15 * * * *", "2026-03-10 10:21", "2026-03-10 10:35"},
+		{"0 9,17 * * *", "2026-03-10 12:00", "2026-03-10 17:00"},
+		// Будни: из пятницы — в понедельник
+		{"0 9 * * 1-5", "2026-03-13 10:00", "2026-03-16 09:00"},
+		// Воскресенье — и 0, и 7
+		{"0 0 * * 0", "2026-03-10 10:00", "2026-03-15 00:00"},
+		{"0 0 * * 7", "2026-03-10 10:00", "2026-03-15 00:00"},
+		{"0 0 * * 5-7", "2026-03-13 10:00", "2026-03-14 00:00"},
+		// Заданы и день месяца, и день недели — подходит любой
+		{"0 0 13 * 5", "2026-03-01 00:00", "2026-03-06 00:00"},
+		{"0 0 13 * 5", "2026-04-11 00:00", "2026-04-13 00:00"},
+		// Со звёздочкой в одном из полей должны совпасть оба
+		{"0 0 13 * *", "2026-03-01 00:00", "2026-03-13 00:00"},
+		{"0 0 * * 5", "2026-03-07 00:00", "2026-03-13 00:00"},
+		{"0 0 */10 * *", "2026-03-12 00:00", "2026-03-21 00:00"},
+		{"0 0 31 * *", "2026-04-01 00:00", "2026-05-31 00:00"},
+		{"0 0 29 2 *", "2026-03-01 00:00", "2028-02-29 00:00"},
+		{"0 0 1 1 *", "2026-03-10 10:00", "2027-01-01 00:00"},
+		{"@hourly", "2026-03-10 10:07", "2026-03-10 11:00"},
+		{"@weekly", "2026-03-10 10:07", "2026-03-15 00:00"},
+		{"@monthly", "2026-03-10 10:07", "2026-04-01 00:00"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec+" after "+tt.from, func(t *testing.T) {
+			c, err := ParseCron(tt.spec)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := c.Next(at(tt.from))
+			if !got.Equal(at(tt.want)) {
+				t.Errorf("Next = %s, want %s", got.Format("2006-01-02 15:04 Mon"), tt.want)
+			}
+		})
+	}
+}
+
+func TestCronNextNeverFires(t *testing.T) {
+	c, err := ParseCron("0 0 30 2 *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := c.Next(time.Now()); !got.IsZero() {
+		t.Errorf("Next = %s, want zero time", got)
+	}
+}
+
+func TestCronNextInLocation(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip("timezone Europe/Berlin is unavailable")
+	}
+	c, err := ParseCron("0 9 * * *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Location = berlin
+	got := c.Next(time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC))
+	if want := time.Date(2026, 3, 11, 8, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("Next = %s, want %s", got.UTC(), want)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{0, 15 * time.Second},
+		{1, 15 * time.Second},
+		{2, 30 * time.Second},
+		{3, time.Minute},
+		{8, 32 * time.Minute},
+		{9, time.Hour},
+		{50, time.Hour},
+	}
+	for _, tt := range tests {
+		if got := backoff(tt.attempt); got != tt.want {
+			t.Errorf("backoff(%d) = %s, want %s", tt.attempt, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,341 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+var (
+	ErrNotFound     = errors.New("job not found")
+	ErrNotRetryable = errors.New("only failed jobs can be retried")
+	ErrJobRunning   = errors.New("job is running")
+	ErrDuplicateJob = errors.New("a job with the same unique key is already queued")
+	ErrUnknownKind  = errors.New("unknown job kind")
+)
+
+type Status string
+
+const (
+	StatusQueued    Status = "queued"
+	StatusRunning   Status = "running"
+	StatusSucceeded Status = "succeeded"
+	StatusFailed    Status = "failed" // попытки кончились или ошибка постоянная
+)
+
+// Job — строка таблицы jobs
+type Job struct {
+	ID          int64           `gorm:"primaryKey" json:"id"`
+	Kind        string          `gorm:"type:varchar(100);not null;index" json:"kind"`
+	Args        json.RawMessage `gorm:"type:jsonb;serializer:json" json:"args"`
+	Status      Status          `gorm:"type:varchar(20);not null;index:idx_jobs_ready,priority:1" json:"status"`
+	Attempts    int             `gorm:"not null" json:"attempts"`
+	MaxAttempts int             `gorm:"not null" json:"max_attempts"`
+	RunAt       time.Time       `gorm:"not null;index:idx_jobs_ready,priority:2" json:"run_at"`
+	// Пока задание с ключом ждёт или выполняется, такое же повторно не ставится
+	UniqueKey   *string    `gorm:"type:varchar(255);uniqueIndex:idx_jobs_unique_key,where:finished_at IS NULL" json:"unique_key,omitempty"`
+	LockedBy    string     `gorm:"type:varchar(100)" json:"locked_by,omitempty"`
+	LockedUntil *time.Time `json:"locked_until,omitempty"`
+	LastError   string     `gorm:"type:text" json:"last_error,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	FinishedAt  *time.Time `gorm:"index" json:"finished_at,omitempty"`
+}
+
+// KindOptions — параметры вида задания
+type KindOptions struct {
+	MaxAttempts int           // по умолчанию 10
+	Timeout     time.Duration // на одну попытку; по умолчанию 5 минут
+}
+
+// EnqueueOptions — параметры постановки одного задания
+type EnqueueOptions struct {
+	RunAt       time.Time // пустое — сразу
+	MaxAttempts int       // 0 — как у вида задания
+	UniqueKey   string
+}
+
+type Config struct {
+	DB           *gorm.DB
+	Concurrency  int           // сколько заданий выполняется одновременно; по умолчанию 4
+	PollInterval time.Duration // по умолчанию секунда
+	Worker       string        // имя процесса в locked_by; по умолчанию host:pid
+}
+
+type handlerFunc func(ctx context.Context, args json.RawMessage) error
+
+type kind struct {
+	handle handlerFunc
+	opts   KindOptions
+}
+
+// Queue — очередь фоновых заданий в Postgres. Несколько процессов могут работать
+// с одной очередью: задания разбираются через SKIP LOCKED, а задание процесса,
+// который упал, снова берётся после истечения блокировки
+type Queue struct {
+	db  *gorm.DB
+	cfg Config
+
+	mu        sync.Mutex
+	kinds     map[string]kind
+	schedules []*schedule
+	periodic  []periodic
+	started   bool
+
+	wake    chan struct{}
+	stop    chan struct{}
+	loops   sync.WaitGroup
+	running sync.WaitGroup
+	active  chan struct{} // занятые слоты
+
+	ctx    context.Context // отменяется, если задания не успели закончиться к остановке
+	cancel context.CancelFunc
+}
+
+func New(cfg Config) (*Queue, error) {
+	if cfg.DB == nil {
+		return nil, errors.New("jobs: queue needs a database")
+	}
+	if cfg.Concurrency <= 0 {
+		cfg.Concurrency = 4
+	}
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = time.Second
+	}
+	if cfg.Worker == "" {
+		host, _ := os.Hostname()
+		cfg.Worker = host + ":" + strconv.Itoa(os.Getpid())
+	}
+	if err := cfg.DB.AutoMigrate(&Job{}, &scheduleRow{}); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Queue{
+		db:     cfg.DB,
+		cfg:    cfg,
+		kinds:  map[string]kind{},
+		wake:   make(chan struct{}, 1),
+		stop:   make(chan struct{}),
+		active: make(chan struct{}, cfg.Concurrency),
+		ctx:    ctx,
+		cancel: cancel,
+	}, nil
+}
+
+// Kind — зарегистрированный вид заданий с аргументами типа T
+type Kind[T any] struct {
+	name  string
+	queue *Queue
+}
+
+// Register регистрирует обработчик вида name. Аргументы хранятся в JSON; если их не
+// удалось разобрать, задание сразу считается проваленным
+func Register[T any](q *Queue, name string, handle func(ctx context.Context, args T) error, opts KindOptions) Kind[T] {
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = 10
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 5 * time.Minute
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if _, ok := q.kinds[name]; ok {
+		panic(fmt.Sprintf("jobs: kind %q registered twice", name))
+	}
+	q.kinds[name] = kind{opts: opts, handle: func(ctx context.Context, raw json.RawMessage) error {
+		var args T
+		if err := json.Unmarshal(raw, &args); err != nil {
+			return Permanent(fmt.Errorf("decode args: %w", err))
+		}
+		return handle(ctx, args)
+	}}
+	return Kind[T]{name: name, queue: q}
+}
+
+func (k Kind[T]) Name() string {
+	return k.name
+}
+
+// Enqueue ставит задание в очередь. false — задание с тем же UniqueKey уже ждёт
+// или выполняется
+func (k Kind[T]) Enqueue(ctx context.Context, args T, opts EnqueueOptions) (bool, error) {
+	return k.EnqueueTx(k.queue.db.WithContext(ctx), args, opts)
+}
+
+// EnqueueTx ставит задание в транзакции tx — вместе с изменением, которое его породило
+func (k Kind[T]) EnqueueTx(tx *gorm.DB, args T, opts EnqueueOptions) (bool, error) {
+	raw, err := json.Marshal(args)
+	if err != nil {
+		return false, err
+	}
+	return k.queue.insert(tx, k.name, raw, opts)
+}
+
+func (q *Queue) insert(tx *gorm.DB, name string, args json.RawMessage, opts EnqueueOptions) (bool, error) {
+	q.mu.Lock()
+	kind, ok := q.kinds[name]
+	q.mu.Unlock()
+	if !ok {
+		return false, fmt.Errorf("%w: %s", ErrUnknownKind, name)
+	}
+
+	job := Job{
+		Kind:        name,
+		Args:        args,
+		Status:      StatusQueued,
+		MaxAttempts: opts.MaxAttempts,
+		RunAt:       opts.RunAt,
+	}
+	if job.MaxAttempts <= 0 {
+		job.MaxAttempts = kind.opts.MaxAttempts
+	}
+	if job.RunAt.IsZero() {
+		job.RunAt = time.Now()
+	}
+	if opts.UniqueKey != "" {
+		job.UniqueKey = &opts.UniqueKey
+	}
+
+	result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&job)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected > 0 && !job.RunAt.After(time.Now()) {
+		q.notify()
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// notify будит цикл выборки, не дожидаясь следующего опроса
+func (q *Queue) notify() {
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+// permanentError — ошибка, после которой повторять задание бессмысленно
+type permanentError struct {
+	err error
+}
+
+func (e permanentError) Error() string { return e.err.Error() }
+func (e permanentError) Unwrap() error { return e.err }
+
+// Permanent помечает ошибку обработчика как постоянную: задание проваливается без повторов
+func Permanent(err error) error {
+	return permanentError{err: err}
+}
+
+// backoff — пауза перед попыткой attempt+1: 15 секунд, дальше вдвое больше, не больше часа
+func backoff(attempt int) time.Duration {
+	d := 15 * time.Second
+	for i := 1; i < attempt && d < time.Hour; i++ {
+		d *= 2
+	}
+	return min(d, time.Hour)
+}
+
+// ListFilter — выборка заданий для администратора
+type ListFilter struct {
+	Status Status
+	Kind   string
+	Limit  int // по умолчанию 50, не больше 200
+}
+
+func (q *Queue) List(ctx context.Context, filter ListFilter) ([]Job, error) {
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = 50
+	}
+	limit = min(limit, 200)
+
+	query := q.db.WithContext(ctx).Order("updated_at DESC, id DESC").Limit(limit)
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.Kind != "" {
+		query = query.Where("kind = ?", filter.Kind)
+	}
+	var jobs []Job
+	err := query.Find(&jobs).Error
+	return jobs, err
+}
+
+func (q *Queue) Get(ctx context.Context, id int64) (*Job, error) {
+	var job Job
+	if err := q.db.WithContext(ctx).First(&job, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &job, nil
+}
+
+// Retry возвращает проваленное задание в очередь с новым запасом попыток
+func (q *Queue) Retry(ctx context.Context, id int64) (*Job, error) {
+	job, err := q.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if job.Status != StatusFailed {
+		return nil, ErrNotRetryable
+	}
+
+	result := q.db.WithContext(ctx).Model(&Job{}).
+		Where("id = ? AND status = ?", id, StatusFailed).
+		Updates(map[string]any{
+			"status":      StatusQueued,
+			"attempts":    0,
+			"run_at":      time.Now(),
+			"finished_at": nil,
+			"updated_at":  time.Now(),
+		})
+	var pgErr *pgconn.PgError
+	if errors.As(result.Error, &pgErr) && pgErr.Code == "23505" {
+		return nil, ErrDuplicateJob
+	}
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrNotRetryable
+	}
+	q.notify()
+	return q.Get(ctx, id)
+}
+
+// Delete удаляет задание, которое сейчас не выполняется
+func (q *Queue) Delete(ctx context.Context, id int64) error {
+	result := q.db.WithContext(ctx).Where("status <> ?", StatusRunning).Delete(&Job{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		if _, err := q.Get(ctx, id); err != nil {
+			return err
+		}
+		return ErrJobRunning
+	}
+	return nil
+}
+
+// PurgeFinished удаляет выполненные задания, завершившиеся раньше before. Проваленные
+// остаются, пока их не повторят или не удалят
+func (q *Queue) PurgeFinished(ctx context.Context, before time.Time) (int64, error) {
+	result := q.db.WithContext(ctx).
+		Where("status = ? AND finished_at < ?", StatusSucceeded, before).
+		Delete(&Job{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,265 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"runtime/debug"
+	"time"
+)
+
+// Запас к таймауту задания: блокировку не должен перехватить другой процесс, пока
+// этот ещё записывает результат
+const lockGrace = time.Minute
+
+// Start запускает выборку заданий и расписания. Виды заданий и расписания нужно
+// зарегистрировать до запуска
+func (q *Queue) Start() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.started {
+		return
+	}
+	q.started = true
+
+	q.loops.Add(2)
+	go q.fetchLoop()
+	go q.scheduleLoop()
+	for _, p := range q.periodic {
+		q.running.Add(1)
+		go q.runPeriodic(p)
+	}
+}
+
+// periodic — работа, которую процесс выполняет сам раз в interval, а не через очередь
+type periodic struct {
+	name     string
+	interval time.Duration
+	fn       func(ctx context.Context) (int, error)
+}
+
+// Every выполняет fn раз в interval на каждом процессе, пока работает очередь: для частой
+// работы, которой мало минутного расписания (разбор outbox, доставка вебхуков). fn
+// возвращает, сколько сделано; пока не ноль, следующий вызов идёт сразу. Shutdown
+// дожидается начатого вызова так же, как выполняющихся заданий. Регистрировать до Start
+func (q *Queue) Every(name string, interval time.Duration, fn func(ctx context.Context) (int, error)) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.periodic = append(q.periodic, periodic{name: name, interval: interval, fn: fn})
+}
+
+func (q *Queue) runPeriodic(p periodic) {
+	defer q.running.Done()
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-q.stop:
+			return
+		case <-ticker.C:
+		}
+		for {
+			// Тик и остановка могли прийти одновременно
+			select {
+			case <-q.stop:
+				return
+			default:
+			}
+			n, err := p.fn(q.ctx)
+			if err != nil {
+				log.Printf("jobs: %s failed: %v", p.name, err)
+			}
+			if n == 0 || err != nil {
+				break
+			}
+		}
+	}
+}
+
+// Shutdown перестаёт брать новые задания и ждёт выполняющиеся и начатые вызовы Every.
+// Если ctx истёк раньше, их контексты отменяются, а задания возвращаются в очередь
+// без траты попытки
+func (q *Queue) Shutdown(ctx context.Context) error {
+	q.mu.Lock()
+	started := q.started
+	q.started = false
+	q.mu.Unlock()
+	if !started {
+		return nil
+	}
+
+	close(q.stop)
+	q.loops.Wait()
+
+	done := make(chan struct{})
+	go func() {
+		q.running.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		q.cancel()
+		<-done
+		return ctx.Err()
+	}
+}
+
+// fetchLoop берёт задания, пока есть свободные слоты. Когда очередь пуста, ждёт
+// опроса или сигнала о новом задании
+func (q *Queue) fetchLoop() {
+	defer q.loops.Done()
+	ticker := time.NewTicker(q.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		free := cap(q.active) - len(q.active)
+		claimed := 0
+		if free > 0 {
+			jobs, err := q.claim(free)
+			if err != nil {
+				log.Printf("jobs: claim failed: %v", err)
+			}
+			for _, job := range jobs {
+				q.active <- struct{}{}
+				q.running.Add(1)
+				go q.run(job)
+			}
+			claimed = len(jobs)
+		}
+		// Взяли сколько могли — возможно, готовы ещё
+		if claimed > 0 && claimed == free {
+			select {
+			case <-q.stop:
+				return
+			default:
+				continue
+			}
+		}
+
+		select {
+		case <-q.stop:
+			return
+		case <-ticker.C:
+		case <-q.wake:
+		}
+	}
+}
+
+// claim забирает до limit готовых заданий известных процессу видов: ждущих своего
+// времени и брошенных процессами, чья блокировка истекла
+func (q *Queue) claim(limit int) ([]Job, error) {
+	q.mu.Lock()
+	names := make([]string, 0, len(q.kinds))
+	lease := time.Duration(0)
+	for name, kind := range q.kinds {
+		names = append(names, name)
+		lease = max(lease, kind.opts.Timeout)
+	}
+	q.mu.Unlock()
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	var ids []int64
+	err := q.db.Raw(`
+		UPDATE jobs SET status = @running, attempts = attempts + 1, locked_by = @worker,
+			locked_until = now() + make_interval(secs => @lease), updated_at = now()
+		WHERE id IN (
+			SELECT id FROM jobs
+			WHERE kind IN @kinds
+				AND ((status = @queued AND run_at <= now()) OR (status = @running AND locked_until < now()))
+			ORDER BY run_at, id
+			LIMIT @limit
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id`,
+		map[string]any{
+			"running": StatusRunning,
+			"queued":  StatusQueued,
+			"worker":  q.cfg.Worker,
+			"lease":   (lease + lockGrace).Seconds(),
+			"kinds":   names,
+			"limit":   limit,
+		},
+	).Scan(&ids).Error
+	if err != nil || len(ids) == 0 {
+		return nil, err
+	}
+
+	var jobs []Job
+	err = q.db.Where("id IN ?", ids).Order("run_at, id").Find(&jobs).Error
+	return jobs, err
+}
+
+func (q *Queue) run(job Job) {
+	defer func() {
+		<-q.active
+		q.running.Done()
+		q.notify()
+	}()
+
+	q.mu.Lock()
+	kind := q.kinds[job.Kind]
+	q.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(q.ctx, kind.opts.Timeout)
+	err := call(ctx, kind.handle, job)
+	cancel()
+
+	if err := q.finish(job, err); err != nil {
+		log.Printf("jobs: saving result of job %d (%s) failed: %v", job.ID, job.Kind, err)
+	}
+}
+
+// call выполняет обработчик; паника — такая же ошибка попытки
+func call(ctx context.Context, handle handlerFunc, job Job) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v\n%s", r, debug.Stack())
+		}
+	}()
+	return handle(ctx, job.Args)
+}
+
+// finish записывает результат попытки. Блокировку проверяем по locked_by: если
+// задание успели перехватить, результат этой попытки уже не важен
+func (q *Queue) finish(job Job, runErr error) error {
+	now := time.Now()
+	updates := map[string]any{
+		"locked_by":    "",
+		"locked_until": nil,
+		"updated_at":   now,
+	}
+
+	var permanent permanentError
+	switch {
+	case runErr == nil:
+		updates["status"] = StatusSucceeded
+		updates["finished_at"] = now
+		updates["last_error"] = ""
+	case q.ctx.Err() != nil && errors.Is(runErr, context.Canceled):
+		// Остановка процесса: попытку не засчитываем
+		updates["status"] = StatusQueued
+		updates["attempts"] = job.Attempts - 1
+	case errors.As(runErr, &permanent) || job.Attempts >= job.MaxAttempts:
+		updates["status"] = StatusFailed
+		updates["finished_at"] = now
+		updates["last_error"] = runErr.Error()
+		log.Printf("jobs: job %d (%s) failed after %d attempts: %v", job.ID, job.Kind, job.Attempts, runErr)
+	default:
+		delay := backoff(job.Attempts)
+		delay += time.Duration(rand.Int64N(int64(delay/10) + 1)) // разброс, чтобы повторы не сходились
+		updates["status"] = StatusQueued
+		updates["run_at"] = now.Add(delay)
+		updates["last_error"] = runErr.Error()
+	}
+
+	return q.db.Model(&Job{}).
+		Where("id = ? AND status = ? AND locked_by = ?", job.ID, StatusRunning, q.cfg.Worker).
+		Updates(updates).Error
+}
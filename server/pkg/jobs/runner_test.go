@@ -0,0 +1,104 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newIdleQueue — очередь без базы и без видов заданий: работают только вызовы Every
+func newIdleQueue() *Queue {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Queue{
+		cfg:    Config{Concurrency: 1, PollInterval: time.Hour},
+		kinds:  map[string]kind{},
+		wake:   make(chan struct{}, 1),
+		stop:   make(chan struct{}),
+		active: make(chan struct{}, 1),
+		ctx:    ctx,
+		cancel: cancel,
+	}
+}
+
+func TestEveryDrainsUntilNothingLeft(t *testing.T) {
+	q := newIdleQueue()
+	var calls atomic.Int32
+	backlog := 3
+	drained := make(chan struct{})
+	q.Every("drain", 10*time.Millisecond, func(context.Context) (int, error) {
+		calls.Add(1)
+		if backlog == 0 {
+			select {
+			case <-drained:
+			default:
+				close(drained)
+			}
+			return 0, nil
+		}
+		backlog--
+		return 1, nil
+	})
+	q.Start()
+	<-drained
+	if err := q.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	// Три порции подряд и пустой вызов в одном тике
+	if got := calls.Load(); got != 4 {
+		t.Errorf("calls = %d, want 4", got)
+	}
+}
+
+func TestShutdownWaitsForEvery(t *testing.T) {
+	q := newIdleQueue()
+	started, finished := make(chan struct{}), make(chan struct{})
+	var once atomic.Bool
+	q.Every("slow", time.Millisecond, func(ctx context.Context) (int, error) {
+		if once.Swap(true) {
+			return 0, nil
+		}
+		close(started)
+		time.Sleep(50 * time.Millisecond)
+		close(finished)
+		return 0, nil
+	})
+	q.Start()
+	<-started
+
+	if err := q.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-finished:
+	default:
+		t.Fatal("Shutdown returned before the running call finished")
+	}
+}
+
+func TestShutdownCancelsEveryAfterTimeout(t *testing.T) {
+	q := newIdleQueue()
+	started := make(chan struct{})
+	var cancelled, once atomic.Bool
+	q.Every("stuck", time.Millisecond, func(ctx context.Context) (int, error) {
+		if once.Swap(true) {
+			return 0, nil
+		}
+		close(started)
+		<-ctx.Done()
+		cancelled.Store(true)
+		return 0, ctx.Err()
+	})
+	q.Start()
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := q.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Shutdown error = %v, want DeadlineExceeded", err)
+	}
+	if !cancelled.Load() {
+		t.Error("the running call was not cancelled")
+	}
+}
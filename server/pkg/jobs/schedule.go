@@ -0,0 +1,118 @@
+package jobs
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"log"
+	"time"
+)
+
+// Как часто процесс проверяет, не пора ли ставить задания по расписанию
+const scheduleInterval = 15 * time.Second
+
+type schedule struct {
+	name string
+	spec string
+	cron *Cron
+	kind string
+	args json.RawMessage
+}
+
+// scheduleRow — строка таблицы job_schedules: когда расписание сработает в следующий
+// раз. Её общая для всех процессов блокировка не даёт поставить задание дважды
+type scheduleRow struct {
+	Name      string    `gorm:"type:varchar(100);primaryKey"`
+	Spec      string    `gorm:"type:varchar(100);not null"`
+	NextRunAt time.Time `gorm:"not null"`
+	UpdatedAt time.Time
+}
+
+func (scheduleRow) TableName() string {
+	return "job_schedules"
+}
+
+// Schedule ставит задание с аргументами args по расписанию spec (см. ParseCron).
+// Расписание называется по виду задания; если прошлое задание ещё не выполнено,
+// новое не ставится. Пропущенные за время простоя срабатывания догоняются одним заданием
+func (k Kind[T]) Schedule(spec string, args T) error {
+	cron, err := ParseCron(spec)
+	if err != nil {
+		return err
+	}
+	if cron.Next(time.Now()).IsZero() {
+		return fmt.Errorf("%w: %q never fires", ErrInvalidCron, spec)
+	}
+	raw, err := json.Marshal(args)
+	if err != nil {
+		return err
+	}
+
+	q := k.queue
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for _, s := range q.schedules {
+		if s.name == k.name {
+			return fmt.Errorf("jobs: kind %q already scheduled", k.name)
+		}
+	}
+	q.schedules = append(q.schedules, &schedule{name: k.name, spec: spec, cron: cron, kind: k.name, args: raw})
+	return nil
+}
+
+func (q *Queue) scheduleLoop() {
+	defer q.loops.Done()
+	ticker := time.NewTicker(scheduleInterval)
+	defer ticker.Stop()
+
+	for {
+		q.mu.Lock()
+		schedules := append([]*schedule(nil), q.schedules...)
+		q.mu.Unlock()
+		for _, s := range schedules {
+			if err := q.fire(s, time.Now()); err != nil {
+				log.Printf("jobs: schedule %s failed: %v", s.name, err)
+			}
+		}
+
+		select {
+		case <-q.stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// fire ставит задание, если срок расписания наступил, и переносит срок. Строку
+// расписания, занятую другим процессом, пропускаем — он справится сам
+func (q *Queue) fire(s *schedule, now time.Time) error {
+	return q.db.Transaction(func(tx *gorm.DB) error {
+		var row scheduleRow
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("name = ?", s.name).Take(&row).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// Первый запуск расписания (или строку держит другой процесс — тогда вставка ничего не сделает)
+			return tx.Clauses(clause.OnConflict{DoNothing: true}).
+				Create(&scheduleRow{Name: s.name, Spec: s.spec, NextRunAt: s.cron.Next(now)}).Error
+		}
+		if err != nil {
+			return err
+		}
+
+		if row.Spec != s.spec {
+			// Расписание поменялось — считаем срок заново
+			return tx.Model(&row).Updates(map[string]any{"spec": s.spec, "next_run_at": s.cron.Next(now)}).Error
+		}
+		if row.NextRunAt.After(now) {
+			return nil
+		}
+
+		opts := EnqueueOptions{RunAt: row.NextRunAt, UniqueKey: "schedule:" + s.name}
+		if _, err := q.insert(tx, s.kind, s.args, opts); err != nil {
+			return err
+		}
+		return tx.Model(&row).Update("next_run_at", s.cron.Next(now)).Error
+	})
+}
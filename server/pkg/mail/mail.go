@@ -0,0 +1,92 @@
+// Package mail отправляет письма: через SMTP или в файлы .eml для разработки и тестов.
+package mail
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"strings"
+	"time"
+)
+
+// Message — текстовое письмо одному получателю
+type Message struct {
+	To      string `json:"to"`
+	Subject string `json:"subject"`
+	Text    string `json:"text"`
+}
+
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+type Config struct {
+	Driver string // smtp | file (только для разработки: письма не уходят адресатам)
+	From   string
+
+	// SMTP. Порт 465 — TLS сразу, иначе STARTTLS, если сервер его предлагает
+	Host     string
+	Port     string
+	Username string
+	Password string
+
+	Dir string // каталог для писем драйвера file
+}
+
+func New(config Config) (Mailer, error) {
+	if config.From == "" {
+		config.From = "Task Tracker <noreply@localhost>"
+	}
+	switch config.Driver {
+	case "", "file":
+		if config.Dir == "" {
+			config.Dir = "./mail"
+		}
+		return NewFileMailer(config.Dir, config.From)
+	case "smtp":
+		return NewSMTPMailer(config)
+	default:
+		return nil, fmt.Errorf("unknown mail driver %q", config.Driver)
+	}
+}
+
+// build собирает письмо в формате RFC 5322: заголовки кодируются по RFC 2047, текст —
+// quoted-printable. Переводы строк в заголовках убираются, чтобы не подставить чужие
+func build(from string, msg Message) ([]byte, error) {
+	var id [16]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		return nil, err
+	}
+	domain := "localhost"
+	if _, d, ok := strings.Cut(strings.Trim(from, "<> "), "@"); ok {
+		domain = strings.TrimRight(d, ">")
+	}
+
+	var b bytes.Buffer
+	header := func(name, value string) {
+		value = strings.NewReplacer("\r", " ", "\n", " ").Replace(value)
+		fmt.Fprintf(&b, "%s: %s\r\n", name, value)
+	}
+	header("From", from)
+	header("To", msg.To)
+	header("Subject", mime.QEncoding.Encode("utf-8", msg.Subject))
+	header("Date", time.Now().Format(time.RFC1123Z))
+	header("Message-ID", "<"+hex.EncodeToString(id[:])+"@"+domain+">")
+	header("MIME-Version", "1.0")
+	header("Content-Type", "text/plain; charset=utf-8")
+	header("Content-Transfer-Encoding", "quoted-printable")
+	b.WriteString("\r\n")
+
+	w := quotedprintable.NewWriter(&b)
+	if _, err := w.Write([]byte(strings.ReplaceAll(msg.Text, "\n", "\r\n"))); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
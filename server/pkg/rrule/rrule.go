@@ -0,0 +1,460 @@
+// Package rrule реализует подмножество правил повторения iCalendar (RFC 5545):
+// FREQ=DAILY|WEEKLY|MONTHLY, INTERVAL, BYDAY, BYMONTHDAY, WKST, COUNT и UNTIL.
+package rrule
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Frequency int
+
+const (
+	Daily Frequency = iota
+	Weekly
+	Monthly
+)
+
+var frequencyNames = map[Frequency]string{
+	Daily:   "DAILY",
+	Weekly:  "WEEKLY",
+	Monthly: "MONTHLY",
+}
+
+var weekdayNames = map[time.Weekday]string{
+	time.Monday:    "MO",
+	time.Tuesday:   "TU",
+	time.Wednesday: "WE",
+	time.Thursday:  "TH",
+	time.Friday:    "FR",
+	time.Saturday:  "SA",
+	time.Sunday:    "SU",
+}
+
+// Защита от правил, которые никогда не дают вхождений (например, BYMONTHDAY=31;BYDAY=1MO)
+const maxEmptyPeriods = 1000
+
+// WeekdayNum — элемент BYDAY: день недели и необязательный порядковый номер в месяце (1MO, -1FR)
+type WeekdayNum struct {
+	Weekday time.Weekday
+	N       int
+}
+
+type Rule struct {
+	Freq       Frequency
+	Interval   int
+	ByDay      []WeekdayNum
+	ByMonthDay []int
+	WeekStart  time.Weekday
+	Count      int
+	Until      time.Time
+	// UntilDate — UNTIL задан датой без времени и включает весь этот день
+	UntilDate bool
+}
+
+func Parse(s string) (*Rule, error) {
+	s = strings.TrimSpace(s)
+	s = strings.TrimPrefix(strings.ToUpper(s), "RRULE:")
+	if s == "" {
+		return nil, errors.New("rrule: empty rule")
+	}
+
+	r := &Rule{Interval: 1, WeekStart: time.Monday}
+	seen := map[string]bool{}
+	hasFreq := false
+
+	for _, part := range strings.Split(s, ";") {
+		key, value, ok := strings.Cut(part, "=")
+		if !ok || value == "" {
+			return nil, fmt.Errorf("rrule: malformed part %q", part)
+		}
+		if seen[key] {
+			return nil, fmt.Errorf("rrule: duplicate %s", key)
+		}
+		seen[key] = true
+
+		var err error
+		switch key {
+		case "FREQ":
+			hasFreq = true
+			r.Freq, err = parseFrequency(value)
+		case "INTERVAL":
+			r.Interval, err = strconv.Atoi(value)
+			if err == nil && r.Interval < 1 {
+				err = errors.New("must be positive")
+			}
+		case "COUNT":
+			r.Count, err = strconv.Atoi(value)
+			if err == nil && r.Count < 1 {
+				err = errors.New("must be positive")
+			}
+		case "UNTIL":
+			r.Until, r.UntilDate, err = parseUntil(value)
+		case "BYDAY":
+			r.ByDay, err = parseByDay(value)
+		case "BYMONTHDAY":
+			r.ByMonthDay, err = parseByMonthDay(value)
+		case "WKST":
+			var wd WeekdayNum
+			wd, err = parseWeekdayNum(value)
+			if err == nil && wd.N != 0 {
+				err = errors.New("must be a plain weekday")
+			}
+			r.WeekStart = wd.Weekday
+		default:
+			return nil, fmt.Errorf("rrule: unsupported part %s", key)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("rrule: invalid %s=%s: %v", key, value, err)
+		}
+	}
+
+	if !hasFreq {
+		return nil, errors.New("rrule: FREQ is required")
+	}
+	if seen["COUNT"] && seen["UNTIL"] {
+		return nil, errors.New("rrule: COUNT and UNTIL are mutually exclusive")
+	}
+	if r.Freq == Weekly && len(r.ByMonthDay) > 0 {
+		return nil, errors.New("rrule: BYMONTHDAY is not allowed with FREQ=WEEKLY")
+	}
+	if r.Freq != Monthly {
+		for _, wd := range r.ByDay {
+			if wd.N != 0 {
+				return nil, errors.New("rrule: numbered BYDAY is only allowed with FREQ=MONTHLY")
+			}
+		}
+	}
+
+	return r, nil
+}
+
+// String возвращает каноническое представление правила
+func (r *Rule) String() string {
+	parts := []string{"FREQ=" + frequencyNames[r.Freq]}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if len(r.ByDay) > 0 {
+		days := make([]string, len(r.ByDay))
+		for i, wd := range r.ByDay {
+			days[i] = wd.String()
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+	if len(r.ByMonthDay) > 0 {
+		days := make([]string, len(r.ByMonthDay))
+		for i, d := range r.ByMonthDay {
+			days[i] = strconv.Itoa(d)
+		}
+		parts = append(parts, "BYMONTHDAY="+strings.Join(days, ","))
+	}
+	if r.WeekStart != time.Monday {
+		parts = append(parts, "WKST="+weekdayNames[r.WeekStart])
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	if !r.Until.IsZero() {
+		if r.UntilDate {
+			parts = append(parts, "UNTIL="+r.Until.Format("20060102"))
+		} else {
+			parts = append(parts, "UNTIL="+r.Until.UTC().Format("20060102T150405Z"))
+		}
+	}
+	return strings.Join(parts, ";")
+}
+
+func (wd WeekdayNum) String() string {
+	if wd.N == 0 {
+		return weekdayNames[wd.Weekday]
+	}
+	return strconv.Itoa(wd.N) + weekdayNames[wd.Weekday]
+}
+
+// Next возвращает первое вхождение серии, начатой в dtstart, строго после after.
+// Вычисления ведутся по настенному времени в часовом поясе dtstart, поэтому
+// переход на летнее/зимнее время не сдвигает время суток.
+// DTSTART всегда считается первым вхождением (RFC 5545, 3.8.5.3).
+func (r *Rule) Next(dtstart, after time.Time) (time.Time, bool) {
+	var until time.Time
+	if !r.Until.IsZero() {
+		until = r.Until
+		if r.UntilDate {
+			y, m, d := r.Until.Date()
+			until = time.Date(y, m, d+1, 0, 0, 0, 0, dtstart.Location()).Add(-time.Nanosecond)
+		}
+	}
+
+	count := 0
+	emit := func(t time.Time) (time.Time, bool, bool) {
+		if !until.IsZero() && t.After(until) {
+			return time.Time{}, false, true
+		}
+		count++
+		if r.Count > 0 && count > r.Count {
+			return time.Time{}, false, true
+		}
+		if t.After(after) {
+			return t, true, true
+		}
+		return time.Time{}, false, false
+	}
+
+	if t, ok, done := emit(dtstart); done {
+		return t, ok
+	}
+
+	empty := 0
+	for period := 0; empty < maxEmptyPeriods; period++ {
+		candidates := r.candidates(dtstart, period)
+		if len(candidates) == 0 {
+			empty++
+			continue
+		}
+		empty = 0
+		for _, t := range candidates {
+			if !t.After(dtstart) {
+				continue
+			}
+			if t, ok, done := emit(t); done {
+				return t, ok
+			}
+		}
+	}
+	return time.Time{}, false
+}
+
+// candidates возвращает отсортированные вхождения n-го периода (дня, недели или месяца)
+func (r *Rule) candidates(dtstart time.Time, n int) []time.Time {
+	y, m, d := dtstart.Date()
+	step := n * r.Interval
+
+	var days []time.Time
+	switch r.Freq {
+	case Daily:
+		day := r.at(dtstart, y, m, d+step)
+		if r.matchesDay(day) && r.matchesMonthDay(day) {
+			days = append(days, day)
+		}
+	case Weekly:
+		offset := (int(dtstart.Weekday()) - int(r.WeekStart) + 7) % 7
+		weekStart := d - offset + 7*step
+		if len(r.ByDay) == 0 {
+			days = append(days, r.at(dtstart, y, m, weekStart+offset))
+			break
+		}
+		for _, wd := range r.ByDay {
+			days = append(days, r.at(dtstart, y, m, weekStart+(int(wd.Weekday)-int(r.WeekStart)+7)%7))
+		}
+	case Monthly:
+		// Месяц считаем арифметически: AddDate(0, n, 0) от 31-го числа перескочил бы месяц
+		total := int(m) - 1 + step
+		year, month := y+total/12, time.Month(total%12+1)
+		for _, day := range r.monthDays(dtstart, year, month) {
+			days = append(days, r.at(dtstart, year, month, day))
+		}
+	}
+
+	sort.Slice(days, func(i, j int) bool { return days[i].Before(days[j]) })
+	return dedupe(days)
+}
+
+func (r *Rule) monthDays(dtstart time.Time, year int, month time.Month) []int {
+	dim := daysIn(year, month)
+	var byMonthDay, byDay map[int]bool
+
+	if len(r.ByMonthDay) > 0 {
+		byMonthDay = map[int]bool{}
+		for _, md := range r.ByMonthDay {
+			if md < 0 {
+				md = dim + md + 1
+			}
+			if md >= 1 && md <= dim {
+				byMonthDay[md] = true
+			}
+		}
+	}
+
+	if len(r.ByDay) > 0 {
+		byDay = map[int]bool{}
+		first := time.Date(year, month, 1, 0, 0, 0, 0, time.UTC).Weekday()
+		for _, wd := range r.ByDay {
+			firstMatch := 1 + (int(wd.Weekday)-int(first)+7)%7
+			var matches []int
+			for day := firstMatch; day <= dim; day += 7 {
+				matches = append(matches, day)
+			}
+			switch {
+			case wd.N == 0:
+				for _, day := range matches {
+					byDay[day] = true
+				}
+			case wd.N > 0 && wd.N <= len(matches):
+				byDay[matches[wd.N-1]] = true
+			case wd.N < 0 && -wd.N <= len(matches):
+				byDay[matches[len(matches)+wd.N]] = true
+			}
+		}
+	}
+
+	var days []int
+	switch {
+	case byMonthDay != nil && byDay != nil:
+		for day := range byMonthDay {
+			if byDay[day] {
+				days = append(days, day)
+			}
+		}
+	case byMonthDay != nil:
+		for day := range byMonthDay {
+			days = append(days, day)
+		}
+	case byDay != nil:
+		for day := range byDay {
+			days = append(days, day)
+		}
+	default:
+		// Без BYxxx повторяем число из DTSTART; месяцы, где его нет, пропускаются
+		if dtstart.Day() <= dim {
+			days = append(days, dtstart.Day())
+		}
+	}
+	return days
+}
+
+func (r *Rule) matchesDay(t time.Time) bool {
+	if len(r.ByDay) == 0 {
+		return true
+	}
+	for _, wd := range r.ByDay {
+		if wd.Weekday == t.Weekday() {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Rule) matchesMonthDay(t time.Time) bool {
+	if len(r.ByMonthDay) == 0 {
+		return true
+	}
+	dim := daysIn(t.Year(), t.Month())
+	for _, md := range r.ByMonthDay {
+		if md < 0 {
+			md = dim + md + 1
+		}
+		if md == t.Day() {
+			return true
+		}
+	}
+	return false
+}
+
+// at строит момент с датой (нормализуя переполнение дней) и временем суток из dtstart.
+// По RFC 5545 несуществующее время (переход на летнее) берётся со смещением,
+// действовавшим до перехода, а из двух одинаковых (переход на зимнее) — первое.
+func (r *Rule) at(dtstart time.Time, year int, month time.Month, day int) time.Time {
+	h, mi, s := dtstart.Clock()
+	ns, loc := dtstart.Nanosecond(), dtstart.Location()
+
+	t := time.Date(year, month, day, h, mi, s, ns, loc)
+	_, offsetBefore := t.Add(-12 * time.Hour).Zone()
+	naive := time.Date(year, month, day, h, mi, s, ns, time.UTC)
+	early := naive.Add(-time.Duration(offsetBefore) * time.Second).In(loc)
+
+	if th, tm, ts := t.Clock(); th != h || tm != mi || ts != s {
+		return early
+	}
+	if eh, em, es := early.Clock(); eh == h && em == mi && es == s && early.Before(t) {
+		return early
+	}
+	return t
+}
+
+func daysIn(year int, month time.Month) int {
+	return time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
+
+func dedupe(times []time.Time) []time.Time {
+	out := times[:0]
+	for i, t := range times {
+		if i == 0 || !t.Equal(times[i-1]) {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+func parseFrequency(value string) (Frequency, error) {
+	for f, name := range frequencyNames {
+		if name == value {
+			return f, nil
+		}
+	}
+	return 0, errors.New("supported values are DAILY, WEEKLY, MONTHLY")
+}
+
+func parseUntil(value string) (time.Time, bool, error) {
+	if t, err := time.Parse("20060102T150405Z", value); err == nil {
+		return t, false, nil
+	}
+	if t, err := time.Parse("20060102", value); err == nil {
+		return t, true, nil
+	}
+	return time.Time{}, false, errors.New("expected YYYYMMDD or YYYYMMDDTHHMMSSZ")
+}
+
+func parseByDay(value string) ([]WeekdayNum, error) {
+	var days []WeekdayNum
+	for _, item := range strings.Split(value, ",") {
+		wd, err := parseWeekdayNum(item)
+		if err != nil {
+			return nil, err
+		}
+		days = append(days, wd)
+	}
+	return days, nil
+}
+
+func parseWeekdayNum(value string) (WeekdayNum, error) {
+	if len(value) < 2 {
+		return WeekdayNum{}, fmt.Errorf("bad weekday %q", value)
+	}
+	name, num := value[len(value)-2:], value[:len(value)-2]
+
+	var wd WeekdayNum
+	found := false
+	for day, n := range weekdayNames {
+		if n == name {
+			wd.Weekday, found = day, true
+		}
+	}
+	if !found {
+		return WeekdayNum{}, fmt.Errorf("bad weekday %q", value)
+	}
+	if num != "" {
+		n, err := strconv.Atoi(num)
+		if err != nil || n == 0 || n < -5 || n > 5 {
+			return WeekdayNum{}, fmt.Errorf("bad weekday ordinal %q", value)
+		}
+		wd.N = n
+	}
+	return wd, nil
+}
+
+func parseByMonthDay(value string) ([]int, error) {
+	var days []int
+	for _, item := range strings.Split(value, ",") {
+		d, err := strconv.Atoi(item)
+		if err != nil || d == 0 || d < -31 || d > 31 {
+			return nil, fmt.Errorf("bad month day %q", item)
+		}
+		days = append(days, d)
+	}
+	return days, nil
+}
@@ -0,0 +1,337 @@
+package rrule
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func mustLoad(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Skipf("timezone %s is unavailable: %v", name, err)
+	}
+	return loc
+}
+
+// occurrences возвращает первые n вхождений серии, включая dtstart
+func occurrences(t *testing.T, rule string, dtstart time.Time, n int) []time.Time {
+	t.Helper()
+	r, err := Parse(rule)
+	if err != nil {
+		t.Fatalf("Parse(%q): %v", rule, err)
+	}
+	result := []time.Time{dtstart}
+	for after := dtstart; len(result) < n; {
+		next, ok := r.Next(dtstart, after)
+		if !ok {
+			break
+		}
+		if !next.After(after) {
+			t.Fatalf("Next(%v) = %v does not advance", after, next)
+		}
+		result = append(result, next)
+		after = next
+	}
+	return result
+}
+
+func format(times []time.Time) string {
+	s := make([]string, len(times))
+	for i, t := range times {
+		s[i] = t.Format("2006-01-02 15:04 MST")
+	}
+	return strings.Join(s, ", ")
+}
+
+func TestNextMonthEnd(t *testing.T) {
+	utc := func(y int, m time.Month, d int) time.Time { return time.Date(y, m, d, 9, 0, 0, 0, time.UTC) }
+
+	tests := []struct {
+		name    string
+		rule    string
+		dtstart time.Time
+		want    []string
+	}{
+		{
+			name:    "BYMONTHDAY=31 skips short months",
+			rule:    "FREQ=MONTHLY;BYMONTHDAY=31",
+			dtstart: utc(2026, 1, 31),
+			want:    []string{"2026-01-31", "2026-03-31", "2026-05-31", "2026-07-31", "2026-08-31"},
+		},
+		{
+			name:    "plain monthly from the 31st skips short months",
+			rule:    "FREQ=MONTHLY",
+			dtstart: utc(2026, 1, 31),
+			want:    []string{"2026-01-31", "2026-03-31", "2026-05-31", "2026-07-31", "2026-08-31"},
+		},
+		{
+			name:    "BYMONTHDAY=-1 is the last day of every month",
+			rule:    "FREQ=MONTHLY;BYMONTHDAY=-1",
+			dtstart: utc(2026, 1, 31),
+			want:    []string{"2026-01-31", "2026-02-28", "2026-03-31", "2026-04-30", "2026-05-31"},
+		},
+		{
+			name:    "BYMONTHDAY=-1 in a leap year",
+			rule:    "FREQ=MONTHLY;BYMONTHDAY=-1",
+			dtstart: utc(2028, 1, 31),
+			want:    []string{"2028-01-31", "2028-02-29", "2028-03-31"},
+		},
+		{
+			name:    "BYMONTHDAY=-3 counts from the month end",
+			rule:    "FREQ=MONTHLY;BYMONTHDAY=-3",
+			dtstart: utc(2026, 1, 29),
+			want:    []string{"2026-01-29", "2026-02-26", "2026-03-29", "2026-04-28"},
+		},
+		{
+			name:    "Feb 29 only exists in leap years",
+			rule:    "FREQ=MONTHLY;INTERVAL=12;BYMONTHDAY=29",
+			dtstart: utc(2024, 2, 29),
+			want:    []string{"2024-02-29", "2028-02-29", "2032-02-29"},
+		},
+		{
+			name:    "monthly from Jan 29 skips February in a common year",
+			rule:    "FREQ=MONTHLY",
+			dtstart: utc(2027, 1, 29),
+			want:    []string{"2027-01-29", "2027-03-29", "2027-04-29"},
+		},
+		{
+			name:    "monthly from Jan 29 keeps February in a leap year",
+			rule:    "FREQ=MONTHLY",
+			dtstart: utc(2028, 1, 29),
+			want:    []string{"2028-01-29", "2028-02-29", "2028-03-29"},
+		},
+		{
+			name:    "last Friday",
+			rule:    "FREQ=MONTHLY;BYDAY=-1FR",
+			dtstart: utc(2026, 1, 30),
+			want:    []string{"2026-01-30", "2026-02-27", "2026-03-27", "2026-04-24"},
+		},
+		{
+			name:    "fifth Monday skips months without one",
+			rule:    "FREQ=MONTHLY;BYDAY=5MO",
+			dtstart: utc(2026, 3, 30),
+			want:    []string{"2026-03-30", "2026-06-29", "2026-08-31", "2026-11-30"},
+		},
+		{
+			name:    "BYMONTHDAY and BYDAY intersect",
+			rule:    "FREQ=MONTHLY;BYDAY=FR;BYMONTHDAY=13",
+			dtstart: utc(2026, 2, 13),
+			want:    []string{"2026-02-13", "2026-03-13", "2026-11-13", "2027-08-13"},
+		},
+		{
+			name:    "daily with BYMONTHDAY=-1",
+			rule:    "FREQ=DAILY;BYMONTHDAY=-1",
+			dtstart: utc(2026, 2, 28),
+			want:    []string{"2026-02-28", "2026-03-31", "2026-04-30"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := occurrences(t, tt.rule, tt.dtstart, len(tt.want))
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d occurrences (%s), want %d", len(got), format(got), len(tt.want))
+			}
+			for i, want := range tt.want {
+				if d := got[i].Format("2006-01-02"); d != want || got[i].Hour() != 9 {
+					t.Errorf("occurrence %d = %s, want %s 09:00", i, got[i].Format(time.RFC3339), want)
+				}
+			}
+		})
+	}
+}
+
+func TestNextDST(t *testing.T) {
+	ny := mustLoad(t, "America/New_York")
+	berlin := mustLoad(t, "Europe/Berlin")
+
+	tests := []struct {
+		name    string
+		rule    string
+		dtstart time.Time
+		// Ожидаемые моменты в UTC
+		want []string
+	}{
+		{
+			name:    "daily 09:00 keeps the wall clock across spring forward",
+			rule:    "FREQ=DAILY",
+			dtstart: time.Date(2026, 3, 7, 9, 0, 0, 0, ny),
+			want:    []string{"2026-03-07T14:00:00Z", "2026-03-08T13:00:00Z", "2026-03-09T13:00:00Z"},
+		},
+		{
+			name:    "daily 09:00 keeps the wall clock across fall back",
+			rule:    "FREQ=DAILY",
+			dtstart: time.Date(2026, 10, 31, 9, 0, 0, 0, ny),
+			want:    []string{"2026-10-31T13:00:00Z", "2026-11-01T14:00:00Z", "2026-11-02T14:00:00Z"},
+		},
+		{
+			// 02:30 8 марта не существует: берётся смещение до перехода, то есть 03:30 EDT
+			name:    "nonexistent time uses the offset before the transition",
+			rule:    "FREQ=DAILY",
+			dtstart: time.Date(2026, 3, 7, 2, 30, 0, 0, ny),
+			want:    []string{"2026-03-07T07:30:00Z", "2026-03-08T07:30:00Z", "2026-03-09T06:30:00Z"},
+		},
+		{
+			// 01:30 1 ноября встречается дважды: берётся первое (EDT)
+			name:    "ambiguous time uses the first occurrence",
+			rule:    "FREQ=DAILY",
+			dtstart: time.Date(2026, 10, 31, 1, 30, 0, 0, ny),
+			want:    []string{"2026-10-31T05:30:00Z", "2026-11-01T05:30:00Z", "2026-11-02T06:30:00Z"},
+		},
+		{
+			name:    "weekly on Monday around the European transition",
+			rule:    "FREQ=WEEKLY;BYDAY=MO",
+			dtstart: time.Date(2026, 3, 23, 8, 0, 0, 0, berlin),
+			want:    []string{"2026-03-23T07:00:00Z", "2026-03-30T06:00:00Z", "2026-04-06T06:00:00Z"},
+		},
+		{
+			name:    "weekly midnight stays on Monday",
+			rule:    "FREQ=WEEKLY;BYDAY=MO",
+			dtstart: time.Date(2026, 3, 2, 0, 0, 0, 0, ny),
+			want:    []string{"2026-03-02T05:00:00Z", "2026-03-09T04:00:00Z", "2026-03-16T04:00:00Z"},
+		},
+		{
+			name:    "monthly last day across a transition",
+			rule:    "FREQ=MONTHLY;BYMONTHDAY=-1",
+			dtstart: time.Date(2026, 2, 28, 18, 0, 0, 0, ny),
+			want:    []string{"2026-02-28T23:00:00Z", "2026-03-31T22:00:00Z", "2026-04-30T22:00:00Z"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := occurrences(t, tt.rule, tt.dtstart, len(tt.want))
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d occurrences (%s), want %d", len(got), format(got), len(tt.want))
+			}
+			for i, want := range tt.want {
+				if s := got[i].UTC().Format(time.RFC3339); s != want {
+					t.Errorf("occurrence %d = %s (%s), want %s", i, s, got[i].Format("15:04 MST"), want)
+				}
+			}
+		})
+	}
+}
+
+func TestNextBounds(t *testing.T) {
+	start := time.Date(2026, 1, 5, 9, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		rule string
+		want int
+	}{
+		{"COUNT includes dtstart", "FREQ=DAILY;COUNT=3", 3},
+		{"UNTIL date includes the whole day", "FREQ=DAILY;UNTIL=20260107", 3},
+		{"UNTIL time is inclusive", "FREQ=DAILY;UNTIL=20260107T090000Z", 3},
+		{"UNTIL time before the occurrence", "FREQ=DAILY;UNTIL=20260107T085959Z", 2},
+		{"COUNT with weekly BYDAY", "FREQ=WEEKLY;BYDAY=MO,WE,FR;COUNT=5", 5},
+		{"impossible rule stops", "FREQ=MONTHLY;BYMONTHDAY=31;BYDAY=1MO", 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := occurrences(t, tt.rule, start, 100)
+			if len(got) != tt.want {
+				t.Errorf("got %d occurrences (%s), want %d", len(got), format(got), tt.want)
+			}
+		})
+	}
+}
+
+func TestNextWeekly(t *testing.T) {
+	// Понедельник
+	start := time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		rule string
+		want []string
+	}{
+		{"several days", "FREQ=WEEKLY;BYDAY=MO,TH", []string{"03-02", "03-05", "03-09", "03-12"}},
+		{"interval", "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,FR", []string{"03-02", "03-06", "03-16", "03-20"}},
+		{"without BYDAY repeats dtstart weekday", "FREQ=WEEKLY", []string{"03-02", "03-09", "03-16"}},
+		// С WKST=SU воскресенье 8 марта начинает новую неделю, и INTERVAL=2 её пропускает
+		{"WKST changes interval weeks", "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,SU;WKST=SU", []string{"03-02", "03-15", "03-16", "03-29"}},
+		{"WKST=MO keeps Sunday in the same week", "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,SU", []string{"03-02", "03-08", "03-16", "03-22"}},
+		{"daily with BYDAY", "FREQ=DAILY;BYDAY=MO,TU", []string{"03-02", "03-03", "03-09", "03-10"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := occurrences(t, tt.rule, start, len(tt.want))
+			for i, want := range tt.want {
+				if i >= len(got) {
+					t.Fatalf("got only %s", format(got))
+				}
+				if d := got[i].Format("01-02"); d != want {
+					t.Errorf("occurrence %d = %s, want %s (all: %s)", i, d, want, format(got))
+				}
+			}
+		})
+	}
+}
+
+func TestNextAfterSkipsPast(t *testing.T) {
+	r, err := Parse("FREQ=MONTHLY;BYMONTHDAY=-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dtstart := time.Date(2026, 1, 31, 0, 0, 0, 0, time.UTC)
+	// after между вхождениями и до dtstart
+	next, ok := r.Next(dtstart, time.Date(2026, 2, 10, 0, 0, 0, 0, time.UTC))
+	if !ok || !next.Equal(time.Date(2026, 2, 28, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Next = %v, %v; want 2026-02-28", next, ok)
+	}
+	next, ok = r.Next(dtstart, dtstart.AddDate(0, 0, -1))
+	if !ok || !next.Equal(dtstart) {
+		t.Errorf("Next before dtstart = %v, %v; want dtstart", next, ok)
+	}
+}
+
+func TestParse(t *testing.T) {
+	valid := []struct {
+		in, want string
+	}{
+		{"FREQ=DAILY", "FREQ=DAILY"},
+		{"rrule:freq=weekly;byday=mo,fr;interval=1", "FREQ=WEEKLY;BYDAY=MO,FR"},
+		{"FREQ=MONTHLY;BYDAY=-1FR;COUNT=3", "FREQ=MONTHLY;BYDAY=-1FR;COUNT=3"},
+		{"FREQ=MONTHLY;BYMONTHDAY=1,-1;UNTIL=20261231", "FREQ=MONTHLY;BYMONTHDAY=1,-1;UNTIL=20261231"},
+		{"FREQ=WEEKLY;WKST=SU;UNTIL=20261231T235959Z", "FREQ=WEEKLY;WKST=SU;UNTIL=20261231T235959Z"},
+	}
+	for _, tt := range valid {
+		r, err := Parse(tt.in)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.in, err)
+			continue
+		}
+		if got := r.String(); got != tt.want {
+			t.Errorf("Parse(%q).String() = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+
+	invalid := []string{
+		"",
+		"INTERVAL=2",
+		"FREQ=YEARLY",
+		"FREQ=DAILY;FREQ=WEEKLY",
+		"FREQ=DAILY;INTERVAL=0",
+		"FREQ=DAILY;COUNT=2;UNTIL=20260101",
+		"FREQ=DAILY;BYMONTHDAY=32",
+		"FREQ=DAILY;BYMONTHDAY=0",
+		"FREQ=WEEKLY;BYMONTHDAY=1",
+		"FREQ=WEEKLY;BYDAY=1MO",
+		"FREQ=MONTHLY;BYDAY=6MO",
+		"FREQ=DAILY;WKST=1MO",
+		"FREQ=DAILY;UNTIL=2026-01-01",
+		"FREQ=DAILY;BYHOUR=9",
+		"FREQ=DAILY;",
+	}
+	for _, in := range invalid {
+		if _, err := Parse(in); err == nil {
+			t.Errorf("Parse(%q) succeeded, want error", in)
+		}
+	}
+}
@@ -0,0 +1,223 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+const (
+	testAccessKey = "AKIDEXAMPLE"
+	testSecretKey = "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"
+	testRegion    = "eu-central-1"
+	testBucket    = "attachments"
+)
+
+// fakeS3 — минимальная замена S3: проверяет подпись SigV4 и хранит объекты в памяти
+type fakeS3 struct {
+	t       *testing.T
+	mu      sync.Mutex
+	objects map[string][]byte
+	types   map[string]string
+	paths   []string
+}
+
+func newFakeS3(t *testing.T) (*fakeS3, *httptest.Server) {
+	f := &fakeS3{t: t, objects: map[string][]byte{}, types: map[string]string{}}
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+	return f, srv
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !f.verify(r) {
+		http.Error(w, "SignatureDoesNotMatch", http.StatusForbidden)
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.paths = append(f.paths, r.URL.EscapedPath())
+
+	prefix := "/" + testBucket + "/"
+	if !strings.HasPrefix(r.URL.Path, prefix) {
+		http.Error(w, "NoSuchBucket", http.StatusNotFound)
+		return
+	}
+	key := strings.TrimPrefix(r.URL.Path, prefix)
+
+	switch r.Method {
+	case http.MethodPut:
+		body, _ := io.ReadAll(r.Body)
+		if r.ContentLength != int64(len(body)) {
+			http.Error(w, "IncompleteBody", http.StatusBadRequest)
+			return
+		}
+		f.objects[key] = body
+		f.types[key] = r.Header.Get("Content-Type")
+	case http.MethodGet, http.MethodHead:
+		body, ok := f.objects[key]
+		if !ok {
+			http.Error(w, "NoSuchKey", http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", f.types[key])
+		w.Write(body)
+	case http.MethodDelete:
+		if _, ok := f.objects[key]; !ok {
+			http.Error(w, "NoSuchKey", http.StatusNotFound)
+			return
+		}
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// verify пересчитывает подпись по тому, что реально пришло по сети
+func (f *fakeS3) verify(r *http.Request) bool {
+	auth := r.Header.Get("Authorization")
+	amzDate := r.Header.Get("X-Amz-Date")
+	date, _, _ := strings.Cut(amzDate, "T")
+	scope := date + "/" + testRegion + "/s3/aws4_request"
+	prefix := "AWS4-HMAC-SHA256 Credential=" + testAccessKey + "/" + scope +
+		", SignedHeaders=host;x-amz-content-sha256;x-amz-date, Signature="
+	if !strings.HasPrefix(auth, prefix) {
+		f.t.Errorf("unexpected Authorization header %q", auth)
+		return false
+	}
+
+	canonical := r.Method + "\n" +
+		r.URL.EscapedPath() + "\n" +
+		r.URL.RawQuery + "\n" +
+		"host:" + r.Host + "\n" +
+		"x-amz-content-sha256:" + r.Header.Get("X-Amz-Content-Sha256") + "\n" +
+		"x-amz-date:" + amzDate + "\n\n" +
+		"host;x-amz-content-sha256;x-amz-date\n" +
+		r.Header.Get("X-Amz-Content-Sha256")
+	hash := sha256.Sum256([]byte(canonical))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(hash[:])
+
+	key := []byte("AWS4" + testSecretKey)
+	for _, part := range []string{date, testRegion, "s3", "aws4_request", stringToSign} {
+		mac := hmac.New(sha256.New, key)
+		mac.Write([]byte(part))
+		key = mac.Sum(nil)
+	}
+	return hmac.Equal([]byte(strings.TrimPrefix(auth, prefix)), []byte(hex.EncodeToString(key)))
+}
+
+func newTestS3Store(t *testing.T, endpoint, secretKey string) BlobStore {
+	t.Helper()
+	store, err := NewS3Store(endpoint, testBucket, testRegion, testAccessKey, secretKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+func TestS3StorePutGetDelete(t *testing.T) {
+	fake, srv := newFakeS3(t)
+	store := newTestS3Store(t, srv.URL, testSecretKey)
+	ctx := context.Background()
+	key := "sha256/ab/abcdef"
+	content := []byte("hello, attachments")
+
+	if err := store.Put(ctx, key, bytes.NewReader(content), int64(len(content)), "text/plain"); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if got := fake.types[key]; got != "text/plain" {
+		t.Errorf("stored content type = %q, want text/plain", got)
+	}
+
+	exists, err := store.Exists(ctx, key)
+	if err != nil || !exists {
+		t.Fatalf("Exists = %v, %v; want true", exists, err)
+	}
+
+	body, err := store.Get(ctx, key)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	got, _ := io.ReadAll(body)
+	body.Close()
+	if !bytes.Equal(got, content) {
+		t.Errorf("Get = %q, want %q", got, content)
+	}
+
+	if err := store.Delete(ctx, key); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, ok := fake.objects[key]; ok {
+		t.Error("object still present after Delete")
+	}
+	exists, err = store.Exists(ctx, key)
+	if err != nil || exists {
+		t.Errorf("Exists after Delete = %v, %v; want false", exists, err)
+	}
+	if _, err := store.Get(ctx, key); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get after Delete error = %v, want ErrNotFound", err)
+	}
+	// Повторное удаление не считается ошибкой
+	if err := store.Delete(ctx, key); err != nil {
+		t.Errorf("second Delete: %v", err)
+	}
+}
+
+func TestS3StoreEscapesKeyAndEndpointPath(t *testing.T) {
+	fake, srv := newFakeS3(t)
+	// Завершающий слэш endpoint не удваивается, ключ экранируется по правилам SigV4
+	store := newTestS3Store(t, srv.URL+"/", testSecretKey)
+	ctx := context.Background()
+	key := "dir/файл с пробелом+плюс.txt"
+
+	if err := store.Put(ctx, key, strings.NewReader("x"), 1, ""); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if _, ok := fake.objects[key]; !ok {
+		t.Fatalf("object stored under unexpected key, have %v", fake.objects)
+	}
+	want := "/" + testBucket + "/dir/%D1%84%D0%B0%D0%B9%D0%BB%20%D1%81%20%D0%BF%D1%80%D0%BE%D0%B1%D0%B5%D0%BB%D0%BE%D0%BC%2B%D0%BF%D0%BB%D1%8E%D1%81.txt"
+	if got := fake.paths[len(fake.paths)-1]; got != want {
+		t.Errorf("request path = %s, want %s", got, want)
+	}
+}
+
+func TestS3StoreRejectedSignature(t *testing.T) {
+	_, srv := newFakeS3(t)
+	store := newTestS3Store(t, srv.URL, "wrong-secret")
+
+	err := store.Put(context.Background(), "k", strings.NewReader("x"), 1, "")
+	if err == nil || !strings.Contains(err.Error(), "403") {
+		t.Fatalf("Put with wrong secret error = %v, want 403", err)
+	}
+}
+
+func TestNewS3StoreValidatesConfig(t *testing.T) {
+	tests := []struct {
+		name     string
+		endpoint string
+		bucket   string
+	}{
+		{"no endpoint", "", "b"},
+		{"no bucket", "http://localhost:9000", ""},
+		{"no scheme", "localhost:9000", "b"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewS3Store(tt.endpoint, tt.bucket, "", "", ""); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
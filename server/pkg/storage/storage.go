@@ -0,0 +1,40 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+)
+
+var ErrNotFound = errors.New("blob not found")
+
+// BlobStore хранит бинарное содержимое по ключу
+type BlobStore interface {
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Exists(ctx context.Context, key string) (bool, error)
+	Delete(ctx context.Context, key string) error
+}
+
+type Config struct {
+	Driver string // local | s3
+	Dir    string
+
+	Endpoint  string
+	Bucket    string
+	Region    string
+	AccessKey string
+	SecretKey string
+}
+
+func New(config Config) (BlobStore, error) {
+	switch config.Driver {
+	case "", "local":
+		return NewLocalStore(config.Dir)
+	case "s3":
+		return NewS3Store(config.Endpoint, config.Bucket, config.Region, config.AccessKey, config.SecretKey)
+	default:
+		return nil, fmt.Errorf("unknown storage driver %q", config.Driver)
+	}
+}
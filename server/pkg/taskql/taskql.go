@@ -0,0 +1,255 @@
+// Package taskql разбирает строку поиска задач вида
+//
+//	project:Backend status:!done priority>=medium due<+7d assignee:me "login bug"
+//
+// Запрос — последовательность условий через пробел, все они должны выполняться.
+// Условие — поле, оператор (:, =, !=, <, <=, >, >=; ":!" означает "!=") и значение:
+// слово, список слов через запятую или строка в кавычках. Всё, что не похоже на условие,
+// считается текстом для полнотекстового поиска. Пакет только строит синтаксическое дерево,
+// смысл полей определяет вызывающий код.
+package taskql
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+type Op string
+
+const (
+	OpEq Op = ":"
+	OpNe Op = "!="
+	OpLt Op = "<"
+	OpLe Op = "<="
+	OpGt Op = ">"
+	OpGe Op = ">="
+)
+
+// Term — одно условие. Pos и ValuePos — позиции (в символах, с единицы) начала условия и значения
+type Term struct {
+	Field    string
+	Op       Op
+	Values   []string
+	Pos      int
+	ValuePos int
+}
+
+// Text — слово или фраза для полнотекстового поиска
+type Text struct {
+	Value  string
+	Phrase bool
+	Pos    int
+}
+
+type Query struct {
+	Terms []Term
+	Text  []Text
+}
+
+// ErrSyntax — общая причина всех ошибок разбора, удобна для errors.Is
+var ErrSyntax = errors.New("invalid query")
+
+// Error указывает позицию в исходной строке, к которой относится ошибка
+type Error struct {
+	Pos int
+	Msg string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("query error at position %d: %s", e.Pos, e.Msg)
+}
+
+func (e *Error) Unwrap() error {
+	return ErrSyntax
+}
+
+// Errorf создаёт ошибку с позицией; пригодится и при разборе смысла условий
+func Errorf(pos int, format string, args ...any) *Error {
+	return &Error{Pos: pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+// SearchText собирает текстовую часть запроса обратно в строку; фразы остаются в кавычках
+func (q *Query) SearchText() string {
+	parts := make([]string, 0, len(q.Text))
+	for _, t := range q.Text {
+		if t.Phrase {
+			parts = append(parts, `"`+strings.ReplaceAll(t.Value, `"`, " ")+`"`)
+		} else {
+			parts = append(parts, t.Value)
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+type parser struct {
+	input  []rune
+	pos    int
+	fields map[string]bool
+}
+
+// Parse разбирает запрос. Если переданы fields, условиями считаются только эти поля,
+// а остальное вида слово:значение (например, http://example.com) остаётся текстом
+func Parse(input string, fields ...string) (*Query, error) {
+	p := &parser{input: []rune(input)}
+	if len(fields) > 0 {
+		p.fields = map[string]bool{}
+		for _, f := range fields {
+			p.fields[strings.ToLower(f)] = true
+		}
+	}
+	q := &Query{}
+
+	for {
+		p.skipSpaces()
+		if p.eof() {
+			return q, nil
+		}
+
+		start := p.pos
+		if p.peek() == '"' {
+			value, err := p.quoted()
+			if err != nil {
+				return nil, err
+			}
+			if value != "" {
+				q.Text = append(q.Text, Text{Value: value, Phrase: true, Pos: start + 1})
+			}
+			continue
+		}
+
+		if field := p.ident(); field != "" && p.known(field) {
+			if op := p.op(); op != "" {
+				term, err := p.term(strings.ToLower(field), op, start)
+				if err != nil {
+					return nil, err
+				}
+				q.Terms = append(q.Terms, *term)
+				continue
+			}
+		}
+
+		p.pos = start
+		q.Text = append(q.Text, Text{Value: p.word(), Pos: start + 1})
+	}
+}
+
+func (p *parser) term(field string, op Op, start int) (*Term, error) {
+	term := &Term{Field: field, Op: op, Pos: start + 1, ValuePos: p.pos + 1}
+	if p.eof() || unicode.IsSpace(p.peek()) {
+		return nil, Errorf(term.ValuePos, "missing value for %q", field)
+	}
+
+	if p.peek() == '"' {
+		value, err := p.quoted()
+		if err != nil {
+			return nil, err
+		}
+		term.Values = []string{value}
+		return term, nil
+	}
+
+	for i, value := range strings.Split(p.word(), ",") {
+		if value == "" {
+			return nil, Errorf(term.ValuePos, "empty item %d in the list for %q", i+1, field)
+		}
+		term.Values = append(term.Values, value)
+	}
+	return term, nil
+}
+
+// op читает оператор; ":!" — короткая запись "!="
+func (p *parser) op() Op {
+	two := ""
+	if p.pos+1 < len(p.input) {
+		two = string(p.input[p.pos : p.pos+2])
+	}
+	switch two {
+	case ":!", "!=":
+		p.pos += 2
+		return OpNe
+	case "<=":
+		p.pos += 2
+		return OpLe
+	case ">=":
+		p.pos += 2
+		return OpGe
+	}
+
+	if p.eof() {
+		return ""
+	}
+	switch p.peek() {
+	case ':', '=':
+		p.pos++
+		return OpEq
+	case '<':
+		p.pos++
+		return OpLt
+	case '>':
+		p.pos++
+		return OpGt
+	}
+	return ""
+}
+
+// quoted читает строку в кавычках; внутри допустимы \" и \\
+func (p *parser) quoted() (string, error) {
+	start := p.pos
+	p.pos++
+
+	var b strings.Builder
+	for !p.eof() {
+		r := p.input[p.pos]
+		p.pos++
+		switch {
+		case r == '"':
+			return b.String(), nil
+		case r == '\\' && !p.eof():
+			b.WriteRune(p.input[p.pos])
+			p.pos++
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return "", Errorf(start+1, "unterminated quoted string")
+}
+
+func (p *parser) known(field string) bool {
+	return p.fields == nil || p.fields[strings.ToLower(field)]
+}
+
+func (p *parser) ident() string {
+	start := p.pos
+	for !p.eof() {
+		r := p.peek()
+		if !unicode.IsLetter(r) && r != '_' {
+			break
+		}
+		p.pos++
+	}
+	return string(p.input[start:p.pos])
+}
+
+func (p *parser) word() string {
+	start := p.pos
+	for !p.eof() && !unicode.IsSpace(p.peek()) {
+		p.pos++
+	}
+	return string(p.input[start:p.pos])
+}
+
+func (p *parser) skipSpaces() {
+	for !p.eof() && unicode.IsSpace(p.peek()) {
+		p.pos++
+	}
+}
+
+func (p *parser) peek() rune {
+	return p.input[p.pos]
+}
+
+func (p *parser) eof() bool {
+	return p.pos >= len(p.input)
+}
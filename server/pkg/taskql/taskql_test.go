@@ -0,0 +1,203 @@
+package taskql
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var testFields = []string{"project", "status", "priority", "due", "assignee"}
+
+func TestParseTerms(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []Term
+	}{
+		{
+			in:   "status:done",
+			want: []Term{{Field: "status", Op: OpEq, Values: []string{"done"}, Pos: 1, ValuePos: 8}},
+		},
+		{
+			in:   "  Status=todo,in_progress",
+			want: []Term{{Field: "status", Op: OpEq, Values: []string{"todo", "in_progress"}, Pos: 3, ValuePos: 10}},
+		},
+		{
+			in:   "status:!done",
+			want: []Term{{Field: "status", Op: OpNe, Values: []string{"done"}, Pos: 1, ValuePos: 9}},
+		},
+		{
+			in:   "status!=done",
+			want: []Term{{Field: "status", Op: OpNe, Values: []string{"done"}, Pos: 1, ValuePos: 9}},
+		},
+		{
+			in: "priority>=medium due<+7d due>today priority<=high priority>low due<2026-01-01",
+			want: []Term{
+				{Field: "priority", Op: OpGe, Values: []string{"medium"}, Pos: 1, ValuePos: 11},
+				{Field: "due", Op: OpLt, Values: []string{"+7d"}, Pos: 18, ValuePos: 22},
+				{Field: "due", Op: OpGt, Values: []string{"today"}, Pos: 26, ValuePos: 30},
+				{Field: "priority", Op: OpLe, Values: []string{"high"}, Pos: 36, ValuePos: 46},
+				{Field: "priority", Op: OpGt, Values: []string{"low"}, Pos: 51, ValuePos: 60},
+				{Field: "due", Op: OpLt, Values: []string{"2026-01-01"}, Pos: 64, ValuePos: 68},
+			},
+		},
+		{
+			in:   `project:"Mobile app"`,
+			want: []Term{{Field: "project", Op: OpEq, Values: []string{"Mobile app"}, Pos: 1, ValuePos: 9}},
+		},
+		{
+			in:   `project:"say \"hi\" \\ there"`,
+			want: []Term{{Field: "project", Op: OpEq, Values: []string{`say "hi" \ there`}, Pos: 1, ValuePos: 9}},
+		},
+		{
+			// Позиции считаются в символах, а не в байтах
+			in:   "ошибка project:Бэкенд",
+			want: []Term{{Field: "project", Op: OpEq, Values: []string{"Бэкенд"}, Pos: 8, ValuePos: 16}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			q, err := Parse(tt.in, testFields...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(q.Terms, tt.want) {
+				t.Errorf("terms = %+v\nwant    %+v", q.Terms, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseText(t *testing.T) {
+	tests := []struct {
+		in     string
+		fields []string
+		text   []Text
+		search string
+	}{
+		{
+			in:     `login bug`,
+			text:   []Text{{Value: "login", Pos: 1}, {Value: "bug", Pos: 7}},
+			search: "login bug",
+		},
+		{
+			in:     `status:todo "login bug" crash`,
+			fields: testFields,
+			text:   []Text{{Value: "login bug", Phrase: true, Pos: 13}, {Value: "crash", Pos: 25}},
+			search: `"login bug" crash`,
+		},
+		{
+			in:     `see http://example.com/a:b`,
+			fields: testFields,
+			text:   []Text{{Value: "see", Pos: 1}, {Value: "http://example.com/a:b", Pos: 5}},
+			search: "see http://example.com/a:b",
+		},
+		{
+			// Числа и незнакомые поля с оператором тоже остаются текстом
+			in:     `5:30 ratio<1 :x`,
+			fields: testFields,
+			text:   []Text{{Value: "5:30", Pos: 1}, {Value: "ratio<1", Pos: 6}, {Value: ":x", Pos: 14}},
+			search: "5:30 ratio<1 :x",
+		},
+		{
+			in:     `status done`,
+			fields: testFields,
+			text:   []Text{{Value: "status", Pos: 1}, {Value: "done", Pos: 8}},
+			search: "status done",
+		},
+		{
+			in:     `"" "a \"b\" c"`,
+			text:   []Text{{Value: `a "b" c`, Phrase: true, Pos: 4}},
+			search: `"a  b  c"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			q, err := Parse(tt.in, tt.fields...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(q.Text, tt.text) {
+				t.Errorf("text = %+v\nwant   %+v", q.Text, tt.text)
+			}
+			if got := q.SearchText(); got != tt.search {
+				t.Errorf("SearchText = %q, want %q", got, tt.search)
+			}
+		})
+	}
+}
+
+func TestParseUnknownFieldWithoutList(t *testing.T) {
+	q, err := Parse("http://example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(q.Terms) != 1 || q.Terms[0].Field != "http" || len(q.Text) != 0 {
+		t.Errorf("without a field list every field:value is a term, got %+v", q)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		in  string
+		pos int
+		msg string
+	}{
+		{"status:", 8, `missing value for "status"`},
+		{"bug status: done", 12, `missing value for "status"`},
+		{"status:todo,,done", 8, `empty item 2 in the list for "status"`},
+		{"status:todo,", 8, `empty item 2 in the list for "status"`},
+		{`project:"unterminated`, 9, "unterminated quoted string"},
+		{`bug "phrase`, 5, "unterminated quoted string"},
+		{`проект project:"Бэк`, 16, "unterminated quoted string"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			_, err := Parse(tt.in, testFields...)
+			var qerr *Error
+			if !errors.As(err, &qerr) {
+				t.Fatalf("error = %v, want *Error", err)
+			}
+			if qerr.Pos != tt.pos || qerr.Msg != tt.msg {
+				t.Errorf("error = %d %q, want %d %q", qerr.Pos, qerr.Msg, tt.pos, tt.msg)
+			}
+			if !errors.Is(err, ErrSyntax) {
+				t.Error("error does not wrap ErrSyntax")
+			}
+			if !strings.Contains(err.Error(), "position") {
+				t.Errorf("message %q has no position", err.Error())
+			}
+		})
+	}
+}
+
+// Значения возвращаются как есть: экранированием занимается тот, кто их использует
+func TestParseKeepsHostileValuesVerbatim(t *testing.T) {
+	in := `project:"x' OR '1'='1" status:done;DROP "%_\\"`
+	q, err := Parse(in, testFields...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Term{
+		{Field: "project", Op: OpEq, Values: []string{"x' OR '1'='1"}, Pos: 1, ValuePos: 9},
+		{Field: "status", Op: OpEq, Values: []string{"done;DROP"}, Pos: 24, ValuePos: 31},
+	}
+	if !reflect.DeepEqual(q.Terms, want) {
+		t.Errorf("terms = %+v\nwant    %+v", q.Terms, want)
+	}
+	if len(q.Text) != 1 || q.Text[0].Value != `%_\` {
+		t.Errorf("text = %+v", q.Text)
+	}
+}
+
+func TestParseEmpty(t *testing.T) {
+	for _, in := range []string{"", "   ", "\t\n"} {
+		q, err := Parse(in)
+		if err != nil || len(q.Terms) != 0 || len(q.Text) != 0 {
+			t.Errorf("Parse(%q) = %+v, %v; want empty query", in, q, err)
+		}
+	}
+}